@@ -0,0 +1,186 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Accent Color Icon ---
+
+// With accent_icon on, the active cup is recolored with the Windows accent
+// color. Windows broadcasts a change of accent color to top-level windows,
+// so a hidden window listens for it and the icon follows along.
+
+const (
+	WM_SETTINGCHANGE               = 0x001A
+	WM_DWMCOLORIZATIONCOLORCHANGED = 0x0320
+)
+
+const (
+	dwmKey                 = `Software\Microsoft\Windows\DWM`
+	accentWatcherClassName = "EspressoAccentWatcher"
+)
+
+// accentChangedCh tells the main loop that the accent color changed.
+var accentChangedCh = make(chan struct{}, 1)
+
+// accentColor returns the accent color as RGB.
+func accentColor() ([3]byte, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, dwmKey, registry.QUERY_VALUE)
+	if err != nil {
+		return [3]byte{}, err
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("AccentColor") // 0xAABBGGRR
+	if err != nil {
+		return [3]byte{}, err
+	}
+	return [3]byte{byte(v), byte(v >> 8), byte(v >> 16)}, nil
+}
+
+// tintIcon recolors the 32 bpp bitmap images of an ICO file with accent,
+// keeping their shading and transparency. Other images, such as PNG ones,
+// are left out.
+func tintIcon(ico []byte, accent [3]byte) ([]byte, error) {
+	if len(ico) < 6 {
+		return nil, errors.New("icon too short")
+	}
+	type image struct {
+		entry [16]byte
+		data  []byte
+	}
+	var images []image
+	count := int(binary.LittleEndian.Uint16(ico[4:]))
+	for i := range count {
+		e := ico[6+16*i:]
+		if len(e) < 16 {
+			return nil, errors.New("icon directory truncated")
+		}
+		size, offset := binary.LittleEndian.Uint32(e[8:]), binary.LittleEndian.Uint32(e[12:])
+		if uint64(offset)+uint64(size) > uint64(len(ico)) {
+			return nil, errors.New("icon image out of range")
+		}
+		data := bytes.Clone(ico[offset : offset+size])
+		if len(data) < 40 || binary.LittleEndian.Uint32(data) != 40 || binary.LittleEndian.Uint16(data[14:]) != 32 {
+			continue // not a 32 bpp BITMAPINFOHEADER image
+		}
+		w, h := int(binary.LittleEndian.Uint32(data[4:])), int(binary.LittleEndian.Uint32(data[8:]))/2
+		pixels := data[40:min(len(data), 40+w*h*4)]
+		for p := 0; p+4 <= len(pixels); p += 4 {
+			tintPixel(pixels[p:p+3], accent)
+		}
+		var img image
+		copy(img.entry[:], e[:16])
+		img.data = data
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return nil, errors.New("icon has no 32 bpp bitmap images")
+	}
+
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	le([3]uint16{0, 1, uint16(len(images))})
+	offset := uint32(6 + 16*len(images))
+	for _, img := range images {
+		b.Write(img.entry[:8])
+		le([2]uint32{uint32(len(img.data)), offset})
+		offset += uint32(len(img.data))
+	}
+	for _, img := range images {
+		b.Write(img.data)
+	}
+	return b.Bytes(), nil
+}
+
+// tintPixel maps the brightness of a BGR pixel onto accent: dark pixels
+// become darker shades of it and light ones lighter tints.
+func tintPixel(bgr []byte, accent [3]byte) {
+	lum := (299*int(bgr[2]) + 587*int(bgr[1]) + 114*int(bgr[0])) / 1000
+	for i, c := range [3]int{int(accent[2]), int(accent[1]), int(accent[0])} {
+		if lum < 128 {
+			bgr[i] = byte(c * lum / 128)
+		} else {
+			bgr[i] = byte(c + (255-c)*(lum-128)/127)
+		}
+	}
+}
+
+// accentChanged reports whether the accent color differs from the one the
+// cup was tinted with.
+func (a *app) accentChanged() bool {
+	accent, err := accentColor()
+	return err == nil && accent != a.accent
+}
+
+// startAccentWatcher creates the hidden window that hears about accent
+// color changes.
+func startAccentWatcher() {
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if _, err := createWindow(accentWatcherClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0, accentWndProc); err != nil {
+			logWarnf("could not watch for accent color changes: %v", err)
+			return
+		}
+		runMessageLoop(0)
+	}()
+}
+
+func accentWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case WM_DWMCOLORIZATIONCOLORCHANGED, WM_SETTINGCHANGE:
+		// The main loop compares the color, since WM_SETTINGCHANGE is
+		// also sent for unrelated settings.
+		select {
+		case accentChangedCh <- struct{}{}:
+		default:
+		}
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// loadCupIcon picks the icon for the active cup, tinted if accent_icon is
+// on. The caller shows it with applyState.
+func (a *app) loadCupIcon() {
+	a.cupIcon = iconData
+	if a.cfg.AccentIcon {
+		accent, err := accentColor()
+		a.accent = accent
+		if err == nil {
+			var ico []byte
+			if ico, err = tintIcon(iconData, accent); err == nil {
+				a.cupIcon = ico
+			}
+		}
+		if err != nil {
+			logWarnf("could not tint the tray icon with the accent color: %v", err)
+		}
+	}
+}
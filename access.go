@@ -0,0 +1,122 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "strings"
+
+// --- Access Prompts ---
+
+// Some features reach beyond the PC's own state: location conditions ask
+// the Windows location service where the machine is, calendar triggers
+// download a feed from the network, and a control API on a network
+// address can be reached by other machines. The first time settings.json
+// uses one of them, Espresso explains what it needs and asks. The answer
+// is stored in that feature's setting (location_access, calendar_access,
+// api.allow_lan), and a declined feature stays off with a warning in the
+// log instead of being asked about again.
+
+// accessPrompt is one feature that needs the user's permission.
+type accessPrompt struct {
+	key     string // locale key of the explanation and settings.json name
+	setting string
+	grant   **bool
+	detail  func(cfg Config) string // what needs access, or "" if nothing
+}
+
+// granted reports whether an access setting is set and true.
+func granted(b *bool) bool {
+	return b != nil && *b
+}
+
+func accessPrompts(cfg *Config) []accessPrompt {
+	return []accessPrompt{
+		{"access.location", "location_access", &cfg.LocationAccess, locationNeeds},
+		{"access.calendar", "calendar_access", &cfg.CalendarAccess, calendarNeeds},
+		{"access.lan", "api.allow_lan", &cfg.API.AllowLAN, lanNeeds},
+	}
+}
+
+// askAccess asks about each feature in use that has not been asked about
+// yet, and saves the answers.
+func (a *app) askAccess() {
+	changed := false
+	for _, p := range accessPrompts(&a.cfg) {
+		if *p.grant != nil {
+			continue
+		}
+		detail := p.detail(a.cfg)
+		if detail == "" {
+			continue
+		}
+		ok := confirm(tr("access.title"), tr(p.key, detail)+"\n\n"+tr("access.footer", p.setting))
+		*p.grant = &ok
+		changed = true
+		if ok {
+			logInfof("Access granted: %s", p.setting)
+		} else {
+			logWarnf("Access declined: %s; set it to true in settings.json to turn the feature on", p.setting)
+		}
+	}
+	if changed {
+		a.saveConfig()
+	}
+}
+
+// locationNeeds lists the places that enabled triggers depend on.
+func locationNeeds(cfg Config) string {
+	if !cfg.feature(featureTriggers) {
+		return ""
+	}
+	var places []string
+	for _, tc := range cfg.allTriggers() {
+		if !tc.Disabled && tc.Location != "" {
+			places = appendUnique(places, strings.TrimPrefix(tc.Location, "!"))
+		}
+	}
+	return strings.Join(places, ", ")
+}
+
+// calendarNeeds lists the calendar feeds that enabled triggers download.
+func calendarNeeds(cfg Config) string {
+	if !cfg.feature(featureTriggers) {
+		return ""
+	}
+	var feeds []string
+	for _, tc := range cfg.allTriggers() {
+		if !tc.Disabled && tc.Type == "calendar" && remoteCalendar(tc.Calendar) {
+			feeds = appendUnique(feeds, calendarHost(tc.Calendar))
+		}
+	}
+	return strings.Join(feeds, ", ")
+}
+
+// lanNeeds lists the network addresses the control API is set to listen
+// on.
+func lanNeeds(cfg Config) string {
+	if !cfg.feature(featureAPI) || !cfg.API.Enabled {
+		return ""
+	}
+	addrs, err := cfg.API.addresses()
+	if err != nil {
+		return ""
+	}
+	return strings.Join(lanAddresses(addrs), ", ")
+}
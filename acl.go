@@ -0,0 +1,185 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Config Permissions ---
+
+// The Espresso folder under %APPDATA% holds API token hashes and the guest
+// link key, so it is restricted to the user, SYSTEM and Administrators.
+// When an administrator sets the DWORD Managed under policyKey, a
+// settings.json that anyone else could have modified is not loaded.
+
+const (
+	fileWriteAccess = windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA | windows.FILE_WRITE_EA |
+		windows.FILE_WRITE_ATTRIBUTES | windows.DELETE | windows.WRITE_DAC | windows.WRITE_OWNER |
+		windows.GENERIC_WRITE | windows.GENERIC_ALL
+
+	INHERIT_ONLY_ACE = 0x08
+)
+
+// managedMode reports whether policy requires a trusted settings.json.
+func managedMode() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, policyKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("Managed")
+	return err == nil && v != 0
+}
+
+// configOwner is the user whose config this is, if not the current user:
+// the service runs as SYSTEM but reads the settings.json of the user who
+// installed it.
+var configOwner *windows.SID
+
+// trustedSIDs are the accounts that may write the config: the current user
+// (or configOwner), SYSTEM and Administrators.
+func trustedSIDs() ([]*windows.SID, error) {
+	owner := configOwner
+	if owner == nil {
+		u, err := windows.GetCurrentProcessToken().GetTokenUser()
+		if err != nil {
+			return nil, err
+		}
+		owner = u.User.Sid
+	}
+	sids := []*windows.SID{owner}
+	for _, t := range []windows.WELL_KNOWN_SID_TYPE{windows.WinLocalSystemSid, windows.WinBuiltinAdministratorsSid} {
+		sid, err := windows.CreateWellKnownSid(t)
+		if err != nil {
+			return nil, err
+		}
+		sids = append(sids, sid)
+	}
+	return sids, nil
+}
+
+func isTrusted(sid *windows.SID, trusted []*windows.SID) bool {
+	for _, t := range trusted {
+		if sid.Equals(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictToUser replaces the DACL of path, a file or folder, with full
+// control for the trusted accounts only, inherited by everything in it.
+func restrictToUser(path string) error {
+	sids, err := trustedSIDs()
+	if err != nil {
+		return err
+	}
+	entries := make([]windows.EXPLICIT_ACCESS, 0, len(sids))
+	for _, sid := range sids {
+		entries = append(entries, windows.EXPLICIT_ACCESS{
+			AccessPermissions: windows.GENERIC_ALL,
+			AccessMode:        windows.SET_ACCESS,
+			Inheritance:       windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT,
+			Trustee: windows.TRUSTEE{
+				TrusteeForm:  windows.TRUSTEE_IS_SID,
+				TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+				TrusteeValue: windows.TrusteeValueFromSID(sid),
+			},
+		})
+	}
+	acl, err := windows.ACLFromEntries(entries, nil)
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, acl, nil)
+}
+
+// untrustedWriter returns a description of an account outside
+// trustedSIDs that owns path or may write to it, or "" if there is none.
+func untrustedWriter(path string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	return untrustedWriterOf(sd)
+}
+
+// untrustedWriterOf is untrustedWriter for the security descriptor of a
+// file.
+func untrustedWriterOf(sd *windows.SECURITY_DESCRIPTOR) (string, error) {
+	trusted, err := trustedSIDs()
+	if err != nil {
+		return "", err
+	}
+
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return "", err
+	}
+	if !isTrusted(owner, trusted) {
+		return "owner " + owner.String(), nil
+	}
+
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return "", err
+	}
+	if dacl == nil {
+		return "everyone (no access control list)", nil
+	}
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, i, &ace); err != nil {
+			return "", err
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Header.AceFlags&INHERIT_ONLY_ACE != 0 ||
+			ace.Mask&fileWriteAccess == 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if !isTrusted(sid, trusted) {
+			if account, domain, _, err := sid.LookupAccount(""); err == nil {
+				return fmt.Sprintf(`%s\%s`, domain, account), nil
+			}
+			return sid.String(), nil
+		}
+	}
+	return "", nil
+}
+
+var hardenOnce sync.Once
+
+// hardenConfigDir restricts the Espresso folder, once per run.
+func hardenConfigDir(dir string) {
+	hardenOnce.Do(func() {
+		if err := restrictToUser(dir); err != nil {
+			logWarnf("could not restrict access to %s: %v", dir, err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// --- Activity Simulation ---
+
+// Some lock policies enforced by Group Policy ignore execution state
+// requests and lock the session after a period without input. Activity
+// simulation periodically injects input that changes nothing on screen,
+// a zero-distance mouse move or a press of F15 (which no keyboard has), so
+// Windows sees the user as active.
+//
+// Chat apps such as Teams and Slack show the user as away after a few
+// minutes without input. Modes with away_prevention press Shift instead,
+// which those apps count as activity, but only when there has been no
+// input for a while, so that it cannot interfere with typing.
+
+const (
+	simulateMouse    = "mouse"
+	simulateKey      = "key"
+	simulatePresence = "presence"
+	simulateNone     = "none"
+
+	// Well under the shortest idle timeout Group Policy allows (1 minute).
+	simulateInterval = 50 * time.Second
+
+	INPUT_MOUSE      = 0
+	INPUT_KEYBOARD   = 1
+	MOUSEEVENTF_MOVE = 0x0001
+	KEYEVENTF_KEYUP  = 0x0002
+	VK_F15           = 0x7E
+	VK_SHIFT         = 0x10
+)
+
+var (
+	procSendInput        = user32.NewProc("SendInput")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+	procGetTickCount     = modkernel32.NewProc("GetTickCount")
+)
+
+// lastInputInfo mirrors LASTINPUTINFO.
+type lastInputInfo struct {
+	Size uint32
+	Time uint32
+}
+
+// idleTime returns how long ago the last keyboard or mouse input was.
+func idleTime() time.Duration {
+	info := lastInputInfo{Size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0
+	}
+	now, _, _ := procGetTickCount.Call()
+	return time.Duration(uint32(now)-info.Time) * time.Millisecond
+}
+
+// mouseInput mirrors INPUT with a MOUSEINPUT, the largest member of the
+// union.
+type mouseInput struct {
+	Type      uint32
+	Dx, Dy    int32
+	MouseData uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+// keyboardInput mirrors INPUT with a KEYBDINPUT, padded to the size of the
+// union.
+type keyboardInput struct {
+	Type      uint32
+	Vk, Scan  uint16
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+	_         [8]byte
+}
+
+// parseSimulate validates a "simulate" setting: "mouse", "key",
+// "presence", or "none" or empty for no simulation.
+func parseSimulate(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", simulateNone:
+		return "", nil
+	case simulateMouse, simulateKey, simulatePresence:
+		return strings.ToLower(s), nil
+	default:
+		return "", fmt.Errorf("invalid simulate %q, use \"mouse\", \"key\", \"presence\" or \"none\"", s)
+	}
+}
+
+func sendActivity(method string) error {
+	var r uintptr
+	var err error
+	switch method {
+	case simulateMouse:
+		in := mouseInput{Type: INPUT_MOUSE, Flags: MOUSEEVENTF_MOVE}
+		r, _, err = procSendInput.Call(1, uintptr(unsafe.Pointer(&in)), unsafe.Sizeof(in))
+	case simulateKey:
+		in := [2]keyboardInput{
+			{Type: INPUT_KEYBOARD, Vk: VK_F15},
+			{Type: INPUT_KEYBOARD, Vk: VK_F15, Flags: KEYEVENTF_KEYUP},
+		}
+		r, _, err = procSendInput.Call(2, uintptr(unsafe.Pointer(&in[0])), unsafe.Sizeof(in[0]))
+	case simulatePresence:
+		if idleTime() < simulateInterval/2 {
+			return nil // the user is active
+		}
+		in := [2]keyboardInput{
+			{Type: INPUT_KEYBOARD, Vk: VK_SHIFT},
+			{Type: INPUT_KEYBOARD, Vk: VK_SHIFT, Flags: KEYEVENTF_KEYUP},
+		}
+		r, _, err = procSendInput.Call(2, uintptr(unsafe.Pointer(&in[0])), unsafe.Sizeof(in[0]))
+	default:
+		return nil
+	}
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// activitySimulator sends simulated input every simulateInterval while a
+// method is set.
+type activitySimulator struct {
+	mu     sync.Mutex
+	method string
+	stop   chan struct{}
+}
+
+// set switches the simulation to method, or stops it if method is empty.
+func (s *activitySimulator) set(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if method == s.method {
+		return
+	}
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.method = method
+	if method == "" {
+		return
+	}
+
+	stop := make(chan struct{})
+	s.stop = stop
+	go func() {
+		ticker := time.NewTicker(simulateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := sendActivity(method); err != nil {
+					logWarnf("activity simulation failed: %v", err)
+				}
+			}
+		}
+	}()
+}
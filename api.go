@@ -0,0 +1,500 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Control API ---
+
+const (
+	defaultAPIListen    = "127.0.0.1:7483"
+	defaultAPIRateLimit = 60 // requests per minute per client
+)
+
+// APIConfig configures the local HTTP control API. It is off by default and
+// only listens on the loopback interface unless AllowLAN is set.
+type APIConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Listen    string `json:"listen,omitempty"`     // host:port, default defaultAPIListen
+	Port      int    `json:"port,omitempty"`       // replaces the port of Listen
+	AllowLAN  *bool  `json:"allow_lan,omitempty"`  // required for non-loopback addresses; see askAccess
+	RateLimit int    `json:"rate_limit,omitempty"` // requests per minute per client
+
+	// Bind, if set, replaces Listen with several listeners. Each entry is
+	// an address ("10.0.5.2", "[::1]:7483") or the name of a network
+	// interface ("Ethernet 2"), which binds every address on it. Entries
+	// without a port use the port of Listen.
+	Bind []string `json:"bind,omitempty"`
+}
+
+// apiCall is a request forwarded to the main loop, which owns the app state.
+type apiCall struct {
+	action   string        // "status", "start", "stop", "extend", "guest" or "webhook"
+	token    string        // bearer token, empty for none
+	duration time.Duration // to start, or to add for "extend"
+	guest    guestLink
+	webhook  webhookRequest
+	reply    chan apiReply
+}
+
+type apiReply struct {
+	code int
+	body any
+}
+
+type apiStatus struct {
+	Active   bool   `json:"active"`
+	Mode     string `json:"mode"`
+	Infinite bool   `json:"infinite,omitempty"`
+	Paused   bool   `json:"paused,omitempty"`
+
+	// The time left in a timed session, in seconds, as an ISO 8601
+	// duration ("PT1H30M") and as shown in the tray ("1h 30m 0s"). EndsAt
+	// is omitted while paused.
+	RemainingS    int        `json:"remaining_s,omitempty"`
+	RemainingISO  string     `json:"remaining_iso,omitempty"`
+	RemainingText string     `json:"remaining_text,omitempty"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+
+	Source   string   `json:"source,omitempty"`
+	Note     string   `json:"note,omitempty"`
+	Triggers []string `json:"triggers,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// startAPI serves the control API if it is enabled, forwarding every
+// request to ch.
+func startAPI(cfg APIConfig, ch chan<- apiCall) {
+	if !cfg.Enabled {
+		return
+	}
+	addrs, err := cfg.addresses()
+	if err != nil {
+		logWarnf("control API disabled: %v", err)
+		return
+	}
+
+	if lan := lanAddresses(addrs); len(lan) > 0 {
+		list := strings.Join(lan, ", ")
+		if !granted(cfg.AllowLAN) {
+			logWarnf("control API disabled: %s is not a loopback address; set \"allow_lan\": true to listen on the network", list)
+			go showToast(tagSession, tr("api.lan_disabled_title"), tr("api.lan_disabled", list), iconPath())
+			return
+		}
+		logWarnf("control API listening on %s is reachable from other machines; tokens are sent in clear text", list)
+		go showToast(tagSession, tr("api.lan_title"), tr("api.lan", list), iconPath())
+	}
+
+	limit := cfg.RateLimit
+	if limit <= 0 {
+		limit = defaultAPIRateLimit
+	}
+	handler := newAPIHandler(ch, newRateLimiter(limit, time.Minute))
+	for _, addr := range addrs {
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+		}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil {
+				logWarnf("control API on %s stopped: %v", addr, err)
+			}
+		}()
+	}
+}
+
+// lanAddresses returns the addresses that are not loopback addresses.
+func lanAddresses(addrs []string) []string {
+	var lan []string
+	for _, addr := range addrs {
+		host, _, _ := net.SplitHostPort(addr)
+		if !isLoopbackHost(host) {
+			lan = append(lan, addr)
+		}
+	}
+	return lan
+}
+
+// addresses resolves Listen and Bind into the host:port pairs to listen on.
+func (cfg APIConfig) addresses() ([]string, error) {
+	listen := cfg.Listen
+	if listen == "" {
+		listen = defaultAPIListen
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", listen, err)
+	}
+	if cfg.Port != 0 {
+		if cfg.Port < 0 || cfg.Port > 65535 {
+			return nil, fmt.Errorf("invalid port %d", cfg.Port)
+		}
+		port = strconv.Itoa(cfg.Port)
+		listen = net.JoinHostPort(host, port)
+	}
+	if len(cfg.Bind) == 0 {
+		return []string{listen}, nil
+	}
+
+	var addrs []string
+	for _, entry := range cfg.Bind {
+		if _, _, err := net.SplitHostPort(entry); err == nil {
+			addrs = append(addrs, entry)
+			continue
+		}
+		if ip := net.ParseIP(strings.Trim(entry, "[]")); ip != nil {
+			addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+			continue
+		}
+
+		iface, err := net.InterfaceByName(entry)
+		if err != nil {
+			return nil, fmt.Errorf("bind entry %q is neither an address nor a network interface", entry)
+		}
+		ifAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses of %q: %w", entry, err)
+		}
+		n := len(addrs)
+		for _, a := range ifAddrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			host := ipnet.IP.String()
+			if ipnet.IP.IsLinkLocalUnicast() {
+				if ipnet.IP.To4() != nil {
+					continue
+				}
+				host += "%" + iface.Name // IPv6 link-local needs its zone
+			}
+			addrs = append(addrs, net.JoinHostPort(host, port))
+		}
+		if len(addrs) == n {
+			return nil, fmt.Errorf("network interface %q has no addresses", entry)
+		}
+	}
+	return addrs, nil
+}
+
+// isLoopbackHost reports whether host only accepts local connections. An
+// empty host listens on every interface.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newAPIHandler(ch chan<- apiCall, rl *rateLimiter) http.Handler {
+	mux := http.NewServeMux()
+	forward := func(w http.ResponseWriter, r *http.Request, c apiCall) {
+		c.token = strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		c.reply = make(chan apiReply, 1)
+		ch <- c
+		rep := <-c.reply
+		writeJSON(w, rep.code, rep.body)
+	}
+
+	// Endpoints are served under /v1 and, for clients such as Home
+	// Assistant's REST integration, at the root.
+	handle := func(path string, h http.HandlerFunc) {
+		mux.HandleFunc("/v1"+path, h)
+		mux.HandleFunc(path, h)
+	}
+
+	handle("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"use GET"})
+			return
+		}
+		forward(w, r, apiCall{action: "status"})
+	})
+	handle("/start", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"use POST"})
+			return
+		}
+		s, ok := requestDuration(w, r)
+		if !ok {
+			return
+		}
+		d := time.Duration(-1)
+		if s != "" && s != "infinite" {
+			var err error
+			if d, err = parseSessionDuration(s); err != nil {
+				writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+				return
+			}
+		}
+		forward(w, r, apiCall{action: "start", duration: d})
+	})
+	handle("/extend", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"use POST"})
+			return
+		}
+		s, ok := requestDuration(w, r)
+		if !ok {
+			return
+		}
+		d, err := parseSessionDuration(s)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{err.Error()})
+			return
+		}
+		forward(w, r, apiCall{action: "extend", duration: d})
+	})
+	handle("/guest", func(w http.ResponseWriter, r *http.Request) {
+		// Guest links are opened in a browser, so this GET changes state;
+		// the signature stands in for a token.
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"use GET"})
+			return
+		}
+		g, err := parseGuestLink(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c := apiCall{action: "guest", guest: g, reply: make(chan apiReply, 1)}
+		ch <- c
+		rep := <-c.reply
+		if e, ok := rep.body.(apiError); ok {
+			http.Error(w, e.Error, rep.code)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "Espresso is keeping this machine awake for %s.\n", formatFriendlyDuration(g.duration))
+	})
+	handle("/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{"use POST"})
+			return
+		}
+		forward(w, r, apiCall{action: "stop"})
+	})
+	handle("/webhook/", func(w http.ResponseWriter, r *http.Request) {
+		// Signed with the webhook's secret instead of a token.
+		c, ok := readWebhook(w, r)
+		if !ok {
+			return
+		}
+		c.reply = make(chan apiReply, 1)
+		ch <- c
+		rep := <-c.reply
+		writeJSON(w, rep.code, rep.body)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if !rl.allow(host) {
+			w.Header().Set("Retry-After", "60")
+			writeJSON(w, http.StatusTooManyRequests, apiError{"rate limit exceeded"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// requestDuration reads the "duration" of a JSON body, or of the query
+// string if the body has none. It writes the error response if the body is
+// invalid.
+func requestDuration(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body struct {
+		Duration string `json:"duration"`
+	}
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{"invalid JSON body"})
+			return "", false
+		}
+	}
+	if body.Duration == "" {
+		body.Duration = r.URL.Query().Get("duration")
+	}
+	return body.Duration, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleAPICall runs on the main loop. Reading the status is open to local
+// clients; changing state requires a valid token.
+func (a *app) handleAPICall(c apiCall) {
+	if c.action == "guest" {
+		if err := a.redeemGuestLink(c.guest); err != nil {
+			c.reply <- apiReply{http.StatusForbidden, apiError{err.Error()}}
+			return
+		}
+		a.startSession(EspressoMode{Duration: c.guest.duration}, sourceGuestLink)
+		if a.cfg.SharedMachine.NotifyRemote {
+			a.announceRemote()
+		} else {
+			go showToast(tagSession, tr("toast.started", a.modeName()),
+				tr("api.started_guest", formatFriendlyDuration(c.guest.duration)), iconPath())
+		}
+		c.reply <- apiReply{http.StatusOK, a.apiStatus()}
+		return
+	}
+	if c.action == "webhook" {
+		a.handleWebhook(c)
+		return
+	}
+	if c.action != "status" {
+		if len(a.cfg.APITokens) == 0 {
+			c.reply <- apiReply{http.StatusUnauthorized, apiError{"no API tokens exist; generate one from the tray menu"}}
+			return
+		}
+		t, ok := verifyAPIToken(a.cfg.APITokens, c.token)
+		if !ok {
+			c.reply <- apiReply{http.StatusUnauthorized, apiError{"missing or invalid token"}}
+			return
+		}
+
+		switch c.action {
+		case "start":
+			a.startSession(EspressoMode{Duration: c.duration}, sourceAPIPrefix+t.ID)
+			if a.cfg.SharedMachine.NotifyRemote {
+				a.announceRemote()
+			} else {
+				go showToast(tagSession, tr("toast.started", a.modeName()),
+					tr("api.started", formatFriendlyDuration(c.duration)), iconPath())
+			}
+		case "stop":
+			if a.active() {
+				a.resetState("stopped")
+				go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+			}
+		case "extend":
+			if !a.timed() {
+				c.reply <- apiReply{http.StatusConflict, apiError{"no timed session is running"}}
+				return
+			}
+			a.extend(c.duration)
+		}
+	}
+	c.reply <- apiReply{http.StatusOK, a.apiStatus()}
+}
+
+func (a *app) apiStatus() apiStatus {
+	s := apiStatus{Active: a.active(), Mode: a.modeName(), Infinite: a.infinite()}
+	if a.active() {
+		s.Source = a.sessionSource
+		s.Note = a.sessionNote
+		s.Paused = a.paused()
+		if a.timed() {
+			if !a.paused() {
+				end := a.sessionEnd().Truncate(time.Second)
+				s.EndsAt = &end
+			}
+			left := a.timeLeft().Truncate(time.Second)
+			s.RemainingS = int(left.Seconds())
+			s.RemainingISO = isoDuration(left)
+			s.RemainingText = formatDuration(left)
+		}
+	}
+	for name := range a.activeTriggers {
+		if a.triggerHolds(name) {
+			s.Triggers = append(s.Triggers, name)
+		}
+	}
+	sort.Strings(s.Triggers)
+	return s
+}
+
+// isoDuration formats d as an ISO 8601 duration, e.g. "PT2H5M30S".
+func isoDuration(d time.Duration) string {
+	if d < time.Second {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	secs := int(d.Seconds())
+	for _, u := range []struct {
+		n      int
+		suffix byte
+	}{{secs / 3600, 'H'}, {secs / 60 % 60, 'M'}, {secs % 60, 'S'}} {
+		if u.n > 0 {
+			b.WriteString(strconv.Itoa(u.n))
+			b.WriteByte(u.suffix)
+		}
+	}
+	return b.String()
+}
+
+// --- Rate Limiting ---
+
+// rateLimiter allows up to limit requests per client in each fixed window.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, clients: make(map[string]*rateWindow)}
+}
+
+func (rl *rateLimiter) allow(client string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	for k, w := range rl.clients {
+		if now.Sub(w.start) >= rl.window {
+			delete(rl.clients, k)
+		}
+	}
+	w, ok := rl.clients[client]
+	if !ok {
+		w = &rateWindow{start: now}
+		rl.clients[client] = w
+	}
+	w.count++
+	return w.count <= rl.limit
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Processor architecture ---
+
+// Espresso builds natively for windows/amd64 and windows/arm64. An x64
+// build still runs on ARM64 PCs (Surface Pro X, Snapdragon laptops) under
+// emulation, which works but is slower to start and reports some power
+// and display APIs differently, so the log says when that happens.
+
+const (
+	IMAGE_FILE_MACHINE_UNKNOWN = 0
+	IMAGE_FILE_MACHINE_I386    = 0x014c
+	IMAGE_FILE_MACHINE_AMD64   = 0x8664
+	IMAGE_FILE_MACHINE_ARM64   = 0xaa64
+)
+
+// nativeArch returns the architecture of the machine, in GOARCH terms,
+// which differs from runtime.GOARCH when running under emulation.
+var nativeArch = sync.OnceValue(func() string {
+	var process, native uint16
+	if err := windows.IsWow64Process2(windows.CurrentProcess(), &process, &native); err != nil {
+		// Before Windows 10 1709 there is no ARM64 emulation to detect.
+		return runtime.GOARCH
+	}
+	switch native {
+	case IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case IMAGE_FILE_MACHINE_I386:
+		return "386"
+	default:
+		return runtime.GOARCH
+	}
+})
+
+// logArchitecture notes at startup when this build is emulated.
+func logArchitecture() {
+	if native := nativeArch(); native != runtime.GOARCH {
+		logInfof("running the %s build under emulation on an %s PC; the %s build starts faster and uses less battery", runtime.GOARCH, native, native)
+	}
+}
+
+// procAvailable reports whether an optional API exists on this system.
+// LazyProc.Call panics when the procedure is missing, which happens on
+// older Windows versions, on Server Core and on some ARM64 images that
+// leave out components such as dxva2.dll, so call sites that can do
+// without an API check it first.
+func procAvailable(p *windows.LazyProc) bool {
+	if err := p.Find(); err != nil {
+		missingProcOnce(p.Name, err)
+		return false
+	}
+	return true
+}
+
+var (
+	missingProcsMu sync.Mutex
+	missingProcs   = map[string]bool{}
+)
+
+// missingProcOnce logs a missing API the first time it is asked for.
+func missingProcOnce(name string, err error) {
+	missingProcsMu.Lock()
+	defer missingProcsMu.Unlock()
+	if !missingProcs[name] {
+		missingProcs[name] = true
+		logInfof("%s is not available on this system: %v", name, err)
+	}
+}
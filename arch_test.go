@@ -0,0 +1,76 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+// captureLog returns what fn logs, without touching the log file.
+func captureLog(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, fail := os.Stdout, logFail
+	os.Stdout, logFail = w, true
+	defer func() { os.Stdout, logFail = stdout, fail }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestProcAvailableMissing(t *testing.T) {
+	p := windows.NewLazySystemDLL("kernel32.dll").NewProc("EspressoNoSuchProc")
+	out := captureLog(t, func() {
+		for range 2 {
+			if procAvailable(p) {
+				t.Error("procAvailable reports a missing procedure as available")
+			}
+		}
+	})
+	if n := strings.Count(out, "EspressoNoSuchProc is not available"); n != 1 {
+		t.Errorf("missing procedure logged %d times, want once:\n%s", n, out)
+	}
+}
+
+func TestProcAvailable(t *testing.T) {
+	p := windows.NewLazySystemDLL("kernel32.dll").NewProc("GetTickCount64")
+	out := captureLog(t, func() {
+		if !procAvailable(p) {
+			t.Error("procAvailable reports GetTickCount64 as missing")
+		}
+	})
+	if out != "" {
+		t.Errorf("available procedure logged:\n%s", out)
+	}
+}
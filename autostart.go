@@ -0,0 +1,101 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Start with Windows ---
+
+const (
+	runKey          = `Software\Microsoft\Windows\CurrentVersion\Run`
+	runValueName    = "Espresso"
+	sourceAutostart = "Windows startup"
+)
+
+// autostartCommand is the Run entry for this executable.
+func autostartCommand() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%s" --autostart`, exe), nil
+}
+
+// setAutostart adds or removes the Run entry that starts Espresso at login.
+func setAutostart(enabled bool) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	if !enabled {
+		if err := k.DeleteValue(runValueName); err != nil && err != registry.ErrNotExist {
+			return err
+		}
+		return nil
+	}
+	cmd, err := autostartCommand()
+	if err != nil {
+		return err
+	}
+	return k.SetStringValue(runValueName, cmd)
+}
+
+// syncAutostart makes the Run entry match enabled, updating it if the
+// executable has moved.
+func syncAutostart(enabled bool) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.QUERY_VALUE)
+	var current string
+	if err == nil {
+		current, _, _ = k.GetStringValue(runValueName)
+		k.Close()
+	}
+	want, err := autostartCommand()
+	if err != nil || (enabled && current == want) || (!enabled && current == "") {
+		return
+	}
+	if err := setAutostart(enabled); err != nil {
+		logWarnf("could not update the startup entry: %v", err)
+	}
+}
+
+// restoreLastMode starts the mode that was last used, if RestoreLastMode is
+// set. It is called when Windows starts Espresso at login.
+func (a *app) restoreLastMode() {
+	// A session interrupted by the restart takes precedence.
+	if !a.cfg.RestoreLastMode || a.cfg.LastMode == "" || a.pendingResume != nil {
+		return
+	}
+	for _, m := range modes {
+		if m.Name == a.cfg.LastMode {
+			a.startSession(m, sourceAutostart)
+			go showToast(tagSession, tr("toast.restored", m.Name), a.startedMessage(), iconPath())
+			return
+		}
+	}
+	logWarnf("last mode %q no longer exists", a.cfg.LastMode)
+}
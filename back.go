@@ -0,0 +1,78 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "time"
+
+// --- Until I'm Back ---
+
+// An Until I'm Back session keeps the system awake while the user is away,
+// with no time limit, and for back_minutes more once they return, so that
+// the PC is exactly as they left it when they sit down again. The user
+// counts as away once there has been no keyboard or mouse input for
+// backAwayAfter, since starting the session is input itself, and as back
+// at the first input after that. Such sessions never simulate activity,
+// which would look like the user returning.
+
+const (
+	backAwayAfter      = time.Minute
+	defaultBackMinutes = 10
+)
+
+// backMode is the Until I'm Back session.
+func backMode() EspressoMode {
+	return EspressoMode{Name: tr("back.mode"), Duration: -1, Simulate: simulateNone, Back: true}
+}
+
+// backSettle returns how long the session goes on once the user is back.
+func (cfg Config) backSettle() time.Duration {
+	if cfg.BackMinutes <= 0 {
+		return defaultBackMinutes * time.Minute
+	}
+	return time.Duration(cfg.BackMinutes) * time.Minute
+}
+
+// checkBack turns an Until I'm Back session into a timed one once the
+// user returns. It is called every tick.
+func (a *app) checkBack() {
+	if !a.active() || a.paused() || !a.sessionBack {
+		return
+	}
+	idle := idleTime()
+	if !a.backAway {
+		if a.backAway = idle >= backAwayAfter; a.backAway {
+			logInfof("User away; %s holds until they are back", a.modeName())
+		}
+		return
+	}
+	if idle >= backAwayAfter {
+		return
+	}
+
+	settle := a.cfg.backSettle()
+	a.sessionBack = false
+	now := time.Now()
+	a.session.EndAt(now.Add(settle), now)
+	logInfof("User back; %s ends in %s", a.modeName(), formatFriendlyDuration(settle))
+	a.applyState()
+	go showToast(tagSession, tr("back.title"),
+		tr("back.returned", formatFriendlyDuration(settle), a.sessionEnd().Format("15:04")), iconPath())
+}
@@ -0,0 +1,109 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "unsafe"
+
+// --- Battery-Aware Auto-Stop ---
+
+var (
+	procGetSystemPowerStatus = modkernel32.NewProc("GetSystemPowerStatus")
+)
+
+const (
+	AC_LINE_ONLINE      = 1
+	BATTERY_FLAG_NONE   = 128 // no system battery
+	BATTERY_PERCENT_UNK = 255
+)
+
+// BatteryConfig ends manual sessions to save a laptop's battery.
+type BatteryConfig struct {
+	// StopOnUnplug ends the session when the charger is disconnected.
+	StopOnUnplug bool `json:"stop_on_unplug,omitempty"`
+	// StopBelowPercent ends the session when, on battery, the charge
+	// drops below this level. 0 turns it off.
+	StopBelowPercent int `json:"stop_below_percent,omitempty"`
+}
+
+func (c BatteryConfig) enabled() bool {
+	return c.StopOnUnplug || c.StopBelowPercent > 0
+}
+
+// systemPowerStatus mirrors SYSTEM_POWER_STATUS.
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// powerState is the part of the power status that auto-stop looks at.
+type powerState struct {
+	known     bool
+	onBattery bool
+	percent   int // -1 if unknown
+}
+
+func readPowerState() powerState {
+	var s systemPowerStatus
+	if r, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); r == 0 || s.BatteryFlag == BATTERY_FLAG_NONE {
+		return powerState{}
+	}
+	p := powerState{known: true, onBattery: s.ACLineStatus != AC_LINE_ONLINE, percent: -1}
+	if s.BatteryLifePercent != BATTERY_PERCENT_UNK {
+		p.percent = int(s.BatteryLifePercent)
+	}
+	return p
+}
+
+// checkBattery ends the manual session when the charger is unplugged or the
+// battery runs low during it. Only changes count, so a session started on
+// battery, or already below the threshold, is left alone until the next
+// one.
+func (a *app) checkBattery() {
+	if !a.cfg.Battery.enabled() {
+		return
+	}
+	now, last := readPowerState(), a.lastPower
+	a.lastPower = now
+	if !a.active() || a.paused() || !now.known || !last.known {
+		return
+	}
+
+	var why string
+	threshold := a.cfg.Battery.StopBelowPercent
+	switch {
+	case a.cfg.Battery.StopOnUnplug && now.onBattery && !last.onBattery:
+		why = tr("battery.unplugged")
+	case threshold > 0 && now.onBattery && now.percent >= 0 && now.percent < threshold &&
+		(!last.onBattery || last.percent >= threshold):
+		why = tr("battery.low", now.percent)
+	default:
+		return
+	}
+
+	name := a.modeName()
+	a.resetState("battery")
+	logInfof("Stopped %s: %s", name, why)
+	go showToast(tagSession, tr("battery.title", name), why+"\n"+a.releasedMessage(), icoffPath())
+}
@@ -0,0 +1,98 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// --- What's New ---
+
+// CHANGELOG.md is embedded so that, after an update, Espresso can show
+// what changed since the version the user last ran. Each release is a
+// "## <version>" section, newest first; the first one is this build's
+// version.
+
+//go:embed CHANGELOG.md
+var changelog string
+
+type release struct {
+	version string
+	notes   string
+}
+
+func releases() []release {
+	var rs []release
+	for _, part := range strings.Split(changelog, "\n## ")[1:] {
+		version, notes, _ := strings.Cut(part, "\n")
+		rs = append(rs, release{version: strings.TrimSpace(version), notes: strings.TrimSpace(notes)})
+	}
+	return rs
+}
+
+// appVersion is the newest version in the changelog.
+func appVersion() string {
+	if rs := releases(); len(rs) > 0 {
+		return rs[0].version
+	}
+	return ""
+}
+
+// whatsNew returns the notes of every release newer than since, or all of
+// them if since is unknown.
+func whatsNew(since string) string {
+	var b strings.Builder
+	for _, r := range releases() {
+		if r.version == since {
+			break
+		}
+		b.WriteString(tr("whatsnew.version", r.version) + "\n" + r.notes + "\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// announceUpdate shows a notification the first time a new version runs,
+// with a button that opens its release notes. Fresh installs only record
+// the version.
+func (a *app) announceUpdate(upgraded bool) {
+	current := appVersion()
+	if a.cfg.LastSeenVersion == current {
+		return
+	}
+	previous := a.cfg.LastSeenVersion
+	a.cfg.LastSeenVersion = current
+	a.saveConfig()
+	if !upgraded {
+		return
+	}
+	link := urlScheme + "://whatsnew"
+	if previous != "" {
+		link += "?since=" + previous
+	}
+	go showToastActions(tagReminder, tr("whatsnew.title", current), tr("whatsnew.toast"), iconPath(),
+		[]toastAction{{Label: tr("whatsnew.button"), Arguments: link}})
+}
+
+// showWhatsNew shows the release notes since version since.
+func showWhatsNew(since string) {
+	showMessage(tr("whatsnew.window", appVersion()), whatsNew(since))
+}
@@ -0,0 +1,211 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"espresso/pkg/espresso"
+)
+
+// --- CI Mode ---
+
+// "espresso ci [flags] -- command [args]" keeps a build agent awake while
+// a command runs, for at most --timeout, and exits with the command's exit
+// code. It runs on its own, without the tray, notifications or prompts,
+// and does not touch the session of a running instance.
+
+const (
+	defaultCITimeout = 4 * time.Hour
+	ciExitTimeout    = 124 // as GNU timeout
+	ciExitNoStart    = 127
+)
+
+// ciStatus is printed to stderr, as one JSON object per line with --json.
+type ciStatus struct {
+	Event     string   `json:"event"` // "started" or "finished"
+	Command   []string `json:"command"`
+	Keep      []string `json:"keep"`
+	Timeout   string   `json:"timeout,omitempty"`
+	PID       int      `json:"pid,omitempty"`
+	ExitCode  *int     `json:"exit_code,omitempty"`
+	TimedOut  bool     `json:"timed_out,omitempty"`
+	DurationS float64  `json:"duration_s,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// runCI runs "espresso ci" with the arguments after "ci" and returns the
+// process exit code.
+func runCI(args []string) int {
+	// Build agents usually redirect the output; only use the parent's
+	// console if they do not.
+	if _, err := os.Stderr.Stat(); err != nil {
+		attachConsole()
+	}
+
+	var usage bytes.Buffer
+	fs := flag.NewFlagSet("espresso ci", flag.ContinueOnError)
+	fs.SetOutput(&usage)
+	timeout := fs.String("timeout", defaultCITimeout.String(), "stop the command and allow sleep after this `duration`, or \"none\"")
+	keep := fs.String("keep", "system", "keep \"system\", \"display\" or \"system,display\" awake")
+	asJSON := fs.Bool("json", false, "print status as JSON lines on stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(&usage, "Usage: espresso ci [flags] -- command [args]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(os.Stderr, usage.String())
+			return 0
+		}
+		fmt.Fprint(os.Stderr, usage.String())
+		return 2
+	}
+	command := fs.Args()
+	if len(command) == 0 {
+		fmt.Fprintln(os.Stderr, "espresso ci: no command given")
+		return 2
+	}
+	flags, err := parseKeep(strings.Split(*keep, ","))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "espresso ci:", err)
+		return 2
+	}
+	var limit time.Duration
+	if !strings.EqualFold(*timeout, "none") {
+		if limit, err = time.ParseDuration(*timeout); err != nil || limit <= 0 {
+			fmt.Fprintf(os.Stderr, "espresso ci: invalid timeout %q\n", *timeout)
+			return 2
+		}
+	}
+
+	report := func(s ciStatus) {
+		s.Command, s.Keep = command, keepList(flags)
+		if limit > 0 {
+			s.Timeout = limit.String()
+		}
+		if *asJSON {
+			data, _ := json.Marshal(s)
+			fmt.Fprintln(os.Stderr, string(data))
+			return
+		}
+		switch {
+		case s.Error != "":
+			fmt.Fprintln(os.Stderr, "espresso ci:", s.Error)
+		case s.Event == "started":
+			fmt.Fprintf(os.Stderr, "espresso ci: keeping %s awake while %s runs\n", strings.Join(s.Keep, " and "), command[0])
+		case s.TimedOut:
+			fmt.Fprintf(os.Stderr, "espresso ci: %s timed out after %s\n", command[0], s.Timeout)
+		default:
+			fmt.Fprintf(os.Stderr, "espresso ci: %s exited with code %d after %s\n", command[0], *s.ExitCode, formatDuration(time.Duration(s.DurationS*float64(time.Second))))
+		}
+	}
+
+	engine := espresso.NewEngine(sleeper)
+	defer engine.Close()
+	if err := engine.Start(espresso.Mode{Name: "ci", Duration: limit, Flags: espresso.Flags(flags)}); err != nil {
+		report(ciStatus{Event: "finished", Error: err.Error()})
+		return 1
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		report(ciStatus{Event: "finished", Error: err.Error()})
+		return ciExitNoStart
+	}
+	job, err := newKillJob(cmd.Process.Pid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "espresso ci: warning: child processes will not be stopped on timeout: %v\n", err)
+	}
+	report(ciStatus{Event: "started", PID: cmd.Process.Pid})
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	status := ciStatus{Event: "finished"}
+	select {
+	case err = <-done:
+	case <-engine.Expired():
+		status.TimedOut = true
+		if job != 0 {
+			windows.TerminateJobObject(job, ciExitTimeout)
+		} else {
+			cmd.Process.Kill()
+		}
+		err = <-done
+	}
+	status.DurationS = time.Since(started).Round(time.Millisecond).Seconds()
+
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case status.TimedOut:
+		code = ciExitTimeout
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		status.Error = err.Error()
+		code = 1
+	}
+	status.ExitCode = &code
+	report(status)
+	return code
+}
+
+// newKillJob puts process pid in a job object that ends it and every
+// process it starts when terminated or when Espresso exits.
+func newKillJob(pid int) (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return 0, err
+	}
+	proc, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return 0, err
+	}
+	defer windows.CloseHandle(proc)
+	if err := windows.AssignProcessToJobObject(job, proc); err != nil {
+		windows.CloseHandle(job)
+		return 0, err
+	}
+	return job, nil
+}
@@ -0,0 +1,268 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Command Line ---
+
+// commandLine is a parsed command line. The flags control a session in the
+// running instance; positional arguments are deep links.
+type commandLine struct {
+	start  string // duration, or "infinite"
+	stop   bool
+	status bool
+	keep   string
+	onEnd  string
+	note   string
+	dryRun bool
+	links  []string
+
+	autostart bool // started by Windows at login
+}
+
+// errHelp reports that --help was given; the usage text is its message.
+type errHelp string
+
+func (e errHelp) Error() string { return string(e) }
+
+func parseCommandLine(args []string) (commandLine, error) {
+	var c commandLine
+	var usage bytes.Buffer
+	fs := flag.NewFlagSet("espresso", flag.ContinueOnError)
+	fs.SetOutput(&usage)
+	fs.StringVar(&c.start, "start", "", "start a session of this `duration` (e.g. 2h, 90m, infinite)")
+	fs.BoolVar(&c.stop, "stop", false, "stop the current session")
+	fs.BoolVar(&c.status, "status", false, "print what is keeping the system awake")
+	fs.StringVar(&c.keep, "keep", "", "with --start: keep \"system\", \"display\" or \"system,display\" awake")
+	fs.StringVar(&c.onEnd, "on-end", "", "with --start: `action` when the session ends ("+strings.Join(endActions, ", ")+")")
+	fs.StringVar(&c.note, "note", "", "with --start: note shown with the session")
+	fs.BoolVar(&c.dryRun, "dry-run", false, "with --start: describe what the session would do, without starting it")
+	fs.BoolVar(&c.autostart, "autostart", false, "start in the tray, restoring the last mode if restore_last_mode is set")
+	fs.BoolVar(&c.autostart, "minimized", false, "same as --autostart")
+	fs.Usage = func() {
+		fmt.Fprintf(&usage, "Usage: espresso [flags] [espresso://link ...]\n       espresso ci [flags] -- command [args]\n       espresso --service [install|uninstall|start|stop]\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return c, errHelp(usage.String())
+		}
+		return c, errors.New(strings.TrimSpace(usage.String()))
+	}
+	for _, arg := range fs.Args() {
+		if !isDeepLink(arg) {
+			return c, fmt.Errorf("unexpected argument %q", arg)
+		}
+		c.links = append(c.links, arg)
+	}
+
+	n := 0
+	for _, set := range []bool{c.start != "", c.stop, c.status} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return c, errors.New("use only one of --start, --stop and --status")
+	}
+	if c.start == "" && (c.keep != "" || c.onEnd != "" || c.note != "" || c.dryRun) {
+		return c, errors.New("--keep, --on-end, --note and --dry-run need --start")
+	}
+	return c, nil
+}
+
+// mode returns the session requested by --start.
+func (c commandLine) mode() (EspressoMode, error) {
+	var m EspressoMode
+	var err error
+	m.Duration = -1
+	if !strings.EqualFold(c.start, "infinite") {
+		if m.Duration, err = parseSessionDuration(c.start); err != nil {
+			return m, err
+		}
+	}
+	if c.keep != "" {
+		if m.Flags, err = parseKeep(strings.Split(c.keep, ",")); err != nil {
+			return m, err
+		}
+	}
+	if m.OnEnd, err = parseEndAction(c.onEnd); err != nil {
+		return m, err
+	}
+	m.Note = c.note
+	return m, nil
+}
+
+// dryRunOutput is what --dry-run prints for a session of mode m.
+func dryRunOutput(a *app, m EspressoMode) string {
+	return "Dry run; nothing was started.\n\n" + a.describePlan(a.planSession(m, time.Now()))
+}
+
+// runDryRun prints what --dry-run describes when Espresso is not running,
+// from settings.json alone. It returns the process exit code.
+func runDryRun(c commandLine) int {
+	attachConsole()
+	m, err := c.mode()
+	if err != nil {
+		return printReply(ipcReply{Error: err.Error()})
+	}
+	a := &app{cfg: loadConfig(), activeTriggers: make(map[string]*activeTrigger)}
+	return printReply(ipcReply{Output: dryRunOutput(a, m)})
+}
+
+// runRemote sends the command line to the running instance and prints its
+// reply. It returns the process exit code.
+func runRemote(args []string) int {
+	attachConsole()
+	rep, err := forwardArgs(args)
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	return printReply(rep)
+}
+
+// printReply prints a command reply and returns the process exit code.
+func printReply(rep ipcReply) int {
+	if rep.Output != "" {
+		fmt.Println(rep.Output)
+	}
+	if rep.Error != "" {
+		fmt.Fprintln(os.Stderr, "espresso:", rep.Error)
+		return 1
+	}
+	return 0
+}
+
+const ATTACH_PARENT_PROCESS = ^uintptr(0) // (DWORD)-1
+
+var procAttachConsole = modkernel32.NewProc("AttachConsole")
+
+// attachConsole connects stdout and stderr to the console of the shell that
+// started Espresso. It is built as a GUI program, so it has none of its own.
+func attachConsole() {
+	if r, _, _ := procAttachConsole.Call(ATTACH_PARENT_PROCESS); r == 0 {
+		return
+	}
+	name, _ := windows.UTF16PtrFromString("CONOUT$")
+	h, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return
+	}
+	out := os.NewFile(uintptr(h), "CONOUT$")
+	os.Stdout, os.Stderr = out, out
+}
+
+// --- Command Handling ---
+
+// handleArgs acts on a command line passed at startup or forwarded by a
+// later instance. Deep links are confirmed first, since any web page can
+// open one; confirmed modes are sent on linkCh.
+func (a *app) handleArgs(args []string, linkCh chan<- EspressoMode) ipcReply {
+	c, err := parseCommandLine(args)
+	if err != nil {
+		return ipcReply{Error: err.Error()}
+	}
+
+	var rep ipcReply
+	switch {
+	case c.autostart && c.start == "":
+		// A second instance started at login has nothing to do.
+		if !a.active() {
+			a.restoreLastMode()
+		}
+	case c.dryRun:
+		m, err := c.mode()
+		if err != nil {
+			return ipcReply{Error: err.Error()}
+		}
+		rep.Output = dryRunOutput(a, m)
+	case c.start != "":
+		m, err := c.mode()
+		if err != nil {
+			return ipcReply{Error: err.Error()}
+		}
+		a.startSession(m, sourceCLI)
+		msg := a.startedMessage()
+		go showToast(tagSession, tr("toast.started", a.modeName()), msg, iconPath())
+		rep.Output = fmt.Sprintf("%s mode started. %s", a.modeName(), msg)
+	case c.stop:
+		if a.active() {
+			a.resetState("stopped")
+			go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+		}
+		rep.Output = a.releasedMessage()
+	case c.status:
+		rep.Output = a.statusReport()
+	}
+
+	for _, link := range c.links {
+		if err := a.openDeepLink(link, linkCh); err != nil {
+			go showMessage("Espresso", tr("link.failed", link, err))
+		}
+	}
+	return rep
+}
+
+func (a *app) openDeepLink(link string, linkCh chan<- EspressoMode) error {
+	action, q, err := splitDeepLink(link)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "preset":
+		m, err := parsePresetLink(q)
+		if err != nil {
+			return err
+		}
+		msg := tr("link.confirm", describeMode(m, a.defaultSessionFlags()))
+		go func() {
+			if confirm("Espresso", msg) {
+				linkCh <- m
+			}
+		}()
+	case "release":
+		return a.releaseRemote(q.Get("nonce"))
+	case "extend":
+		return a.extendFromLink(q.Get("by"), q.Get("nonce"))
+	case "stop":
+		return a.stopFromLink(q.Get("nonce"))
+	case "cancelend":
+		return a.cancelEndFromLink(q.Get("nonce"))
+	case "whatsnew":
+		go showWhatsNew(q.Get("since"))
+	default:
+		return fmt.Errorf("unknown link action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Clipboard ---
+
+var (
+	procOpenClipboard    = user32.NewProc("OpenClipboard")
+	procCloseClipboard   = user32.NewProc("CloseClipboard")
+	procEmptyClipboard   = user32.NewProc("EmptyClipboard")
+	procSetClipboardData = user32.NewProc("SetClipboardData")
+
+	procGlobalAlloc   = modkernel32.NewProc("GlobalAlloc")
+	procGlobalFree    = modkernel32.NewProc("GlobalFree")
+	procGlobalLock    = modkernel32.NewProc("GlobalLock")
+	procGlobalUnlock  = modkernel32.NewProc("GlobalUnlock")
+	procRtlMoveMemory = modkernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	CF_UNICODETEXT = 13
+	GMEM_MOVEABLE  = 0x0002
+)
+
+// copyToClipboard replaces the clipboard contents with text.
+func copyToClipboard(text string) error {
+	u, err := windows.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+
+	if r, _, err := procOpenClipboard.Call(0); r == 0 {
+		return err
+	}
+	defer procCloseClipboard.Call()
+	procEmptyClipboard.Call()
+
+	size := uintptr(len(u)) * unsafe.Sizeof(u[0])
+	h, _, err := procGlobalAlloc.Call(GMEM_MOVEABLE, size)
+	if h == 0 {
+		return err
+	}
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		procGlobalFree.Call(h)
+		return err
+	}
+	procRtlMoveMemory.Call(p, uintptr(unsafe.Pointer(&u[0])), size)
+	procGlobalUnlock.Call(h)
+
+	// On success the clipboard owns the memory.
+	if r, _, _ := procSetClipboardData.Call(CF_UNICODETEXT, h); r == 0 {
+		procGlobalFree.Call(h)
+		return errors.New("SetClipboardData failed")
+	}
+	return nil
+}
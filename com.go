@@ -0,0 +1,115 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Minimal COM Support ---
+
+var (
+	modole32             = windows.NewLazySystemDLL("ole32.dll")
+	procCoCreateInstance = modole32.NewProc("CoCreateInstance")
+
+	modoleaut32        = windows.NewLazySystemDLL("oleaut32.dll")
+	procSysAllocString = modoleaut32.NewProc("SysAllocString")
+	procSysFreeString  = modoleaut32.NewProc("SysFreeString")
+)
+
+const (
+	CLSCTX_INPROC_SERVER = 0x1
+	CLSCTX_LOCAL_SERVER  = 0x4
+	RPC_E_CHANGED_MODE   = 0x80010106
+	S_FALSE              = 0x1
+)
+
+// comObject is a raw COM interface pointer. Methods are called by their
+// vtable index, counting the three IUnknown methods (and the four
+// IDispatch methods for dual interfaces).
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+func (o *comObject) call(method int, args ...uintptr) error {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	if int32(r) < 0 {
+		return fmt.Errorf("HRESULT 0x%08X", uint32(r))
+	}
+	return nil
+}
+
+func (o *comObject) release() {
+	if o != nil {
+		syscall.SyscallN(o.vtbl[2], uintptr(unsafe.Pointer(o)))
+	}
+}
+
+// withCOM runs fn on a locked OS thread with COM initialized for it.
+func withCOM(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED)
+	if err != nil {
+		if errno, ok := err.(syscall.Errno); !ok || (errno != S_FALSE && errno != RPC_E_CHANGED_MODE) {
+			return fmt.Errorf("CoInitializeEx failed: %w", err)
+		}
+	}
+	if err == nil || err == syscall.Errno(S_FALSE) {
+		defer windows.CoUninitialize()
+	}
+	return fn()
+}
+
+func createInstance(clsid, iid *windows.GUID) (*comObject, error) {
+	var obj *comObject
+	r, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(clsid)),
+		0,
+		CLSCTX_INPROC_SERVER|CLSCTX_LOCAL_SERVER,
+		uintptr(unsafe.Pointer(iid)),
+		uintptr(unsafe.Pointer(&obj)))
+	if int32(r) < 0 {
+		return nil, fmt.Errorf("CoCreateInstance failed: HRESULT 0x%08X", uint32(r))
+	}
+	return obj, nil
+}
+
+// bstr is a BSTR allocated with SysAllocString. Free it with free.
+type bstr uintptr
+
+func newBSTR(s string) bstr {
+	p, _ := windows.UTF16PtrFromString(s)
+	r, _, _ := procSysAllocString.Call(uintptr(unsafe.Pointer(p)))
+	return bstr(r)
+}
+
+func (b bstr) free() {
+	if b != 0 {
+		procSysFreeString.Call(uintptr(b))
+	}
+}
@@ -0,0 +1,169 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Deep Links ---
+
+// A deep link starts a fully specified session, so that ready-made presets
+// can be shared as URLs:
+//
+//	espresso://preset?name=NightRender&duration=5h&keep=system&on_end=sleep&note=Render%20farm
+//
+// name may refer to a configured mode; the other parameters override it.
+// duration accepts the formats of parseSessionDuration or "infinite", keep
+// is a comma-separated keep list and on_end an end action.
+
+const urlScheme = "espresso"
+
+// registerURLScheme points espresso:// links at this executable for the
+// current user.
+func registerURLScheme() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := fmt.Sprintf(`"%s" "%%1"`, exe)
+
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, `Software\Classes\`+urlScheme, registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.SetStringValue("", "URL:Espresso"); err != nil {
+		return err
+	}
+	if err := k.SetStringValue("URL Protocol", ""); err != nil {
+		return err
+	}
+
+	icon, _, err := registry.CreateKey(k, "DefaultIcon", registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer icon.Close()
+	if err := icon.SetStringValue("", exe+",0"); err != nil {
+		return err
+	}
+
+	cmd, _, err := registry.CreateKey(k, `shell\open\command`, registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer cmd.Close()
+	return cmd.SetStringValue("", command)
+}
+
+// isDeepLink reports whether a command-line argument is an espresso:// link.
+func isDeepLink(arg string) bool {
+	return strings.HasPrefix(strings.ToLower(arg), urlScheme+":")
+}
+
+// splitDeepLink returns the action of a deep link, e.g. "preset", and its
+// parameters.
+func splitDeepLink(raw string) (string, url.Values, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid link: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, urlScheme) {
+		return "", nil, fmt.Errorf("not an %s:// link", urlScheme)
+	}
+	return strings.ToLower(u.Host + strings.TrimSuffix(u.Path, "/")), u.Query(), nil
+}
+
+// parsePresetLink converts the parameters of a preset link into the mode
+// they describe.
+func parsePresetLink(q url.Values) (EspressoMode, error) {
+	var m EspressoMode
+	var err error
+
+	m.Name = q.Get("name")
+	hasDuration := false
+	for _, p := range modes {
+		if m.Name != "" && strings.EqualFold(p.Name, m.Name) {
+			m = p
+			hasDuration = true
+			break
+		}
+	}
+
+	if s := q.Get("duration"); s != "" {
+		if strings.EqualFold(s, "infinite") {
+			m.Duration = -1
+		} else if m.Duration, err = parseSessionDuration(s); err != nil {
+			return m, err
+		}
+		hasDuration = true
+	}
+	if !hasDuration {
+		return m, fmt.Errorf("the link names no known mode and has no duration")
+	}
+
+	if s := q.Get("keep"); s != "" {
+		if m.Flags, err = parseKeep(strings.Split(s, ",")); err != nil {
+			return m, err
+		}
+	}
+	if q.Has("on_end") {
+		if m.OnEnd, err = parseEndAction(q.Get("on_end")); err != nil {
+			return m, err
+		}
+	}
+	if q.Has("note") {
+		m.Note = q.Get("note")
+	}
+	if m.Name == "" {
+		m.Name = "Shared Preset"
+	}
+	return m, nil
+}
+
+// describeMode summarizes a mode for a confirmation prompt.
+func describeMode(m EspressoMode, defaultFlags uint32) string {
+	var b strings.Builder
+	b.WriteString(tr("link.mode", m.Name) + "\n")
+	if m.Duration < 0 {
+		b.WriteString(tr("link.infinite") + "\n")
+	} else {
+		b.WriteString(tr("link.duration", formatFriendlyDuration(m.Duration)) + "\n")
+	}
+	flags := m.Flags
+	if flags == 0 {
+		flags = defaultFlags
+	}
+	b.WriteString(tr("link.keep", keepName(flags)) + "\n")
+	if m.OnEnd != "" {
+		b.WriteString(tr("link.on_end", endActionName(m.OnEnd)) + "\n")
+	}
+	if m.Note != "" {
+		b.WriteString(tr("link.note", m.Note) + "\n")
+	}
+	return b.String()
+}
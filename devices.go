@@ -0,0 +1,185 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Device Changes ---
+
+// Docking, closing the lid and unplugging the charger often explain why a
+// session or trigger ended, e.g. a trigger limited to a docked laptop. A
+// hidden window hears about display, lid and power source changes and
+// records them in the event log, where the session history and the
+// diagnostics connect them to whatever let go soon after. Docking shows
+// as a change of displays.
+
+var procRegisterPowerSettingNotification = user32.NewProc("RegisterPowerSettingNotification")
+
+var GUID_LIDSWITCH_STATE_CHANGE = windows.GUID{Data1: 0xba3e0f4d, Data2: 0xb817, Data3: 0x4094, Data4: [8]byte{0xa2, 0xd1, 0xd5, 0x63, 0x79, 0xe6, 0xa0, 0xf3}}
+
+const (
+	WM_DISPLAYCHANGE            = 0x007E
+	PBT_APMPOWERSTATUSCHANGE    = 0x000A
+	PBT_POWERSETTINGCHANGE      = 0x8013
+	DEVICE_NOTIFY_WINDOW_HANDLE = 0
+
+	deviceWatcherClassName = "EspressoDeviceWatcher"
+)
+
+// Devices in device_changed events.
+const (
+	deviceDisplays = "displays"
+	deviceLid      = "lid"
+	devicePower    = "power"
+)
+
+// powerBroadcastSetting mirrors POWERBROADCAST_SETTING with the DWORD of
+// data the lid switch sends.
+type powerBroadcastSetting struct {
+	PowerSetting windows.GUID
+	DataLength   uint32
+	Data         uint32
+}
+
+// deviceWatcher holds the last known state of each device. It is only
+// touched on the window thread.
+type deviceWatcher struct {
+	displays string
+	lid      string
+	power    string
+}
+
+// startDeviceWatcher creates the hidden window that records device
+// changes.
+func startDeviceWatcher() {
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		w := &deviceWatcher{power: powerSource()}
+		w.displays, _ = displaysState()
+		hwnd, err := createWindow(deviceWatcherClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0, w.wndProc)
+		if err != nil {
+			logWarnf("could not watch for device changes: %v", err)
+			return
+		}
+		// Windows sends the current lid state at once, then every change.
+		if procAvailable(procRegisterPowerSettingNotification) {
+			procRegisterPowerSettingNotification.Call(uintptr(hwnd),
+				uintptr(unsafe.Pointer(&GUID_LIDSWITCH_STATE_CHANGE)), DEVICE_NOTIFY_WINDOW_HANDLE)
+		}
+		runMessageLoop(0)
+	}()
+}
+
+func (w *deviceWatcher) wndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	switch {
+	case msg == WM_DISPLAYCHANGE:
+		state, detail := displaysState()
+		w.changed(deviceDisplays, &w.displays, state, detail)
+	case msg == WM_POWERBROADCAST && wParam == PBT_APMPOWERSTATUSCHANGE:
+		w.changed(devicePower, &w.power, powerSource(), "")
+		return 1
+	case msg == WM_POWERBROADCAST && wParam == PBT_POWERSETTINGCHANGE:
+		var s powerBroadcastSetting
+		procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&s)), lParam, unsafe.Sizeof(s))
+		if s.PowerSetting == GUID_LIDSWITCH_STATE_CHANGE {
+			state := "open"
+			if s.Data == 0 {
+				state = "closed"
+			}
+			w.changed(deviceLid, &w.lid, state, "")
+		}
+		return 1
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// changed records state for device if it differs from *last. The first
+// state heard is only remembered.
+func (w *deviceWatcher) changed(device string, last *string, state, detail string) {
+	if state == *last || state == "" {
+		return
+	}
+	first := *last == ""
+	*last = state
+	if first {
+		return
+	}
+	logInfof("Device change: %s %s", device, state)
+	logDeviceChanged(device, state, detail)
+}
+
+// displaysState describes the displays, e.g. "2 displays", with a list
+// of them as detail.
+func displaysState() (state, detail string) {
+	ds := listDisplays()
+	defer closeDisplays(ds)
+	parts := make([]string, len(ds))
+	for i, d := range ds {
+		parts[i] = fmt.Sprintf("%d: %s", d.number, d.name)
+		if d.primary {
+			parts[i] += " (primary)"
+		}
+	}
+	state = fmt.Sprintf("%d displays", len(ds))
+	if len(ds) == 1 {
+		state = "1 display"
+	}
+	return state, strings.Join(parts, ", ")
+}
+
+// powerSource returns "ac" or "battery", or "" if the PC has no battery.
+func powerSource() string {
+	switch p := readPowerState(); {
+	case !p.known:
+		return ""
+	case p.onBattery:
+		return "battery"
+	default:
+		return "ac"
+	}
+}
+
+// describeDeviceChange says what e, a device_changed event, was, e.g. "the
+// lid was closed".
+func describeDeviceChange(e event) string {
+	switch e.Device {
+	case deviceLid:
+		return "the lid was " + e.State
+	case devicePower:
+		if e.State == "battery" {
+			return "the charger was unplugged"
+		}
+		return "the charger was plugged in"
+	case deviceDisplays:
+		return "the displays changed to " + e.State
+	default:
+		return e.Device + " changed to " + e.State
+	}
+}
@@ -0,0 +1,198 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Diagnostics Bundle ---
+
+// powerEventQuery selects sleep, wake and power request events from the
+// System event log.
+const powerEventQuery = "*[System[Provider[@Name='Microsoft-Windows-Kernel-Power' or " +
+	"@Name='Microsoft-Windows-Power-Troubleshooter']]]"
+
+const (
+	diagnosticsPowerEvents = 50
+	diagnosticsEventBytes  = 256 << 10 // most recent part of events.jsonl
+	diagnosticsLogBytes    = 256 << 10 // most recent part of espresso.log
+)
+
+// diagnosticsFile is one entry of the bundle. Entries whose content fails
+// are replaced by a note with the error so the rest is still exported.
+type diagnosticsFile struct {
+	name    string
+	content func() ([]byte, error)
+}
+
+// exportDiagnostics writes a zip with everything useful for a bug report
+// next to the settings and returns its path. status is the current
+// statusReport.
+func exportDiagnostics(cfg Config, status string) (string, error) {
+	files := []diagnosticsFile{
+		{"system.txt", func() ([]byte, error) { return []byte(systemSummary(status)), nil }},
+		{"settings.json", func() ([]byte, error) { return json.MarshalIndent(sanitizeConfig(cfg), "", "  ") }},
+		{"events.jsonl", func() ([]byte, error) { return tailFile(eventLogPath(), diagnosticsEventBytes) }},
+		{"history.txt", func() ([]byte, error) { return []byte(sessionHistory()), nil }},
+		{"espresso.log", func() ([]byte, error) { return tailFile(logPath(), diagnosticsLogBytes) }},
+		{"power-requests.txt", func() ([]byte, error) { return commandOutput("powercfg", "/requests") }},
+		{"power-events.txt", func() ([]byte, error) {
+			return commandOutput("wevtutil", "qe", "System", "/q:"+powerEventQuery,
+				fmt.Sprintf("/c:%d", diagnosticsPowerEvents), "/rd:true", "/f:text")
+		}},
+	}
+
+	name := fmt.Sprintf("espresso-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+	p := filepath.Join(filepath.Dir(settingsPath()), name)
+	f, err := os.Create(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to create diagnostics bundle: %w", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, df := range files {
+		data, err := df.content()
+		if err != nil {
+			data = append(data, fmt.Sprintf("\n\n[could not collect %s: %v]\n", df.name, err)...)
+		}
+		w, err := zw.Create(df.name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write(data); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+func systemSummary(status string) string {
+	v := windows.RtlGetVersion()
+	return fmt.Sprintf("Espresso diagnostics, %s\n\nWindows %d.%d build %d\nArchitecture: %s (machine: %s)\nGo: %s\n\n%s\n%s\n",
+		time.Now().Format(time.RFC1123), v.MajorVersion, v.MinorVersion, v.BuildNumber,
+		runtime.GOARCH, nativeArch(), runtime.Version(), displaysSummary(), status)
+}
+
+// sanitizeConfig strips anything that could be a credential, and the
+// user's whereabouts, from cfg.
+func sanitizeConfig(cfg Config) Config {
+	cfg.Triggers = sanitizeTriggers(cfg.Triggers)
+	profiles := make([]ProfileConfig, len(cfg.Profiles))
+	for i, p := range cfg.Profiles {
+		p.Triggers = sanitizeTriggers(p.Triggers)
+		profiles[i] = p
+	}
+	cfg.Profiles = profiles
+
+	tokens := make([]APIToken, len(cfg.APITokens))
+	for i, t := range cfg.APITokens {
+		t.Hash = "redacted"
+		tokens[i] = t
+	}
+	cfg.APITokens = tokens
+
+	webhooks := make([]WebhookConfig, len(cfg.Webhooks))
+	for i, wh := range cfg.Webhooks {
+		wh.Secret = "redacted"
+		webhooks[i] = wh
+	}
+	cfg.Webhooks = webhooks
+
+	// Places keep their names and radius, which triggers refer to, but not
+	// where they are.
+	places := make(map[string]PlaceConfig, len(cfg.Places))
+	for name, p := range cfg.Places {
+		places[name] = PlaceConfig{RadiusM: p.RadiusM}
+	}
+	cfg.Places = places
+
+	if cfg.GuestLinks.Key != "" {
+		cfg.GuestLinks.Key = "redacted"
+	}
+	cfg.GuestLinks.Used = nil
+	return cfg
+}
+
+// sanitizeTriggers returns a copy of triggers without Docker credentials
+// or private calendar addresses.
+func sanitizeTriggers(triggers []TriggerConfig) []TriggerConfig {
+	out := make([]TriggerConfig, len(triggers))
+	for i, tc := range triggers {
+		if u, err := url.Parse(tc.DockerHost); err == nil && u.User != nil {
+			u.User = url.User("redacted")
+			tc.DockerHost = u.String()
+		}
+		if remoteCalendar(tc.Calendar) {
+			tc.Calendar = "redacted address on " + calendarHost(tc.Calendar)
+		}
+		out[i] = tc
+	}
+	return out
+}
+
+// tailFile returns up to the last n bytes of the file at p, starting at a
+// line boundary.
+func tailFile(p string, n int64) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > n {
+		data = data[int64(len(data))-n:]
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return data, nil
+}
+
+// commandOutput runs a console tool without flashing a window and returns
+// its combined output.
+func commandOutput(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	return cmd.CombinedOutput()
+}
+
+// revealInExplorer opens an Explorer window with p selected.
+func revealInExplorer(p string) {
+	cmd := exec.Command("explorer.exe")
+	// Explorer parses its own command line and needs /select,"path" verbatim.
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `explorer.exe /select,"` + p + `"`}
+	_ = cmd.Start()
+}
@@ -0,0 +1,130 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Input Dialog ---
+
+const inputClassName = "EspressoInput"
+
+// inputDialog is the state of the open input box. Only one can be open at a
+// time, since the window class shares a single window procedure.
+type inputDialog struct {
+	title, prompt, initial string
+
+	edit windows.HWND
+	text string
+	ok   bool
+}
+
+var (
+	inputMu     sync.Mutex
+	activeInput *inputDialog
+)
+
+// inputBox shows a small modal-looking prompt with an edit box and returns
+// the entered text, or false if the user cancelled. It blocks until the box
+// is closed, so call it from a goroutine. If another input box is already
+// open it returns false immediately.
+func inputBox(title, prompt, initial string) (string, bool) {
+	inputMu.Lock()
+	if activeInput != nil {
+		inputMu.Unlock()
+		return "", false
+	}
+	d := &inputDialog{title: title, prompt: prompt, initial: initial}
+	activeInput = d
+	inputMu.Unlock()
+
+	defer func() {
+		inputMu.Lock()
+		activeInput = nil
+		inputMu.Unlock()
+	}()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Client area layout in 96-DPI units.
+	const width, height = 320, 112
+	frame := windows.Rect{Right: dpiScale(width), Bottom: dpiScale(height)}
+	procAdjustWindowRectEx.Call(uintptr(unsafe.Pointer(&frame)), WS_CAPTION|WS_SYSMENU, 0, WS_EX_DLGMODALFRAME|WS_EX_TOPMOST)
+	w, h := frame.Right-frame.Left, frame.Bottom-frame.Top
+	wa := workArea()
+
+	hwnd, err := createWindow(inputClassName, WS_EX_DLGMODALFRAME|WS_EX_TOPMOST, WS_CAPTION|WS_SYSMENU,
+		wa.Left+(wa.Right-wa.Left-w)/2, wa.Top+(wa.Bottom-wa.Top-h)/2, w, h, inputWndProc)
+	if err != nil {
+		return "", false
+	}
+	t, _ := windows.UTF16PtrFromString(title)
+	procSetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(t)))
+
+	s := dpiScale
+	createControl(hwnd, "STATIC", prompt, 0, 0, s(12), s(12), s(296), s(20), 0)
+	d.edit = createControl(hwnd, "EDIT", initial, WS_EX_CLIENTEDGE, WS_TABSTOP|ES_AUTOHSCROLL, s(12), s(36), s(296), s(24), 0)
+	createControl(hwnd, "BUTTON", "OK", 0, WS_TABSTOP|BS_DEFPUSHBUTTON, s(140), s(74), s(80), s(26), IDOK)
+	createControl(hwnd, "BUTTON", "Cancel", 0, WS_TABSTOP|BS_PUSHBUTTON, s(228), s(74), s(80), s(26), IDCANCEL)
+
+	procShowWindow.Call(uintptr(hwnd), SW_SHOWNORMAL)
+	procSetForegroundWindow.Call(uintptr(hwnd))
+	procSetFocus.Call(uintptr(d.edit))
+	procSendMessageW.Call(uintptr(d.edit), EM_SETSEL, 0, ^uintptr(0))
+
+	runMessageLoop(hwnd)
+	return d.text, d.ok
+}
+
+func inputWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	inputMu.Lock()
+	d := activeInput
+	inputMu.Unlock()
+
+	switch msg {
+	case WM_COMMAND:
+		switch wParam & 0xFFFF {
+		case IDOK:
+			if d != nil {
+				d.text = windowText(d.edit)
+				d.ok = true
+			}
+			procDestroyWindow.Call(uintptr(hwnd))
+			return 0
+		case IDCANCEL:
+			procDestroyWindow.Call(uintptr(hwnd))
+			return 0
+		}
+	case WM_CLOSE:
+		procDestroyWindow.Call(uintptr(hwnd))
+		return 0
+	case WM_DESTROY:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
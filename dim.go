@@ -0,0 +1,262 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Soft Landing ---
+
+// With soft_landing set, the displays fade during the last minute of a
+// timed session as a cue that it is about to end, and return to their
+// brightness when it is extended or ends. External monitors are dimmed
+// over DDC/CI and built-in panels through WMI; displays supporting neither
+// are left alone.
+
+const (
+	softLandingLead  = time.Minute // how long before the end dimming starts
+	softLandingSteps = 6
+	softLandingFloor = 0.3 // fraction of the original brightness to fade to
+)
+
+var (
+	moddxva2                                    = windows.NewLazySystemDLL("dxva2.dll")
+	procGetNumberOfPhysicalMonitorsFromHMONITOR = moddxva2.NewProc("GetNumberOfPhysicalMonitorsFromHMONITOR")
+	procGetPhysicalMonitorsFromHMONITOR         = moddxva2.NewProc("GetPhysicalMonitorsFromHMONITOR")
+	procDestroyPhysicalMonitors                 = moddxva2.NewProc("DestroyPhysicalMonitors")
+	procGetMonitorBrightness                    = moddxva2.NewProc("GetMonitorBrightness")
+	procSetMonitorBrightness                    = moddxva2.NewProc("SetMonitorBrightness")
+
+	procEnumDisplayMonitors = user32.NewProc("EnumDisplayMonitors")
+)
+
+// physicalMonitor mirrors PHYSICAL_MONITOR.
+type physicalMonitor struct {
+	Handle      windows.Handle
+	Description [128]uint16
+}
+
+// ddcMonitor is a monitor whose brightness can be set over DDC/CI.
+type ddcMonitor struct {
+	handle        windows.Handle
+	min, cur, max uint32
+}
+
+// savedBrightness is the brightness of every display before dimming.
+type savedBrightness struct {
+	monitors []physicalMonitor
+	ddc      []ddcMonitor
+	panel    int // WMI brightness in percent, -1 for none
+}
+
+// displayDimmer fades the displays on its own goroutine, since DDC/CI and
+// WMI calls are slow.
+type displayDimmer struct {
+	want atomic.Bool
+	wake chan struct{}
+
+	mu     sync.Mutex
+	saved  *savedBrightness // nil while not dimmed
+	closed bool
+}
+
+func startDimmer() *displayDimmer {
+	d := &displayDimmer{wake: make(chan struct{}, 1)}
+	go d.run()
+	return d
+}
+
+// set starts dimming, or restores the brightness.
+func (d *displayDimmer) set(on bool) {
+	d.want.Store(on)
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close restores the brightness and stops dimming for good, before
+// Espresso exits.
+func (d *displayDimmer) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.saved != nil {
+		d.saved.restore()
+		d.saved = nil
+	}
+	d.closed = true
+}
+
+func (d *displayDimmer) run() {
+	var next <-chan time.Time
+	step := 0
+	for {
+		select {
+		case <-d.wake:
+		case <-next:
+		}
+		next = nil
+
+		d.mu.Lock()
+		switch {
+		case d.closed:
+		case !d.want.Load():
+			if d.saved != nil {
+				d.saved.restore()
+				d.saved = nil
+			}
+		default:
+			if d.saved == nil {
+				d.saved = readBrightness()
+				step = 0
+			}
+			if step < softLandingSteps {
+				step++
+				d.saved.dim(1 - (1-softLandingFloor)*float64(step)/softLandingSteps)
+				next = time.After(softLandingLead / softLandingSteps)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// updateSoftLanding dims the displays in the last minute of a running timed
+// session, and restores them otherwise. It is called every tick.
+func (a *app) updateSoftLanding() {
+	dim := a.cfg.SoftLanding && a.timed() && !a.paused() && a.timeLeft() <= softLandingLead
+	if dim == a.dimming {
+		return
+	}
+	a.dimming = dim
+	if a.dimmer == nil {
+		a.dimmer = startDimmer()
+	}
+	a.dimmer.set(dim)
+}
+
+// --- Brightness Control ---
+
+var (
+	enumMonitorsMu   sync.Mutex
+	enumMonitorsList []windows.Handle
+	enumMonitorsProc = windows.NewCallback(func(hmon windows.Handle, _ windows.Handle, _ *windows.Rect, _ uintptr) uintptr {
+		enumMonitorsList = append(enumMonitorsList, hmon)
+		return 1
+	})
+)
+
+// displayMonitors returns the HMONITOR of every display.
+func displayMonitors() []windows.Handle {
+	enumMonitorsMu.Lock()
+	defer enumMonitorsMu.Unlock()
+	enumMonitorsList = nil
+	procEnumDisplayMonitors.Call(0, 0, enumMonitorsProc, 0)
+	return enumMonitorsList
+}
+
+func readBrightness() *savedBrightness {
+	s := &savedBrightness{panel: -1}
+	// Without dxva2.dll there is no DDC/CI, and only the built-in panel dims.
+	var hmons []windows.Handle
+	if procAvailable(procGetNumberOfPhysicalMonitorsFromHMONITOR) {
+		hmons = displayMonitors()
+	}
+	for _, hmon := range hmons {
+		var n uint32
+		if r, _, _ := procGetNumberOfPhysicalMonitorsFromHMONITOR.Call(uintptr(hmon), uintptr(unsafe.Pointer(&n))); r == 0 || n == 0 {
+			continue
+		}
+		pms := make([]physicalMonitor, n)
+		if r, _, _ := procGetPhysicalMonitorsFromHMONITOR.Call(uintptr(hmon), uintptr(n), uintptr(unsafe.Pointer(&pms[0]))); r == 0 {
+			continue
+		}
+		s.monitors = append(s.monitors, pms...)
+		for _, pm := range pms {
+			m := ddcMonitor{handle: pm.Handle}
+			r, _, _ := procGetMonitorBrightness.Call(uintptr(pm.Handle),
+				uintptr(unsafe.Pointer(&m.min)), uintptr(unsafe.Pointer(&m.cur)), uintptr(unsafe.Pointer(&m.max)))
+			if r != 0 && m.max > m.min {
+				s.ddc = append(s.ddc, m)
+			}
+		}
+	}
+
+	out, err := wmiBrightness("(Get-CimInstance -Namespace root/wmi -ClassName WmiMonitorBrightness -ErrorAction Stop | Select-Object -First 1).CurrentBrightness")
+	if err == nil {
+		if v, err := strconv.Atoi(strings.TrimSpace(out)); err == nil {
+			s.panel = v
+		}
+	}
+	return s
+}
+
+// dim sets every display to fraction f of its saved brightness.
+func (s *savedBrightness) dim(f float64) {
+	for _, m := range s.ddc {
+		v := m.min + uint32(float64(m.cur-m.min)*f)
+		procSetMonitorBrightness.Call(uintptr(m.handle), uintptr(v))
+	}
+	if s.panel >= 0 {
+		setPanelBrightness(int(float64(s.panel) * f))
+	}
+}
+
+// restore returns every display to its saved brightness and releases the
+// monitor handles.
+func (s *savedBrightness) restore() {
+	for _, m := range s.ddc {
+		procSetMonitorBrightness.Call(uintptr(m.handle), uintptr(m.cur))
+	}
+	if s.panel >= 0 {
+		setPanelBrightness(s.panel)
+	}
+	if len(s.monitors) > 0 {
+		procDestroyPhysicalMonitors.Call(uintptr(len(s.monitors)), uintptr(unsafe.Pointer(&s.monitors[0])))
+	}
+}
+
+func setPanelBrightness(percent int) {
+	script := fmt.Sprintf("Get-CimInstance -Namespace root/wmi -ClassName WmiMonitorBrightnessMethods -ErrorAction Stop | "+
+		"Invoke-CimMethod -MethodName WmiSetBrightness -Arguments @{Timeout=0; Brightness=[byte]%d} | Out-Null", percent)
+	if _, err := wmiBrightness(script); err != nil {
+		logWarnf("could not set display brightness: %v", err)
+	}
+}
+
+// wmiBrightness runs a brightness query or command in a hidden PowerShell.
+func wmiBrightness(script string) (string, error) {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass",
+		"-EncodedCommand", encodePowerShell(script))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,271 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Discord Rich Presence ---
+
+// With discord enabled, the session is shown as the user's activity in
+// Discord, through the local RPC pipe of the Discord client. Discord only
+// shows activities of registered applications, so the user supplies the
+// client ID of one they created in the Discord Developer Portal.
+
+const (
+	defaultPresenceDetails = "{mode} mode"
+	defaultPresenceState   = "{remaining} remaining"
+
+	discordOpHandshake = 0
+	discordOpFrame     = 1
+	discordOpClose     = 2
+
+	// Discord limits activity updates to five every 20 seconds.
+	discordMinInterval = 15 * time.Second
+	discordRetry       = time.Minute
+)
+
+// DiscordConfig configures Rich Presence. Details and State are templates
+// for the two lines of the activity; see presenceText.
+type DiscordConfig struct {
+	Enabled  bool   `json:"enabled"`
+	ClientID string `json:"client_id,omitempty"`
+	Details  string `json:"details,omitempty"` // default defaultPresenceDetails
+	State    string `json:"state,omitempty"`   // default defaultPresenceState
+}
+
+// presence is the activity to show, or the zero value for none.
+type presence struct {
+	details, state string
+	end            time.Time // shown as a countdown if set
+}
+
+// discordPresence publishes activities on its own goroutine. Only the
+// latest one is sent.
+type discordPresence struct {
+	last    presence
+	pending chan presence
+}
+
+func startDiscordPresence(cfg DiscordConfig) *discordPresence {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.ClientID == "" {
+		logWarnf("Discord Rich Presence needs a client_id")
+		return nil
+	}
+	d := &discordPresence{pending: make(chan presence, 1)}
+	go d.run(cfg.ClientID)
+	return d
+}
+
+func (d *discordPresence) set(p presence) {
+	if d == nil || p == d.last {
+		return
+	}
+	d.last = p
+	select {
+	case <-d.pending:
+	default:
+	}
+	d.pending <- p
+}
+
+func (d *discordPresence) run(clientID string) {
+	var conn *discordConn
+	var lastSent, lastFail time.Time
+	for p := range d.pending {
+		if wait := discordMinInterval - time.Since(lastSent); wait > 0 {
+			time.Sleep(wait)
+			// Skip to the newest activity queued while waiting.
+			select {
+			case p = <-d.pending:
+			default:
+			}
+		}
+		if conn == nil {
+			if time.Since(lastFail) < discordRetry {
+				continue
+			}
+			var err error
+			if conn, err = dialDiscord(clientID); err != nil {
+				lastFail = time.Now()
+				continue // Discord is not running
+			}
+		}
+		if err := conn.setActivity(p); err != nil {
+			logWarnf("Discord Rich Presence: %v", err)
+			conn.Close()
+			conn = nil
+			lastFail = time.Now()
+			continue
+		}
+		lastSent = time.Now()
+	}
+}
+
+// updatePresence publishes the current session to Discord.
+func (a *app) updatePresence() {
+	if a.discord == nil {
+		return
+	}
+	if !a.active() || a.paused() {
+		a.discord.set(presence{})
+		return
+	}
+	cfg := a.cfg.Discord
+	p := presence{
+		details: a.presenceText(cfg.Details, defaultPresenceDetails),
+		state:   a.presenceText(cfg.State, defaultPresenceState),
+	}
+	if a.timed() {
+		p.end = a.sessionEnd().Truncate(time.Second)
+	}
+	a.discord.set(p)
+}
+
+// presenceText fills in a template: {mode}, {note}, {remaining} (rounded
+// to the minute, or "no time limit") and {ends} (the end time, 15:04).
+func (a *app) presenceText(tmpl, def string) string {
+	if tmpl == "" {
+		tmpl = def
+	}
+	remaining, ends := tr("presence.unlimited"), ""
+	if a.timed() {
+		left := a.timeLeft().Round(time.Minute)
+		if left < time.Minute {
+			left = time.Minute
+		}
+		remaining = formatFriendlyDuration(left)
+		ends = a.sessionEnd().Format("15:04")
+	}
+	return strings.NewReplacer(
+		"{mode}", a.modeName(),
+		"{note}", a.sessionNote,
+		"{remaining}", remaining,
+		"{ends}", ends,
+	).Replace(tmpl)
+}
+
+// --- Discord RPC ---
+
+// discordConn is a connection to the Discord client's RPC pipe. Each frame
+// is an opcode and a length, both little-endian uint32, followed by JSON.
+type discordConn struct {
+	*os.File
+	nonce int
+}
+
+func dialDiscord(clientID string) (*discordConn, error) {
+	var f *os.File
+	var err error
+	for i := 0; i < 10; i++ {
+		if f, err = os.OpenFile(fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, i), os.O_RDWR, 0); err == nil {
+			break
+		}
+	}
+	if f == nil {
+		return nil, err
+	}
+	c := &discordConn{File: f}
+	if err := c.write(discordOpHandshake, map[string]any{"v": 1, "client_id": clientID}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.read(); err != nil { // the READY event
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *discordConn) setActivity(p presence) error {
+	var activity any // null clears the activity
+	if p != (presence{}) {
+		act := map[string]any{"details": p.details, "state": p.state}
+		if !p.end.IsZero() {
+			act["timestamps"] = map[string]int64{"end": p.end.Unix()}
+		}
+		activity = act
+	}
+	c.nonce++
+	err := c.write(discordOpFrame, map[string]any{
+		"cmd":   "SET_ACTIVITY",
+		"args":  map[string]any{"pid": os.Getpid(), "activity": activity},
+		"nonce": strconv.Itoa(c.nonce),
+	})
+	if err != nil {
+		return err
+	}
+	return c.read()
+}
+
+func (c *discordConn) write(op uint32, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, [2]uint32{op, uint32(len(data))})
+	b.Write(data)
+	_, err = c.Write(b.Bytes())
+	return err
+}
+
+// read reads one reply and returns the error it reports, if any.
+func (c *discordConn) read() error {
+	var hdr [2]uint32
+	if err := binary.Read(c, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	if hdr[1] > 1<<20 {
+		return errors.New("reply too large")
+	}
+	data := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, data); err != nil {
+		return err
+	}
+	var reply struct {
+		Evt  string `json:"evt"`
+		Data struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	json.Unmarshal(data, &reply)
+	if hdr[0] == discordOpClose {
+		return fmt.Errorf("closed by Discord: %s", reply.Data.Message)
+	}
+	if reply.Evt == "ERROR" {
+		return errors.New(reply.Data.Message)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Displays ---
+
+// A session that keeps the screen on keeps every display on. With
+// displays.keep set, only the listed displays stay on: the others turn off
+// over DDC/CI once the user has been idle for displays.off_after, as they
+// would without a session, and back on at the next keyboard or mouse
+// input. Displays without DDC/CI stay on.
+
+var (
+	procGetMonitorInfoW = user32.NewProc("GetMonitorInfoW")
+	procSetVCPFeature   = moddxva2.NewProc("SetVCPFeature")
+)
+
+const (
+	MONITORINFOF_PRIMARY = 0x00000001
+
+	vcpPowerMode = 0xD6 // DDC/CI power mode
+	vcpPowerOn   = 1
+	vcpPowerOff  = 4 // off (DPM); 5 would also cut DDC/CI on many monitors
+
+	defaultDisplayOffAfter = 10 * time.Minute
+)
+
+// DisplaysConfig picks the displays that sessions keep on. Each entry of
+// Keep is "primary", "secondary" (every display but the primary), a
+// display number as in \\.\DISPLAY2, or a monitor name; the Espresso
+// diagnostics list the displays with their numbers and names.
+type DisplaysConfig struct {
+	Keep     []string `json:"keep,omitempty"`
+	OffAfter string   `json:"off_after,omitempty"` // default "10m"
+}
+
+// offAfter returns how long the user must be idle before the other
+// displays turn off.
+func (c DisplaysConfig) offAfter() time.Duration {
+	if c.OffAfter == "" {
+		return defaultDisplayOffAfter
+	}
+	d, err := time.ParseDuration(c.OffAfter)
+	if err != nil || d <= 0 {
+		logWarnf("invalid displays.off_after %q, using %s", c.OffAfter, defaultDisplayOffAfter)
+		return defaultDisplayOffAfter
+	}
+	return d
+}
+
+// keeps reports whether d is one of the displays to keep on.
+func (c DisplaysConfig) keeps(d display) bool {
+	for _, k := range c.Keep {
+		k = strings.TrimSpace(k)
+		switch {
+		case strings.EqualFold(k, "primary"):
+			if d.primary {
+				return true
+			}
+		case strings.EqualFold(k, "secondary"):
+			if !d.primary {
+				return true
+			}
+		default:
+			if n, err := strconv.Atoi(k); err == nil {
+				if d.number == n {
+					return true
+				}
+			} else if strings.EqualFold(k, d.name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// monitorInfoEx mirrors MONITORINFOEXW.
+type monitorInfoEx struct {
+	Size    uint32
+	Monitor windows.Rect
+	Work    windows.Rect
+	Flags   uint32
+	Device  [32]uint16
+}
+
+// display is one display with its physical monitors, whose handles must
+// be released with closeDisplays.
+type display struct {
+	number   int // n in \\.\DISPLAYn
+	primary  bool
+	name     string // first physical monitor's description
+	monitors []physicalMonitor
+}
+
+// listDisplays returns every display, in the order Windows enumerates
+// them.
+func listDisplays() []display {
+	var ds []display
+	for _, hmon := range displayMonitors() {
+		info := monitorInfoEx{Size: uint32(unsafe.Sizeof(monitorInfoEx{}))}
+		if r, _, _ := procGetMonitorInfoW.Call(uintptr(hmon), uintptr(unsafe.Pointer(&info))); r == 0 {
+			continue
+		}
+		d := display{primary: info.Flags&MONITORINFOF_PRIMARY != 0}
+		device := windows.UTF16ToString(info.Device[:])
+		d.number, _ = strconv.Atoi(strings.TrimPrefix(device, `\\.\DISPLAY`))
+
+		var n uint32
+		if procAvailable(procGetNumberOfPhysicalMonitorsFromHMONITOR) {
+			procGetNumberOfPhysicalMonitorsFromHMONITOR.Call(uintptr(hmon), uintptr(unsafe.Pointer(&n)))
+		}
+		if n > 0 {
+			pms := make([]physicalMonitor, n)
+			if r, _, _ := procGetPhysicalMonitorsFromHMONITOR.Call(uintptr(hmon), uintptr(n), uintptr(unsafe.Pointer(&pms[0]))); r != 0 {
+				d.monitors = pms
+				d.name = windows.UTF16ToString(pms[0].Description[:])
+			}
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// closeDisplays releases the physical monitor handles of ds.
+func closeDisplays(ds []display) {
+	for _, d := range ds {
+		if len(d.monitors) > 0 {
+			procDestroyPhysicalMonitors.Call(uintptr(len(d.monitors)), uintptr(unsafe.Pointer(&d.monitors[0])))
+		}
+	}
+}
+
+// displaysSummary lists the displays for the diagnostics.
+func displaysSummary() string {
+	ds := listDisplays()
+	defer closeDisplays(ds)
+	var b strings.Builder
+	b.WriteString("Displays:\n")
+	for _, d := range ds {
+		role := "secondary"
+		if d.primary {
+			role = "primary"
+		}
+		fmt.Fprintf(&b, "  %d: %q, %s, %d physical monitor(s)\n", d.number, d.name, role, len(d.monitors))
+	}
+	return b.String()
+}
+
+// updateDisplayPower turns the displays not in displays.keep off while a
+// session keeps the screen on and the user is idle, and on otherwise. It
+// is called every tick.
+func (a *app) updateDisplayPower() {
+	c := a.cfg.Displays
+	off := len(c.Keep) > 0 && a.heldFlags()&ES_DISPLAY_REQUIRED != 0 && idleTime() >= c.offAfter()
+	if off == a.displaysOff {
+		return
+	}
+	a.displaysOff = off
+	if a.displayPower == nil {
+		a.displayPower = startDisplayPower()
+	}
+	a.displayPower.set(off, c)
+}
+
+// displayPower switches displays off and on in the background, as DDC/CI
+// calls are slow.
+type displayPower struct {
+	want atomic.Bool
+	cfg  atomic.Pointer[DisplaysConfig]
+	wake chan struct{}
+
+	mu     sync.Mutex
+	off    []display // displays turned off, nil while all are on
+	closed bool
+}
+
+func startDisplayPower() *displayPower {
+	p := &displayPower{wake: make(chan struct{}, 1)}
+	go p.run()
+	return p
+}
+
+// set turns the displays that c does not keep off, or every display back
+// on.
+func (p *displayPower) set(off bool, c DisplaysConfig) {
+	p.cfg.Store(&c)
+	p.want.Store(off)
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close turns every display back on for good, before Espresso exits.
+func (p *displayPower) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.powerOn()
+	p.closed = true
+}
+
+func (p *displayPower) run() {
+	for range p.wake {
+		p.mu.Lock()
+		switch {
+		case p.closed:
+		case !p.want.Load():
+			p.powerOn()
+		case p.off == nil:
+			p.powerOff(*p.cfg.Load())
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *displayPower) powerOff(c DisplaysConfig) {
+	var keep []display
+	for _, d := range listDisplays() {
+		if c.keeps(d) || len(d.monitors) == 0 {
+			keep = append(keep, d)
+			continue
+		}
+		for _, m := range d.monitors {
+			procSetVCPFeature.Call(uintptr(m.Handle), vcpPowerMode, vcpPowerOff)
+		}
+		p.off = append(p.off, d)
+		logDebugf("Display %d (%s) turned off", d.number, d.name)
+	}
+	closeDisplays(keep)
+	if p.off == nil {
+		p.off = []display{} // nothing to turn off; not asked again until on
+	}
+}
+
+func (p *displayPower) powerOn() {
+	for _, d := range p.off {
+		for _, m := range d.monitors {
+			procSetVCPFeature.Call(uintptr(m.Handle), vcpPowerMode, vcpPowerOn)
+		}
+		logDebugf("Display %d (%s) turned on", d.number, d.name)
+	}
+	closeDisplays(p.off)
+	p.off = nil
+}
@@ -0,0 +1,117 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Domain Network Condition ---
+
+// A trigger with "domain": "on" only holds while a domain controller of the
+// machine's domain is reachable, and "off" only while none is, e.g. to keep
+// aggressive rules to the home office.
+
+const (
+	domainCacheTTL       = time.Minute
+	DS_FORCE_REDISCOVERY = 0x00000001
+)
+
+var (
+	modnetapi32      = windows.NewLazySystemDLL("netapi32.dll")
+	procDsGetDcNameW = modnetapi32.NewProc("DsGetDcNameW")
+)
+
+var (
+	domainMu      sync.Mutex
+	domainChecked time.Time
+	domainOn      bool
+)
+
+// onDomainNetwork reports whether the machine is domain-joined and can
+// reach a domain controller. Locating a controller can take seconds off the
+// corporate network, so the answer is cached for domainCacheTTL and shared
+// by all triggers.
+func onDomainNetwork() bool {
+	domainMu.Lock()
+	defer domainMu.Unlock()
+	if time.Since(domainChecked) < domainCacheTTL {
+		return domainOn
+	}
+	domainOn = domainControllerReachable()
+	domainChecked = time.Now()
+	return domainOn
+}
+
+func domainControllerReachable() bool {
+	var name *uint16
+	var joinType uint32
+	if err := windows.NetGetJoinInformation(nil, &name, &joinType); err != nil {
+		return false
+	}
+	windows.NetApiBufferFree((*byte)(unsafe.Pointer(name)))
+	if joinType != windows.NetSetupDomainName {
+		return false
+	}
+
+	if !procAvailable(procDsGetDcNameW) {
+		return false
+	}
+	var info *byte // DOMAIN_CONTROLLER_INFOW, unused
+	r, _, _ := procDsGetDcNameW.Call(0, 0, 0, 0, DS_FORCE_REDISCOVERY, uintptr(unsafe.Pointer(&info)))
+	if r != 0 {
+		return false
+	}
+	windows.NetApiBufferFree(info)
+	return true
+}
+
+// domainTrigger holds only while the inner trigger holds and the domain
+// network state matches.
+type domainTrigger struct {
+	inner    trigger
+	onDomain bool
+}
+
+func newDomainCondition(t trigger, domain string) (trigger, error) {
+	switch domain {
+	case "":
+		return t, nil
+	case "on":
+		return domainTrigger{inner: t, onDomain: true}, nil
+	case "off":
+		return domainTrigger{inner: t, onDomain: false}, nil
+	default:
+		return nil, fmt.Errorf("invalid domain %q, use \"on\" or \"off\"", domain)
+	}
+}
+
+func (t domainTrigger) check() (bool, string, error) {
+	if onDomainNetwork() != t.onDomain {
+		return false, "", nil
+	}
+	return t.inner.check()
+}
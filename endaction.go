@@ -0,0 +1,234 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- End-of-Session Actions ---
+
+// When a timed session with an end action expires, a notification says
+// what is about to happen and offers to cancel it. The action runs once
+// the cancel window (end_action_delay, default 30 seconds) has passed.
+
+const defaultEndActionDelay = 30 * time.Second
+
+var (
+	procLockWorkStation = user32.NewProc("LockWorkStation")
+
+	modpowrprof         = windows.NewLazySystemDLL("powrprof.dll")
+	procSetSuspendState = modpowrprof.NewProc("SetSuspendState")
+)
+
+// endActions are what a timed session may do when it expires, besides
+// letting the system sleep.
+var endActions = []string{"lock", "sleep", "hibernate", "shutdown"}
+
+// parseEndAction validates an end action name. An empty name or "none"
+// means no action.
+func parseEndAction(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" {
+		return "", nil
+	}
+	for _, a := range endActions {
+		if s == a {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown end action %q (use %s)", s, strings.Join(endActions, ", "))
+}
+
+// endActionName is the menu label of an end action.
+func endActionName(action string) string {
+	if action == "" {
+		action = "none"
+	}
+	return tr("menu.on_end." + action)
+}
+
+// runEndAction performs an end action returned by parseEndAction.
+func runEndAction(action string) error {
+	var r uintptr
+	var err error
+	switch action {
+	case "":
+		return nil
+	case "lock":
+		r, _, err = procLockWorkStation.Call()
+	case "sleep", "hibernate":
+		if err := enableShutdownPrivilege(); err != nil {
+			return err
+		}
+		if !procAvailable(procSetSuspendState) {
+			return fmt.Errorf("%s is not supported on this system", action)
+		}
+		var hibernate uintptr
+		if action == "hibernate" {
+			hibernate = 1
+		}
+		r, _, err = procSetSuspendState.Call(hibernate, 0, 0)
+	case "shutdown":
+		if err := enableShutdownPrivilege(); err != nil {
+			return err
+		}
+		if err := windows.ExitWindowsEx(windows.EWX_SHUTDOWN|windows.EWX_POWEROFF, windows.SHTDN_REASON_MAJOR_OTHER|windows.SHTDN_REASON_FLAG_PLANNED); err != nil {
+			return fmt.Errorf("shutdown failed: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown end action %q", action)
+	}
+	if r == 0 {
+		return fmt.Errorf("%s failed: %w", action, err)
+	}
+	return nil
+}
+
+// enableShutdownPrivilege enables SeShutdownPrivilege, which suspending
+// and shutting down require, in the process token.
+func enableShutdownPrivilege() error {
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+		return fmt.Errorf("open process token: %w", err)
+	}
+	defer token.Close()
+
+	name, _ := windows.UTF16PtrFromString("SeShutdownPrivilege")
+	tp := windows.Tokenprivileges{PrivilegeCount: 1}
+	if err := windows.LookupPrivilegeValue(nil, name, &tp.Privileges[0].Luid); err != nil {
+		return fmt.Errorf("look up shutdown privilege: %w", err)
+	}
+	tp.Privileges[0].Attributes = windows.SE_PRIVILEGE_ENABLED
+	if err := windows.AdjustTokenPrivileges(token, false, &tp, 0, nil, nil); err != nil {
+		return fmt.Errorf("enable shutdown privilege: %w", err)
+	}
+	return nil
+}
+
+// endActionDelay returns the cancel window before an end action runs, or
+// 0 if the end_action_delay setting is "none".
+func (cfg Config) endActionDelay() time.Duration {
+	switch s := strings.TrimSpace(cfg.EndActionDelay); {
+	case s == "":
+		return defaultEndActionDelay
+	case strings.EqualFold(s, "none"):
+		return 0
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			logWarnf("invalid end_action_delay %q, using %s", s, defaultEndActionDelay)
+			return defaultEndActionDelay
+		}
+		return d
+	}
+}
+
+// pendingEndAction is an end action waiting for its cancel window to pass.
+type pendingEndAction struct {
+	action string
+	at     time.Time
+	nonce  string // authorizes the Cancel button, as in sessionLink
+}
+
+// scheduleEndAction announces that action will run after the cancel
+// window, together with msg about the session that has just finished.
+func (a *app) scheduleEndAction(action, msg string) {
+	delay := a.cfg.endActionDelay()
+	if delay == 0 {
+		go func() {
+			showToast(tagSession, tr("toast.finished"), msg, icoffPath())
+			if err := runEndAction(action); err != nil {
+				showMessage("Espresso", tr("on_end.failed", err))
+			}
+		}()
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logWarnf("could not create action nonce: %v", err)
+	}
+	p := &pendingEndAction{action: action, at: time.Now().Add(delay), nonce: hex.EncodeToString(b)}
+	a.pendingEnd = p
+	a.mCancelEnd.SetTitle(tr("menu.on_end.cancel", endActionName(action)))
+	a.mCancelEnd.Show()
+	a.syncEndGuard()
+
+	left := formatFriendlyDuration(delay)
+	if delay < time.Minute {
+		left = fmt.Sprintf("%ds", int(delay.Seconds()))
+	}
+	msg += "\n" + tr("on_end.pending", endActionName(action), left)
+	link := fmt.Sprintf("%s://cancelend?%s", urlScheme, url.Values{"nonce": {p.nonce}}.Encode())
+	go showToastActions(tagSession, tr("toast.finished"), msg, icoffPath(),
+		[]toastAction{{Label: tr("action.cancel"), Arguments: link}})
+}
+
+// checkEndAction runs the pending end action once its cancel window has
+// passed.
+func (a *app) checkEndAction() {
+	p := a.pendingEnd
+	if p == nil || time.Now().Before(p.at) {
+		return
+	}
+	a.clearEndAction()
+	go func() {
+		if err := runEndAction(p.action); err != nil {
+			showMessage("Espresso", tr("on_end.failed", err))
+		}
+	}()
+}
+
+// cancelEndAction drops the pending end action, if any, and says so.
+func (a *app) cancelEndAction() {
+	p := a.pendingEnd
+	if p == nil {
+		return
+	}
+	a.clearEndAction()
+	logInfof("End action %s cancelled", p.action)
+	go showToast(tagSession, tr("on_end.cancelled", endActionName(p.action)), a.releasedMessage(), icoffPath())
+}
+
+func (a *app) clearEndAction() {
+	a.pendingEnd = nil
+	a.mCancelEnd.Hide()
+	a.syncEndGuard()
+}
+
+// cancelEndFromLink cancels the pending end action from its notification.
+func (a *app) cancelEndFromLink(nonce string) error {
+	if a.pendingEnd == nil || a.pendingEnd.nonce == "" || nonce != a.pendingEnd.nonce {
+		return errors.New("there is no end-of-session action to cancel")
+	}
+	a.cancelEndAction()
+	return nil
+}
@@ -0,0 +1,170 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- End Action Guard ---
+
+// While a timed session is due to end with sleep or hibernate, Espresso
+// runs a copy of itself, "espresso endguard <action> <unix time>", that
+// arms a waitable timer for the end of the session and then runs the
+// action. Espresso stops the guard when the session changes or ends
+// normally, so the guard only acts if Espresso was killed or crashed
+// before keeping its promise.
+
+var (
+	procCreateWaitableTimerW = modkernel32.NewProc("CreateWaitableTimerW")
+	procSetWaitableTimer     = modkernel32.NewProc("SetWaitableTimer")
+)
+
+const (
+	// endGuardGrace delays the guard past the time Espresso itself would
+	// run the action, so a running Espresso always wins.
+	endGuardGrace = time.Minute
+	// endGuardLate is how late the guard may still act, e.g. after the
+	// system slept on its own and woke up past the end of the session.
+	endGuardLate = 5 * time.Minute
+	// endGuardSettle is how long the guard waits before acting, so that a
+	// running Espresso that has just resumed from sleep can extend the
+	// session and stop it first.
+	endGuardSettle = 15 * time.Second
+)
+
+// endGuard is the guard process, if one is running.
+type endGuard struct {
+	proc   *os.Process
+	action string
+	at     time.Time
+}
+
+// guardedEndAction returns the sleep or hibernate action the session is
+// due to run, and when, or "" if there is none.
+func (a *app) guardedEndAction() (string, time.Time) {
+	if p := a.pendingEnd; p != nil {
+		if p.action == "sleep" || p.action == "hibernate" {
+			return p.action, p.at
+		}
+		return "", time.Time{}
+	}
+	if !a.timed() || a.paused() {
+		return "", time.Time{}
+	}
+	if a.sessionOnEnd != "sleep" && a.sessionOnEnd != "hibernate" {
+		return "", time.Time{}
+	}
+	return a.sessionOnEnd, a.sessionEnd().Add(a.cfg.endActionDelay())
+}
+
+// syncEndGuard starts, restarts or stops the guard to match the session.
+func (a *app) syncEndGuard() {
+	action, at := a.guardedEndAction()
+	if at.IsZero() {
+		a.endGuard.stop()
+		return
+	}
+	at = at.Add(endGuardGrace).Truncate(time.Second)
+	if a.endGuard.proc != nil && a.endGuard.action == action && a.endGuard.at.Equal(at) {
+		return
+	}
+	a.endGuard.stop()
+
+	exe, err := os.Executable()
+	if err != nil {
+		logWarnf("could not start the end action guard: %v", err)
+		return
+	}
+	cmd := exec.Command(exe, "endguard", action, strconv.FormatInt(at.Unix(), 10))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	if err := cmd.Start(); err != nil {
+		logWarnf("could not start the end action guard: %v", err)
+		return
+	}
+	a.endGuard = endGuard{proc: cmd.Process, action: action, at: at}
+	logDebugf("End action guard armed: %s at %s", action, at.Format("15:04:05"))
+}
+
+// stop ends the guard process, if any.
+func (g *endGuard) stop() {
+	if g.proc == nil {
+		return
+	}
+	if err := g.proc.Kill(); err != nil {
+		logWarnf("could not stop the end action guard: %v", err)
+	}
+	_ = g.proc.Release()
+	logDebugf("End action guard disarmed")
+	*g = endGuard{}
+}
+
+// runEndGuard runs "espresso endguard" with the arguments after
+// "endguard" and returns the process exit code.
+func runEndGuard(args []string) int {
+	if len(args) != 2 || (args[0] != "sleep" && args[0] != "hibernate") {
+		return 2
+	}
+	action := args[0]
+	sec, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 2
+	}
+	at := time.Unix(sec, 0)
+
+	timer, _, err := procCreateWaitableTimerW.Call(0, 1, 0)
+	if timer == 0 {
+		logErrorf("end action guard: CreateWaitableTimer failed: %v", err)
+		return 1
+	}
+	defer windows.CloseHandle(windows.Handle(timer))
+
+	// An absolute due time is a FILETIME in 100 ns intervals.
+	due := windows.NsecToFiletime(at.UnixNano())
+	dueTime := int64(due.HighDateTime)<<32 | int64(due.LowDateTime)
+	if r, _, err := procSetWaitableTimer.Call(timer, uintptr(unsafe.Pointer(&dueTime)), 0, 0, 0, 0); r == 0 {
+		logErrorf("end action guard: SetWaitableTimer failed: %v", err)
+		return 1
+	}
+	if _, err := windows.WaitForSingleObject(windows.Handle(timer), windows.INFINITE); err != nil {
+		logErrorf("end action guard: %v", err)
+		return 1
+	}
+
+	time.Sleep(endGuardSettle)
+	if late := time.Since(at); late > endGuardLate {
+		logWarnf("End action guard woke %s late; not running %s", formatFriendlyDuration(late), action)
+		return 0
+	}
+	logWarnf("Espresso did not end its session; the end action guard runs %s", action)
+	if err := runEndAction(action); err != nil {
+		logErrorf("end action guard: %v", err)
+		return 1
+	}
+	return 0
+}
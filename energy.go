@@ -0,0 +1,167 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Energy Estimate ---
+
+// EnergyConfig is what the machine draws while it is kept awake instead of
+// sleeping, for the cost estimate in the statistics window.
+type EnergyConfig struct {
+	SystemWatts  float64 `json:"system_watts,omitempty"`  // idle draw of the computer
+	DisplayWatts float64 `json:"display_watts,omitempty"` // all displays together
+	PricePerKWh  float64 `json:"price_per_kwh,omitempty"`
+	Currency     string  `json:"currency,omitempty"` // e.g. "EUR", shown after the cost
+
+	// MonthlyBudgetKWh warns once a calendar month when keep-awake time has
+	// used budgetWarnShare of it. CO2PerKWh, in grams, adds the emissions
+	// to the warning. WarnedMonth ("2006-01") records the last warning.
+	MonthlyBudgetKWh float64 `json:"monthly_budget_kwh,omitempty"`
+	CO2PerKWh        float64 `json:"co2_g_per_kwh,omitempty"`
+	WarnedMonth      string  `json:"warned_month,omitempty"`
+}
+
+const (
+	budgetWarnShare     = 0.8
+	budgetCheckInterval = 10 * time.Minute
+)
+
+func (e EnergyConfig) configured() bool {
+	return e.SystemWatts+e.DisplayWatts > 0
+}
+
+// estimate returns the energy in kWh used while awake for d, and its cost.
+// The estimate assumes the display stayed on, and that sleep would have
+// drawn nothing.
+func (e EnergyConfig) estimate(d time.Duration) (kWh, cost float64) {
+	kWh = (e.SystemWatts + e.DisplayWatts) * d.Hours() / 1000
+	return kWh, kWh * e.PricePerKWh
+}
+
+// describe is the estimate line of the statistics window.
+func (e EnergyConfig) describe(d time.Duration) string {
+	if !e.configured() {
+		return tr("stats.energy_unset")
+	}
+	kWh, cost := e.estimate(d)
+	if e.PricePerKWh <= 0 {
+		return tr("stats.energy", kWh, e.SystemWatts+e.DisplayWatts)
+	}
+	price := strconv.FormatFloat(cost, 'f', 2, 64)
+	if e.Currency != "" {
+		price += " " + e.Currency
+	}
+	return tr("stats.energy_cost", kWh, price, e.SystemWatts+e.DisplayWatts)
+}
+
+// askEnergy prompts for the wattages and price, starting from e, and
+// returns false if the user cancels any prompt.
+func askEnergy(e EnergyConfig) (EnergyConfig, bool) {
+	for _, f := range []struct {
+		key string
+		v   *float64
+	}{
+		{"energy.system", &e.SystemWatts},
+		{"energy.display", &e.DisplayWatts},
+		{"energy.price", &e.PricePerKWh},
+	} {
+		text := strconv.FormatFloat(*f.v, 'f', -1, 64)
+		for {
+			var ok bool
+			if text, ok = inputBox(tr("energy.title"), tr(f.key), text); !ok {
+				return e, false
+			}
+			v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(text), ",", "."), 64)
+			if err != nil || v < 0 {
+				showMessage(tr("energy.title"), fmt.Sprintf("%q: %s", text, tr("energy.invalid")))
+				continue
+			}
+			*f.v = v
+			break
+		}
+	}
+	return e, true
+}
+
+// --- Energy Budget ---
+
+// budgetUsage is the keep-awake energy used so far in month.
+type budgetUsage struct {
+	month string // "2006-01"
+	kWh   float64
+}
+
+// monthEnergy sums the awake time since the start of the month from the
+// event log and sends its energy on ch. It reads the whole log, so it runs
+// off the main loop.
+func monthEnergy(e EnergyConfig, ch chan<- budgetUsage) {
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var d time.Duration
+	for _, s := range awakeSpans() {
+		if s.end.Before(first) {
+			continue
+		}
+		if s.start.Before(first) {
+			s.start = first
+		}
+		d += s.end.Sub(s.start)
+	}
+	kWh, _ := e.estimate(d)
+	ch <- budgetUsage{month: now.Format("2006-01"), kWh: kWh}
+}
+
+// checkEnergyBudget starts a usage count every budgetCheckInterval while a
+// budget is set and the system is kept awake.
+func (a *app) checkEnergyBudget(ch chan<- budgetUsage) {
+	e := a.cfg.Energy
+	if e.MonthlyBudgetKWh <= 0 || !e.configured() || time.Since(a.lastBudgetCheck) < budgetCheckInterval {
+		return
+	}
+	if e.WarnedMonth == time.Now().Format("2006-01") || (!a.active() && a.triggerFlags() == 0) {
+		return
+	}
+	a.lastBudgetCheck = time.Now()
+	go monthEnergy(e, ch)
+}
+
+// handleBudgetUsage warns the first time in a month that usage passes
+// budgetWarnShare of the budget.
+func (a *app) handleBudgetUsage(u budgetUsage) {
+	e := &a.cfg.Energy
+	if e.WarnedMonth == u.month || u.kWh < e.MonthlyBudgetKWh*budgetWarnShare {
+		return
+	}
+	e.WarnedMonth = u.month
+	a.saveConfig()
+
+	msg := tr("budget.used", u.kWh, e.MonthlyBudgetKWh)
+	if e.CO2PerKWh > 0 {
+		msg += " " + tr("budget.co2", u.kWh*e.CO2PerKWh/1000)
+	}
+	go showToast(tagReminder, tr("budget.title", int(budgetWarnShare*100)), msg, iconPath())
+}
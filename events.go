@@ -0,0 +1,220 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// --- Structured Event Log ---
+//
+// events.jsonl, next to settings.json, gets one JSON object per line for
+// every state change, for log shippers and scripts to tail. The schema is
+// versioned by "v"; within a version fields are only ever added, never
+// renamed, removed or given a new meaning.
+//
+// Every event has:
+//
+//	ts      string  RFC 3339 time with the local offset
+//	v       number  schema version, currently 1
+//	event   string  one of the event types below
+//
+// session_start   a manual session began
+//
+//	mode        string  mode name, e.g. "Cappuccino"
+//	duration_s  number  planned length in seconds, -1 for no limit
+//	source      string  what started it, e.g. "the tray menu" or "API token 1a2b3c"
+//	user        string  the Windows account Espresso runs as, e.g. "PC\alice"
+//
+// session_end     a manual session ended
+//
+//	mode    string  mode name
+//	reason  string  "expired", "stopped", "replaced", "simulated", "released", "battery", "process exited" or "quit"
+//	held_s  number  seconds the session lasted
+//
+// session_extended time was added to a timed session
+//
+//	mode     string  mode name
+//	added_s  number  seconds added
+//
+// session_paused  a manual session was paused; sleep is allowed meanwhile
+//
+//	mode         string  mode name
+//	remaining_s  number  seconds left, -1 for no limit
+//
+// session_resumed a paused session continues
+//
+//	mode  string  mode name
+//
+// trigger_fired   a trigger started or stopped keeping the system awake
+//
+//	trigger  string  trigger name
+//	active   bool    true when it starts holding, false when it lets go
+//	detail   string  what satisfied it, or why it let go
+//
+// inhibit_failed  SetThreadExecutionState rejected a keep-awake request
+//
+//	flags  string  what was requested: "system", "display" or both
+//	error  string  the error reported by Windows
+//
+// device_changed  the displays, the lid or the power source changed
+//
+//	device  string  "displays", "lid" or "power"
+//	state   string  e.g. "2 displays", "closed" or "open", "battery" or "ac"
+//	detail  string  for displays, which ones, e.g. "1: Dell U2720Q (primary), 2: Generic PnP Monitor"
+const eventSchemaVersion = 1
+
+const (
+	eventSessionStart    = "session_start"
+	eventSessionEnd      = "session_end"
+	eventSessionPaused   = "session_paused"
+	eventSessionExtended = "session_extended"
+	eventSessionResumed  = "session_resumed"
+	eventTriggerFired    = "trigger_fired"
+	eventInhibitFailed   = "inhibit_failed"
+	eventDeviceChanged   = "device_changed"
+)
+
+// maxEventLogSize is the size at which events.jsonl is rotated to
+// events.1.jsonl, replacing any previous rotation.
+const maxEventLogSize = 5 << 20
+
+type event struct {
+	Time    string `json:"ts"`
+	Version int    `json:"v"`
+	Event   string `json:"event"`
+
+	Mode       string `json:"mode,omitempty"`
+	DurationS  int64  `json:"duration_s,omitempty"`
+	Source     string `json:"source,omitempty"`
+	User       string `json:"user,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	HeldS      int64  `json:"held_s,omitempty"`
+	RemainingS int64  `json:"remaining_s,omitempty"`
+	AddedS     int64  `json:"added_s,omitempty"`
+
+	Trigger string `json:"trigger,omitempty"`
+	Active  *bool  `json:"active,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+
+	Flags string `json:"flags,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	Device string `json:"device,omitempty"`
+	State  string `json:"state,omitempty"`
+}
+
+var eventLogMu sync.Mutex
+
+func eventLogPath() string {
+	return filepath.Join(filepath.Dir(settingsPath()), "events.jsonl")
+}
+
+// logEvent stamps e and appends it to the event log. Failures are reported
+// in espresso.log only; the log must never disturb the app.
+func logEvent(e event) {
+	e.Time = time.Now().Format(time.RFC3339)
+	e.Version = eventSchemaVersion
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		logWarnf("could not encode event: %v", err)
+		return
+	}
+
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+
+	p := eventLogPath()
+	if info, err := os.Stat(p); err == nil && info.Size() >= maxEventLogSize {
+		_ = os.Rename(p, filepath.Join(filepath.Dir(p), "events.1.jsonl"))
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		logWarnf("could not open event log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		logWarnf("could not write event log: %v", err)
+	}
+}
+
+func logSessionStart(mode string, d time.Duration, source string) {
+	durationS := int64(-1)
+	if d >= 0 {
+		durationS = int64(d.Seconds())
+	}
+	logEvent(event{Event: eventSessionStart, Mode: mode, DurationS: durationS, Source: source, User: currentUser()})
+}
+
+var (
+	userOnce sync.Once
+	userName string
+)
+
+// currentUser returns the account Espresso runs as, or "" if unknown.
+func currentUser() string {
+	userOnce.Do(func() {
+		if u, err := user.Current(); err == nil {
+			userName = u.Username
+		}
+	})
+	return userName
+}
+
+func logSessionEnd(mode, reason string, held time.Duration) {
+	logEvent(event{Event: eventSessionEnd, Mode: mode, Reason: reason, HeldS: int64(held.Seconds())})
+}
+
+func logSessionExtended(mode string, added time.Duration) {
+	logEvent(event{Event: eventSessionExtended, Mode: mode, AddedS: int64(added.Seconds())})
+}
+
+func logSessionPaused(mode string, remaining time.Duration) {
+	remainingS := int64(-1)
+	if remaining >= 0 {
+		remainingS = int64(remaining.Seconds())
+	}
+	logEvent(event{Event: eventSessionPaused, Mode: mode, RemainingS: remainingS})
+}
+
+func logSessionResumed(mode string) {
+	logEvent(event{Event: eventSessionResumed, Mode: mode})
+}
+
+func logTriggerFired(name string, active bool, detail string) {
+	logEvent(event{Event: eventTriggerFired, Trigger: name, Active: &active, Detail: detail})
+}
+
+func logDeviceChanged(device, state, detail string) {
+	logEvent(event{Event: eventDeviceChanged, Device: device, State: state, Detail: detail})
+}
+
+func logInhibitFailed(flags uint32, err error) {
+	logEvent(event{Event: eventInhibitFailed, Flags: flagsText(flags), Error: err.Error()})
+}
@@ -0,0 +1,77 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// --- Expiry Warning ---
+
+// A few minutes before a timed session ends, a notification offers to
+// extend it, so that the machine does not go to sleep behind a user who
+// stepped away.
+
+const defaultExpiryWarning = 5 * time.Minute
+
+// expiryWarning returns how long before the end of a session to warn, or
+// 0 if the expiry_warning setting is "none".
+func (cfg Config) expiryWarning() time.Duration {
+	switch s := strings.TrimSpace(cfg.ExpiryWarning); {
+	case s == "":
+		return defaultExpiryWarning
+	case strings.EqualFold(s, "none"):
+		return 0
+	default:
+		d, err := parseSessionDuration(s)
+		if err != nil {
+			logWarnf("invalid expiry_warning %q, using %s", s, defaultExpiryWarning)
+			return defaultExpiryWarning
+		}
+		return d
+	}
+}
+
+// warnExpiry shows the expiry warning once remaining reaches it. Extending
+// the session past the warning arms it again.
+func (a *app) warnExpiry(remaining time.Duration) {
+	warn := a.cfg.expiryWarning()
+	if warn == 0 || a.session.Mode.Duration <= warn {
+		return
+	}
+	if remaining > warn {
+		a.expiryWarned = false
+		return
+	}
+	if a.expiryWarned {
+		return
+	}
+	a.expiryWarned = true
+
+	left := formatFriendlyDuration(remaining.Round(time.Minute))
+	msg := tr("expiry.message", left, a.sessionEnd().Format("15:04"))
+	if a.sessionOnEnd != "" {
+		msg = tr("expiry.message_action", left, a.sessionOnEnd, a.sessionEnd().Format("15:04"))
+	}
+	actions := append(a.extendActions(), toastAction{Label: tr("expiry.let_end")})
+	go showToastActions(tagSession, tr("expiry.title", a.modeName()), msg, iconPath(), actions)
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Feature Flags ---
+
+// Experimental subsystems are off unless enabled in the "features" object
+// of settings.json, so cautious users can run just the timer. A DWORD of
+// the same name under policyKey\Features forces a flag off (0) or on (1).
+// Flags are read at startup, except for simulation.
+
+const (
+	featureTriggers   = "triggers"   // triggers and schedules
+	featureAPI        = "api"        // the control API and guest links
+	featurePipe       = "pipe"       // the named pipe
+	featureSimulation = "simulation" // activity simulation
+)
+
+var featureNames = []string{featureTriggers, featureAPI, featurePipe, featureSimulation}
+
+// feature reports whether the named subsystem is enabled.
+func (cfg Config) feature(name string) bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, policyKey+`\Features`, registry.QUERY_VALUE)
+	if err == nil {
+		defer k.Close()
+		if v, _, err := k.GetIntegerValue(name); err == nil {
+			return v != 0
+		}
+	}
+	return cfg.Features[name]
+}
+
+// defaultFeatures lists every flag. For a settings.json from before
+// feature flags (earlier), it enables those whose subsystems the file
+// already uses, and the named pipe, which was always on, so that updating
+// does not turn them off.
+func (cfg Config) defaultFeatures(earlier bool) map[string]bool {
+	return map[string]bool{
+		featureTriggers:   len(cfg.allTriggers()) > 0 || len(cfg.Schedules) > 0,
+		featureAPI:        cfg.API.Enabled,
+		featurePipe:       earlier,
+		featureSimulation: cfg.usesSimulation(),
+	}
+}
+
+// usesSimulation reports whether any setting asks for activity
+// simulation; see sessionSimulate.
+func (cfg Config) usesSimulation() bool {
+	simulates := func(s string) bool { return s != "" && !strings.EqualFold(s, simulateNone) }
+	uses := simulates(cfg.Simulate) || cfg.PreventLock
+	for _, m := range cfg.Modes {
+		uses = uses || simulates(m.Simulate) || m.AwayPrevention
+	}
+	return uses
+}
+
+// warnDisabledFeatures points out settings that a disabled flag ignores,
+// and flags that policy turns off.
+func (cfg Config) warnDisabledFeatures() {
+	used := cfg.defaultFeatures(false)
+	for _, name := range featureNames {
+		if cfg.feature(name) {
+			continue
+		}
+		switch {
+		case cfg.Features[name]:
+			logWarnf("the %q feature is turned off by policy", name)
+		case used[name]:
+			logWarnf("%s settings are ignored because the %q feature is disabled", name, name)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// --- Guest Links ---
+
+// A guest link lets someone without an API token start one bounded session
+// through the control API:
+//
+//	http://host:7483/v1/guest?d=3600&exp=1717400000&n=<nonce>&sig=<hmac>
+//
+// d is the session length in seconds and exp the Unix time after which the
+// link no longer works. sig is the HMAC-SHA256 of "d|exp|n" under
+// GuestLinkKey, so links cannot be forged or altered, and each nonce is
+// accepted once.
+
+const (
+	defaultGuestLinkHours = 24
+	maxGuestSession       = 12 * time.Hour
+	sourceGuestLink       = "a guest link"
+)
+
+// GuestLinkConfig holds the key guest links are signed with and the
+// nonces already used.
+type GuestLinkConfig struct {
+	Key        string      `json:"key,omitempty"`         // base64, generated on first use
+	ValidHours int         `json:"valid_hours,omitempty"` // default defaultGuestLinkHours
+	Used       []usedNonce `json:"used,omitempty"`
+}
+
+func (cfg GuestLinkConfig) validHours() int {
+	if cfg.ValidHours <= 0 {
+		return defaultGuestLinkHours
+	}
+	return cfg.ValidHours
+}
+
+type usedNonce struct {
+	Nonce   string `json:"nonce"`
+	Expires int64  `json:"expires"`
+}
+
+// guestLink is a parsed guest link.
+type guestLink struct {
+	duration time.Duration
+	expires  time.Time
+	nonce    string
+	sig      string
+}
+
+func (g guestLink) payload() string {
+	return fmt.Sprintf("%d|%d|%s", int64(g.duration.Seconds()), g.expires.Unix(), g.nonce)
+}
+
+func signGuestLink(key []byte, g guestLink) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(g.payload()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func parseGuestLink(q url.Values) (guestLink, error) {
+	var g guestLink
+	secs, err1 := strconv.ParseInt(q.Get("d"), 10, 64)
+	exp, err2 := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err1 != nil || err2 != nil || q.Get("n") == "" || q.Get("sig") == "" {
+		return g, errors.New("incomplete guest link")
+	}
+	g.duration = time.Duration(secs) * time.Second
+	g.expires = time.Unix(exp, 0)
+	g.nonce = q.Get("n")
+	g.sig = q.Get("sig")
+	return g, nil
+}
+
+// guestLinkKey returns the signing key, creating it on first use.
+func (a *app) guestLinkKey() ([]byte, error) {
+	if key, err := base64.StdEncoding.DecodeString(a.cfg.GuestLinks.Key); err == nil && len(key) >= 32 {
+		return key, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	a.cfg.GuestLinks.Key = base64.StdEncoding.EncodeToString(key)
+	a.saveConfig()
+	return key, nil
+}
+
+// createGuestLink returns a new link for a session of length d and whether
+// other machines can reach it.
+func (a *app) createGuestLink(d time.Duration) (string, bool, error) {
+	if !a.cfg.API.Enabled {
+		return "", false, errors.New("the control API is disabled; enable it in settings.json first")
+	}
+	addrs, err := a.cfg.API.addresses()
+	if err != nil {
+		return "", false, err
+	}
+	key, err := a.guestLinkKey()
+	if err != nil {
+		return "", false, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", false, err
+	}
+
+	g := guestLink{
+		duration: d,
+		expires:  time.Now().Add(time.Duration(a.cfg.GuestLinks.validHours()) * time.Hour).Truncate(time.Second),
+		nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+	}
+
+	// Prefer an address other machines can reach. An unspecified address
+	// such as 0.0.0.0 is not one a browser can open; use one of the
+	// machine's own instead.
+	host, reachable := addrs[0], false
+	for _, addr := range addrs {
+		h, port, err := net.SplitHostPort(addr)
+		if err != nil || isLoopbackHost(h) {
+			continue
+		}
+		if ip := net.ParseIP(h); h == "" || ip != nil && ip.IsUnspecified() {
+			lan, ok := lanAddress(ip != nil && ip.To4() != nil)
+			if !ok {
+				continue
+			}
+			addr = net.JoinHostPort(lan.String(), port)
+		}
+		host, reachable = addr, true
+		break
+	}
+	q := url.Values{}
+	q.Set("d", strconv.FormatInt(int64(d.Seconds()), 10))
+	q.Set("exp", strconv.FormatInt(g.expires.Unix(), 10))
+	q.Set("n", g.nonce)
+	q.Set("sig", signGuestLink(key, g))
+	u := url.URL{Scheme: "http", Host: host, Path: "/v1/guest", RawQuery: q.Encode()}
+	return u.String(), reachable, nil
+}
+
+// lanAddress returns an address of this machine that others on the
+// network can reach, preferring IPv4. With v4only, which a listener on
+// 0.0.0.0 needs, it only returns IPv4 addresses.
+func lanAddress(v4only bool) (net.IP, bool) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		logWarnf("guest link: listing addresses: %v", err)
+		return nil, false
+	}
+	var v6 net.IP
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || !n.IP.IsGlobalUnicast() {
+			continue
+		}
+		if n.IP.To4() != nil {
+			return n.IP, true
+		}
+		if v6 == nil && !v4only {
+			v6 = n.IP
+		}
+	}
+	return v6, v6 != nil
+}
+
+// redeemGuestLink checks a guest link and marks it used.
+func (a *app) redeemGuestLink(g guestLink) error {
+	key, err := base64.StdEncoding.DecodeString(a.cfg.GuestLinks.Key)
+	if err != nil || len(key) == 0 {
+		return errors.New("invalid guest link")
+	}
+	if !hmac.Equal([]byte(g.sig), []byte(signGuestLink(key, g))) {
+		return errors.New("invalid guest link")
+	}
+	if time.Now().After(g.expires) {
+		return errors.New("this guest link has expired")
+	}
+	if g.duration <= 0 || g.duration > maxGuestSession {
+		return errors.New("invalid guest session length")
+	}
+
+	now := time.Now().Unix()
+	used := a.cfg.GuestLinks.Used[:0]
+	for _, u := range a.cfg.GuestLinks.Used {
+		if u.Nonce == g.nonce {
+			return errors.New("this guest link has already been used")
+		}
+		if u.Expires > now {
+			used = append(used, u)
+		}
+	}
+	a.cfg.GuestLinks.Used = append(used, usedNonce{Nonce: g.nonce, Expires: g.expires.Unix()})
+	a.saveConfig()
+	return nil
+}
+
+// askGuestLength prompts for the length of a guest session and sends it on
+// ch.
+func askGuestLength(ch chan<- time.Duration) {
+	text := "1h"
+	for {
+		var ok bool
+		text, ok = inputBox(tr("guest.title"), tr("guest.prompt"), text)
+		if !ok {
+			return
+		}
+		d, err := parseSessionDuration(text)
+		if err != nil {
+			showMessage(tr("guest.title"), err.Error())
+			continue
+		}
+		if d > maxGuestSession {
+			showMessage(tr("guest.title"), tr("guest.too_long"))
+			continue
+		}
+		ch <- d
+		return
+	}
+}
+
+// revealGuestLink copies link to the clipboard and shows it.
+func revealGuestLink(link string, reachable bool, d time.Duration, hours int) {
+	msg := tr("guest.link", formatFriendlyDuration(d), hours, link)
+	if err := copyToClipboard(link); err == nil {
+		msg += "\n\n" + tr("guest.copied")
+	}
+	if !reachable {
+		msg += "\n\n" + tr("guest.local_only")
+	}
+	showMessage(tr("guest.title"), msg)
+}
@@ -0,0 +1,116 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Session History ---
+
+const (
+	historyEntries  = 15
+	historyReadSize = 256 << 10 // tail of events.jsonl to scan
+
+	// historyCauseWindow is how soon after a device change a session or
+	// trigger must end for the change to be named as the likely cause.
+	historyCauseWindow = 2 * time.Minute
+)
+
+// sessionHistory lists the most recent sessions and trigger holds from the
+// event log, newest first, with what started each one, so that keep-awake
+// causes on a shared machine can be audited. Ends that came just after a
+// device change, such as undocking, name it.
+func sessionHistory() string {
+	data, err := tailFile(eventLogPath(), historyReadSize)
+	if err != nil {
+		return fmt.Sprintf("No history is available yet (%v).", err)
+	}
+
+	var entries []string
+	open := -1 // index in entries of the session waiting for its end
+	var device event
+	var deviceAt time.Time
+	// cause names the device change just before ts, if any.
+	cause := func(ts time.Time) string {
+		if deviceAt.IsZero() || ts.Sub(deviceAt) > historyCauseWindow {
+			return ""
+		}
+		return fmt.Sprintf(", %s after %s at %s", ts.Sub(deviceAt).Round(time.Second), describeDeviceChange(device), deviceAt.Format("15:04"))
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var e event
+		if len(line) == 0 || json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, e.Time)
+		if err != nil {
+			continue
+		}
+		when := ts.Format("Mon 02 Jan 15:04")
+
+		switch e.Event {
+		case eventSessionStart:
+			length := "no time limit"
+			if e.DurationS >= 0 {
+				length = formatFriendlyDuration(time.Duration(e.DurationS) * time.Second)
+			}
+			s := fmt.Sprintf("%s  %s (%s), started from %s", when, e.Mode, length, e.Source)
+			if e.User != "" {
+				s += " by " + e.User
+			}
+			entries = append(entries, s)
+			open = len(entries) - 1
+		case eventSessionEnd:
+			if open >= 0 {
+				entries[open] += fmt.Sprintf("\n        %s after %s%s", e.Reason, formatDuration(time.Duration(e.HeldS)*time.Second), cause(ts))
+				open = -1
+			}
+		case eventTriggerFired:
+			if e.Active != nil && *e.Active {
+				s := fmt.Sprintf("%s  Trigger %s", when, e.Trigger)
+				if e.Detail != "" {
+					s += " (" + e.Detail + ")"
+				}
+				entries = append(entries, s)
+			} else if c := cause(ts); c != "" {
+				entries = append(entries, fmt.Sprintf("%s  Trigger %s let go%s", when, e.Trigger, c))
+			}
+		case eventDeviceChanged:
+			device, deviceAt = e, ts
+		}
+	}
+
+	if len(entries) == 0 {
+		return "No sessions have been recorded yet."
+	}
+	if len(entries) > historyEntries {
+		entries = entries[len(entries)-historyEntries:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return "Recent keep-awake activity, newest first:\n\n" + strings.Join(entries, "\n")
+}
@@ -0,0 +1,178 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// --- Global Hotkey ---
+
+const (
+	defaultHotkey = "Ctrl+Alt+E"
+	sourceHotkey  = "the hotkey"
+
+	WM_HOTKEY    = 0x0312
+	MOD_ALT      = 0x0001
+	MOD_CONTROL  = 0x0002
+	MOD_SHIFT    = 0x0004
+	MOD_WIN      = 0x0008
+	MOD_NOREPEAT = 0x4000
+)
+
+var (
+	procRegisterHotKey = user32.NewProc("RegisterHotKey")
+)
+
+// HotkeyConfig is the system-wide shortcut that toggles between Mode and
+// Decaf.
+type HotkeyConfig struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Keys     string `json:"keys,omitempty"` // e.g. "Ctrl+Alt+E", defaultHotkey if empty
+	Mode     string `json:"mode,omitempty"` // preset name, the last one picked if empty
+}
+
+var hotkeyNames = map[string]uint32{
+	"space": 0x20, "enter": 0x0D, "tab": 0x09, "esc": 0x1B, "escape": 0x1B,
+	"pause": 0x13, "insert": 0x2D, "delete": 0x2E, "home": 0x24, "end": 0x23,
+	"pageup": 0x21, "pagedown": 0x22, "left": 0x25, "up": 0x26, "right": 0x27, "down": 0x28,
+}
+
+// parseHotkey parses "Ctrl+Alt+E" into RegisterHotKey modifiers and a
+// virtual-key code. The key is a letter, a digit, F1 to F24 or a name
+// such as "Space" or "Pause", and needs at least one modifier.
+func parseHotkey(s string) (mods, vk uint32, err error) {
+	parts := strings.Split(s, "+")
+	for _, p := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(p)) {
+		case "ctrl", "control":
+			mods |= MOD_CONTROL
+		case "alt":
+			mods |= MOD_ALT
+		case "shift":
+			mods |= MOD_SHIFT
+		case "win":
+			mods |= MOD_WIN
+		default:
+			return 0, 0, fmt.Errorf("unknown modifier %q in hotkey %q", p, s)
+		}
+	}
+	if mods == 0 {
+		return 0, 0, fmt.Errorf("hotkey %q needs a modifier such as Ctrl or Alt", s)
+	}
+
+	key := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+	fn, fErr := strconv.Atoi(strings.TrimPrefix(key, "f"))
+	switch {
+	case len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= '0' && key[0] <= '9'):
+		vk = uint32(strings.ToUpper(key)[0])
+	case strings.HasPrefix(key, "f") && fErr == nil && fn >= 1 && fn <= 24:
+		vk = 0x70 + uint32(fn-1) // VK_F1
+	default:
+		var ok bool
+		if vk, ok = hotkeyNames[key]; !ok {
+			return 0, 0, fmt.Errorf("unknown key %q in hotkey %q", parts[len(parts)-1], s)
+		}
+	}
+	return mods | MOD_NOREPEAT, vk, nil
+}
+
+// startHotkey registers the hotkey and returns the channel on which its
+// presses are reported, or nil if it is disabled or cannot be registered.
+func startHotkey(cfg HotkeyConfig) <-chan struct{} {
+	if cfg.Disabled {
+		return nil
+	}
+	keys := cfg.Keys
+	if keys == "" {
+		keys = defaultHotkey
+	}
+	mods, vk, err := parseHotkey(keys)
+	if err != nil {
+		logWarnf("%v", err)
+		return nil
+	}
+
+	ch := make(chan struct{})
+	go func() {
+		// WM_HOTKEY is posted to the thread that registered the hotkey.
+		runtime.LockOSThread()
+		if r, _, err := procRegisterHotKey.Call(0, 1, uintptr(mods), uintptr(vk)); r == 0 {
+			logWarnf("could not register hotkey %s (in use by another program?): %v", keys, err)
+			return
+		}
+		var m winMsg
+		for {
+			r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 {
+				return
+			}
+			if m.Message == WM_HOTKEY {
+				ch <- struct{}{}
+			}
+		}
+	}()
+	return ch
+}
+
+// hotkeyModeName is the name of the mode the hotkey starts: that of the
+// profile in effect, hotkey.mode or the last one picked.
+func (a *app) hotkeyModeName() string {
+	if p := a.activeProfile(); p != nil && p.Mode != "" {
+		return p.Mode
+	}
+	if a.cfg.Hotkey.Mode != "" {
+		return a.cfg.Hotkey.Mode
+	}
+	return a.cfg.LastMode
+}
+
+// hotkeyMode is the mode the hotkey starts.
+func (a *app) hotkeyMode() (EspressoMode, bool) {
+	name := a.hotkeyModeName()
+	for _, m := range modes {
+		if m.Name == name || name == "" {
+			return m, true
+		}
+	}
+	return EspressoMode{}, false
+}
+
+// toggleFromHotkey stops the manual session, or starts the hotkey's mode
+// if none is active.
+func (a *app) toggleFromHotkey() {
+	if a.active() {
+		a.resetState("stopped")
+		go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+		return
+	}
+	m, ok := a.hotkeyMode()
+	if !ok {
+		logWarnf("hotkey mode %q does not exist", a.hotkeyModeName())
+		return
+	}
+	a.startSession(m, sourceHotkey)
+	go showToast(tagSession, tr("toast.started", m.Name), a.startedMessage(), iconPath())
+}
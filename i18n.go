@@ -0,0 +1,139 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/getlantern/systray"
+)
+
+// --- Internationalization ---
+
+// Each locales/<tag>.json maps message keys to text in that language, with
+// fmt verbs for arguments. en-US is complete; missing keys in other
+// locales fall back to it.
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// languages are the locales offered in the Language menu, in menu order.
+var languages = []string{"en-US", "es-ES", "de-DE"}
+
+var (
+	fallbackCatalog = mustLoadCatalog(defaultLanguage)
+	catalog         atomic.Pointer[map[string]string]
+)
+
+func mustLoadCatalog(tag string) map[string]string {
+	c, err := loadCatalog(tag)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func loadCatalog(tag string) (map[string]string, error) {
+	data, err := localeFiles.ReadFile("locales/" + tag + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q", tag)
+	}
+	var c map[string]string
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("locale %s: %w", tag, err)
+	}
+	return c, nil
+}
+
+// setLanguage switches the text returned by tr. Unknown languages fall
+// back to en-US.
+func setLanguage(tag string) {
+	c, err := loadCatalog(tag)
+	if err != nil {
+		logWarnf("%v", err)
+		c = fallbackCatalog
+	}
+	catalog.Store(&c)
+}
+
+// tr returns the text for key in the current language, formatted with args
+// if any. It may be called from any goroutine.
+func tr(key string, args ...any) string {
+	text, ok := "", false
+	if c := catalog.Load(); c != nil {
+		text, ok = (*c)[key]
+	}
+	if !ok {
+		if text, ok = fallbackCatalog[key]; !ok {
+			text = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// --- Localized Menu Items ---
+
+// menuText is a menu item whose title and tooltip come from key and
+// key+".tip", so that they can be redrawn when the language changes.
+type menuText struct {
+	item *systray.MenuItem
+	key  string
+	args []any
+}
+
+var menuTexts []menuText
+
+// localize registers item for relabelMenu and returns it.
+func localize(item *systray.MenuItem, key string, args ...any) *systray.MenuItem {
+	menuTexts = append(menuTexts, menuText{item: item, key: key, args: args})
+	return item
+}
+
+// addMenuItem adds a localized top-level item, or a submenu item if parent
+// is not nil.
+func addMenuItem(parent *systray.MenuItem, key string, args ...any) *systray.MenuItem {
+	if parent == nil {
+		return localize(systray.AddMenuItem(tr(key, args...), tr(key+".tip")), key, args...)
+	}
+	return localize(parent.AddSubMenuItem(tr(key, args...), tr(key+".tip")), key, args...)
+}
+
+// addMenuCheckbox is addMenuItem for checkbox items.
+func addMenuCheckbox(parent *systray.MenuItem, key string, checked bool) *systray.MenuItem {
+	if parent == nil {
+		return localize(systray.AddMenuItemCheckbox(tr(key), tr(key+".tip"), checked), key)
+	}
+	return localize(parent.AddSubMenuItemCheckbox(tr(key), tr(key+".tip"), checked), key)
+}
+
+// relabelMenu redraws every localized menu item in the current language.
+func relabelMenu() {
+	for _, t := range menuTexts {
+		t.item.SetTitle(tr(t.key, t.args...))
+		t.item.SetTooltip(tr(t.key + ".tip"))
+	}
+}
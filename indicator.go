@@ -0,0 +1,226 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// --- Hardware Indicator ---
+
+// An indicator mirrors Espresso's state on hardware, such as keyboard or
+// LED strip lighting, by running a script and/or loading an OpenRGB
+// profile whenever the state changes.
+
+const (
+	indicatorActive    = "active"    // a manual session keeps awake
+	indicatorTriggered = "triggered" // only triggers keep awake
+	indicatorPaused    = "paused"
+	indicatorIdle      = "idle"
+
+	indicatorTimeout     = 10 * time.Second
+	defaultOpenRGBServer = "127.0.0.1:6742"
+)
+
+// IndicatorConfig configures the hardware indicator.
+type IndicatorConfig struct {
+	// Command runs with Args and the state ("active", "triggered",
+	// "paused" or "idle") as its last argument. The state and mode name
+	// are also in ESPRESSO_STATE and ESPRESSO_MODE.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+
+	OpenRGB OpenRGBConfig `json:"openrgb"`
+}
+
+// OpenRGBConfig loads an OpenRGB profile for each state through the SDK
+// server of OpenRGB. States without a profile leave the lighting alone.
+type OpenRGBConfig struct {
+	Server   string            `json:"server,omitempty"` // host:port, default defaultOpenRGBServer
+	Profiles map[string]string `json:"profiles,omitempty"`
+}
+
+type indicatorState struct {
+	state string
+	mode  string
+}
+
+// indicator applies state changes on its own goroutine, so a slow script
+// cannot hold up the main loop. Only the latest state is applied.
+type indicator struct {
+	last    string
+	pending chan indicatorState
+	done    chan struct{} // closed when the goroutine exits
+}
+
+func startIndicator(cfg IndicatorConfig) *indicator {
+	ind := &indicator{pending: make(chan indicatorState, 1), done: make(chan struct{})}
+	if cfg.Command == "" && len(cfg.OpenRGB.Profiles) == 0 {
+		close(ind.done)
+		return ind
+	}
+	go func() {
+		defer close(ind.done)
+		for s := range ind.pending {
+			if cfg.Command != "" {
+				if err := runIndicatorCommand(cfg, s); err != nil {
+					logWarnf("indicator command failed: %v", err)
+				}
+			}
+			if profile := cfg.OpenRGB.Profiles[s.state]; profile != "" {
+				if err := loadOpenRGBProfile(cfg.OpenRGB.Server, profile); err != nil {
+					logWarnf("could not load OpenRGB profile %q: %v", profile, err)
+				}
+			}
+		}
+	}()
+	return ind
+}
+
+// set reports the current state; unchanged states are ignored.
+func (ind *indicator) set(state, mode string) {
+	if ind == nil || state == ind.last {
+		return
+	}
+	ind.last = state
+	select {
+	case <-ind.pending: // superseded
+	default:
+	}
+	ind.pending <- indicatorState{state, mode}
+}
+
+// close shows the idle state and waits for it to be applied, before
+// Espresso exits.
+func (ind *indicator) close() {
+	ind.set(indicatorIdle, "Decaf")
+	close(ind.pending)
+	select {
+	case <-ind.done:
+	case <-time.After(indicatorTimeout):
+	}
+}
+
+// indicatorState returns the state shown by the indicator.
+func (a *app) indicatorState() string {
+	switch {
+	case a.active() && !a.paused():
+		return indicatorActive
+	case a.triggerFlags() != 0 || a.quickFlags != 0:
+		return indicatorTriggered
+	case a.paused():
+		return indicatorPaused
+	default:
+		return indicatorIdle
+	}
+}
+
+func runIndicatorCommand(cfg IndicatorConfig, s indicatorState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), indicatorTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, cfg.Command, append(append([]string(nil), cfg.Args...), s.state)...)
+	cmd.Env = append(os.Environ(), "ESPRESSO_STATE="+s.state, "ESPRESSO_MODE="+s.mode)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	return cmd.Run()
+}
+
+// --- OpenRGB ---
+
+// OpenRGB SDK packet IDs. Profiles need protocol version 2.
+const (
+	openRGBRequestProtocolVersion = 40
+	openRGBSetClientName          = 50
+	openRGBLoadProfile            = 151
+
+	openRGBProtocolVersion = 3
+)
+
+// loadOpenRGBProfile asks the OpenRGB SDK server at server to load a saved
+// profile.
+func loadOpenRGBProfile(server, profile string) error {
+	if server == "" {
+		server = defaultOpenRGBServer
+	}
+	conn, err := net.DialTimeout("tcp", server, 3*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := writeOpenRGB(conn, openRGBSetClientName, []byte("Espresso\x00")); err != nil {
+		return err
+	}
+	version := binary.LittleEndian.AppendUint32(nil, openRGBProtocolVersion)
+	if err := writeOpenRGB(conn, openRGBRequestProtocolVersion, version); err != nil {
+		return err
+	}
+	// Servers before version 1 do not answer, and time out here.
+	id, body, err := readOpenRGB(conn)
+	if err != nil {
+		return fmt.Errorf("no protocol version from the server: %w", err)
+	}
+	if id != openRGBRequestProtocolVersion || len(body) < 4 {
+		return fmt.Errorf("unexpected reply %d from the server", id)
+	}
+	if v := binary.LittleEndian.Uint32(body); v < 2 {
+		return fmt.Errorf("the server speaks protocol version %d, but profiles need version 2", v)
+	}
+	return writeOpenRGB(conn, openRGBLoadProfile, append([]byte(profile), 0))
+}
+
+// writeOpenRGB sends a packet for device 0: the "ORGB" magic, device index,
+// packet ID and body size, followed by the body.
+func writeOpenRGB(w io.Writer, id uint32, body []byte) error {
+	var b bytes.Buffer
+	b.WriteString("ORGB")
+	binary.Write(&b, binary.LittleEndian, [3]uint32{0, id, uint32(len(body))})
+	b.Write(body)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func readOpenRGB(r io.Reader) (uint32, []byte, error) {
+	var hdr struct {
+		Magic  [4]byte
+		Device uint32
+		ID     uint32
+		Size   uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return 0, nil, err
+	}
+	if string(hdr.Magic[:]) != "ORGB" || hdr.Size > 1<<20 {
+		return 0, nil, fmt.Errorf("not an OpenRGB server")
+	}
+	body := make([]byte, hdr.Size)
+	_, err := io.ReadFull(r, body)
+	return hdr.ID, body, err
+}
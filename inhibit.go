@@ -0,0 +1,115 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "time"
+
+// --- Keep-Awake Health ---
+
+// Windows can refuse a keep-awake request, and a user who is not told
+// assumes the PC is protected. Failures turn the tray icon into a red
+// warning and show a notification once; the request is made again every
+// reassertInterval for as long as something should keep the PC awake, and
+// the warning clears once Windows accepts it.
+//
+// An accepted request can also be lost later: other apps, power policies
+// and Modern Standby override the execution state. Before each repeat
+// Espresso checks what Windows reports as held, and says so once when part
+// of its request is missing.
+
+const (
+	defaultReassertInterval = time.Minute
+	minReassertInterval     = 10 * time.Second
+)
+
+// reassertInterval returns how often the keep-awake request is repeated.
+func (a *app) reassertInterval() time.Duration {
+	if a.cfg.ReassertSeconds <= 0 {
+		return defaultReassertInterval
+	}
+	return max(time.Duration(a.cfg.ReassertSeconds)*time.Second, minReassertInterval)
+}
+
+// heldFlags returns what the session and triggers currently keep awake, or
+// 0 if sleep is allowed.
+func (a *app) heldFlags() uint32 {
+	if !a.active() || a.paused() {
+		return a.triggerFlags() | a.quickFlags
+	}
+	flags := a.sessionFlags() | a.triggerFlags() | a.quickFlags
+	if a.cfg.PreventLock {
+		flags |= ES_DISPLAY_REQUIRED
+	}
+	return flags
+}
+
+// reassertKeepAwake repeats the keep-awake request every reassertInterval.
+func (a *app) reassertKeepAwake() {
+	flags := a.heldFlags()
+	if flags == 0 || time.Since(a.lastAssert) < a.reassertInterval() {
+		return
+	}
+	if !a.inhibitFailed {
+		a.checkHeld(flags)
+	}
+	failed := a.inhibitFailed
+	a.keepAwake(flags)
+	if a.inhibitFailed != failed {
+		a.setActiveIcon()
+	}
+}
+
+// checkHeld compares what Windows reports as held with flags, which
+// Espresso requested at the last assertion.
+func (a *app) checkHeld(flags uint32) {
+	state, ok := systemHeld()
+	if !ok {
+		return
+	}
+	lost := flags &^ state
+	switch {
+	case lost != 0 && a.heldLost == 0:
+		logWarnf("keep-awake request 0x%08X was overridden; Windows reports 0x%08X", flags, state)
+		key := "inhibit.lost_system"
+		if lost&ES_SYSTEM_REQUIRED == 0 {
+			key = "inhibit.lost_display"
+		}
+		go showToast(tagSession, tr("inhibit.lost_title"), tr(key), icoffPath())
+	case lost == 0 && a.heldLost != 0:
+		logInfof("keep-awake request held again")
+	}
+	a.heldLost = lost
+}
+
+// inhibitResult updates the warning after a keep-awake request that
+// failed with err, or succeeded if err is nil.
+func (a *app) inhibitResult(err error) {
+	switch {
+	case err != nil && !a.inhibitFailed:
+		a.inhibitFailed = true
+		go showToast(tagSession, tr("inhibit.failed_title"), tr("inhibit.failed", err), icoffPath())
+	case err == nil && a.inhibitFailed:
+		a.inhibitFailed = false
+		a.iconText = ""
+		logInfof("keep-awake request accepted again")
+		go showToast(tagSession, tr("inhibit.restored_title"), tr("inhibit.restored"), iconPath())
+	}
+}
@@ -0,0 +1,194 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Instance Forwarding ---
+
+// Later instances (started by a deep link or from the command line) find
+// the running one by its hidden window and forward their command line in
+// WM_COPYDATA. A window message can only return a number, so the reply is
+// written to a temporary file named by the sender.
+
+const (
+	ipcClassName     = "EspressoIPC"
+	ipcReplyPattern  = "espresso-reply-*.json"
+	ipcReplyTimeout  = 10 * time.Second
+	copyDataRequest  = 0x45535032 // "ESP2": JSON ipcRequest
+	maxCopyDataBytes = 64 << 10
+
+	WM_COPYDATA = 0x004A
+)
+
+var (
+	procFindWindowW              = user32.NewProc("FindWindowW")
+	procAllowSetForegroundWindow = user32.NewProc("AllowSetForegroundWindow")
+)
+
+// copyDataStruct mirrors COPYDATASTRUCT.
+type copyDataStruct struct {
+	Data uintptr
+	Size uint32
+	Ptr  uintptr
+}
+
+// ipcRequest is the WM_COPYDATA payload.
+type ipcRequest struct {
+	Args  []string `json:"args"`
+	Reply string   `json:"reply,omitempty"` // file for the ipcReply
+}
+
+type ipcReply struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ipcCall is a forwarded command line handed to the main loop.
+type ipcCall struct {
+	args  []string
+	reply chan ipcReply
+}
+
+// startIPC creates the window that receives forwarded command lines and
+// returns the channel they are delivered on. Every call must be answered.
+func startIPC() <-chan ipcCall {
+	ch := make(chan ipcCall)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		_, err := createWindow(ipcClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0,
+			func(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+				if msg != WM_COPYDATA {
+					return defWindowProc(hwnd, msg, wParam, lParam)
+				}
+				req, err := readCopyData(lParam)
+				if err != nil {
+					logWarnf("ignoring forwarded command line: %v", err)
+					return 0
+				}
+
+				c := ipcCall{args: req.Args, reply: make(chan ipcReply, 1)}
+				var rep ipcReply
+				select {
+				case ch <- c:
+					rep = <-c.reply
+				case <-time.After(ipcReplyTimeout):
+					rep.Error = "Espresso is busy"
+				}
+				if err := writeIPCReply(req.Reply, rep); err != nil {
+					logWarnf("could not write command reply: %v", err)
+				}
+				return 1
+			})
+		if err != nil {
+			logWarnf("could not create IPC window: %v", err)
+			return
+		}
+		runMessageLoop(0)
+	}()
+	return ch
+}
+
+func readCopyData(lParam uintptr) (ipcRequest, error) {
+	var req ipcRequest
+	var cds copyDataStruct
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&cds)), lParam, unsafe.Sizeof(cds))
+	if cds.Data != copyDataRequest || cds.Size == 0 || cds.Size > maxCopyDataBytes {
+		return req, errors.New("unexpected WM_COPYDATA payload")
+	}
+	buf := make([]byte, cds.Size)
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&buf[0])), cds.Ptr, uintptr(cds.Size))
+
+	err := json.Unmarshal(buf, &req)
+	return req, err
+}
+
+// writeIPCReply writes rep to path, which must be a reply file in the temp
+// directory so that a sender cannot make Espresso overwrite other files.
+func writeIPCReply(path string, rep ipcReply) error {
+	if path == "" {
+		return nil
+	}
+	if ok, _ := filepath.Match(ipcReplyPattern, filepath.Base(path)); !ok ||
+		!strings.EqualFold(filepath.Clean(filepath.Dir(path)), filepath.Clean(os.TempDir())) {
+		return fmt.Errorf("refusing to write reply to %s", path)
+	}
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// forwardArgs hands args to the running instance and returns its reply.
+func forwardArgs(args []string) (ipcReply, error) {
+	var rep ipcReply
+	cls, _ := windows.UTF16PtrFromString(ipcClassName)
+	hwnd, _, _ := procFindWindowW.Call(uintptr(unsafe.Pointer(cls)), 0)
+	if hwnd == 0 {
+		return rep, errors.New("Espresso is not running")
+	}
+
+	f, err := os.CreateTemp("", ipcReplyPattern)
+	if err != nil {
+		return rep, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	data, err := json.Marshal(ipcRequest{Args: args, Reply: f.Name()})
+	if err != nil {
+		return rep, err
+	}
+	cds := copyDataStruct{Data: copyDataRequest, Size: uint32(len(data)), Ptr: uintptr(unsafe.Pointer(&data[0]))}
+
+	// Let the running instance bring its prompts to the front.
+	var pid uint32
+	windows.GetWindowThreadProcessId(windows.HWND(hwnd), &pid)
+	procAllowSetForegroundWindow.Call(uintptr(pid))
+
+	r, _, _ := procSendMessageW.Call(hwnd, WM_COPYDATA, 0, uintptr(unsafe.Pointer(&cds)))
+	runtime.KeepAlive(data)
+	if r == 0 {
+		return rep, errors.New("the running instance rejected the command")
+	}
+
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		return rep, err
+	}
+	err = json.Unmarshal(out, &rep)
+	return rep, err
+}
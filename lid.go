@@ -0,0 +1,218 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Lid Policy ---
+
+// With ignore_lid on, closing the laptop lid does nothing while a session
+// runs, so that a laptop driving external monitors can be used closed.
+// Espresso sets the lid close action of the active power plan to "Do
+// nothing", on battery and plugged in, and puts back the previous actions
+// when the session ends or Espresso exits. The previous actions are kept
+// in lid.json until then, so that they are put back on the next start if
+// Espresso crashes. The power button is left alone: it is how the user
+// still puts the PC to sleep on purpose.
+
+var (
+	procPowerGetActiveScheme   = modpowrprof.NewProc("PowerGetActiveScheme")
+	procPowerSetActiveScheme   = modpowrprof.NewProc("PowerSetActiveScheme")
+	procPowerReadACValueIndex  = modpowrprof.NewProc("PowerReadACValueIndex")
+	procPowerReadDCValueIndex  = modpowrprof.NewProc("PowerReadDCValueIndex")
+	procPowerWriteACValueIndex = modpowrprof.NewProc("PowerWriteACValueIndex")
+	procPowerWriteDCValueIndex = modpowrprof.NewProc("PowerWriteDCValueIndex")
+)
+
+var (
+	GUID_SYSTEM_BUTTON_SUBGROUP = windows.GUID{Data1: 0x4f971e89, Data2: 0xeebd, Data3: 0x4455, Data4: [8]byte{0xa8, 0xde, 0x9e, 0x59, 0x04, 0x0e, 0x73, 0x47}}
+	GUID_LIDCLOSE_ACTION        = windows.GUID{Data1: 0x5ca83367, Data2: 0x6e45, Data3: 0x459f, Data4: [8]byte{0xa2, 0x7b, 0x47, 0x6b, 0x1d, 0x01, 0xc9, 0x36}}
+)
+
+const lidDoNothing = 0 // lid close action index; 1 sleeps, 2 hibernates, 3 shuts down
+
+// lidPolicy is the lid close action of a power plan, as saved in lid.json.
+type lidPolicy struct {
+	Scheme string `json:"scheme"`
+	AC     uint32 `json:"ac"`
+	DC     uint32 `json:"dc"`
+}
+
+func lidPolicyPath() string {
+	return filepath.Join(filepath.Dir(settingsPath()), "lid.json")
+}
+
+// syncLidPolicy ignores the lid while a session runs, if ignore_lid is on,
+// and puts back the previous lid close action otherwise.
+func (a *app) syncLidPolicy() {
+	want := a.cfg.IgnoreLid && a.active() && !a.paused()
+	switch {
+	case want && a.lidSaved == nil:
+		p, err := overrideLid()
+		if err != nil {
+			logWarnf("could not ignore the lid: %v", err)
+			return
+		}
+		a.lidSaved = p
+	case !want && a.lidSaved != nil:
+		a.restoreLid()
+	}
+}
+
+// restoreLid puts back the lid close action Espresso replaced, if any.
+func (a *app) restoreLid() {
+	if a.lidSaved == nil {
+		return
+	}
+	if err := a.lidSaved.restore(); err != nil {
+		logWarnf("could not restore the lid close action: %v", err)
+		return // lid.json stays for the next start
+	}
+	a.lidSaved = nil
+}
+
+// restoreSavedLid puts back a lid close action left in lid.json by an
+// Espresso that did not exit cleanly.
+func restoreSavedLid() {
+	data, err := os.ReadFile(lidPolicyPath())
+	if err != nil {
+		return
+	}
+	var p lidPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		logWarnf("ignoring lid.json: %v", err)
+		os.Remove(lidPolicyPath())
+		return
+	}
+	logInfof("Restoring the lid close action left by the last run")
+	if err := p.restore(); err != nil {
+		logWarnf("could not restore the lid close action: %v", err)
+	}
+}
+
+// overrideLid saves the lid close action of the active power plan to
+// lid.json and sets it to do nothing.
+func overrideLid() (*lidPolicy, error) {
+	if !procAvailable(procPowerGetActiveScheme) {
+		return nil, fmt.Errorf("not supported on this version of Windows")
+	}
+	scheme, err := activeScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &lidPolicy{Scheme: scheme.String()}
+	if err := lidValue(procPowerReadACValueIndex, &scheme, &p.AC); err != nil {
+		return nil, err
+	}
+	if err := lidValue(procPowerReadDCValueIndex, &scheme, &p.DC); err != nil {
+		return nil, err
+	}
+	if p.AC == lidDoNothing && p.DC == lidDoNothing {
+		return nil, nil // nothing to change
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(lidPolicyPath(), data, 0600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("saving the lid close action: %w", err)
+	}
+	if err := setLidAction(&scheme, lidDoNothing, lidDoNothing); err != nil {
+		os.Remove(lidPolicyPath())
+		return nil, err
+	}
+	logInfof("Ignoring the lid (was %d plugged in, %d on battery)", p.AC, p.DC)
+	return p, nil
+}
+
+// restore puts the saved lid close action back and removes lid.json.
+func (p *lidPolicy) restore() error {
+	scheme, err := windows.GUIDFromString(p.Scheme)
+	if err != nil {
+		os.Remove(lidPolicyPath())
+		return err
+	}
+	if err := setLidAction(&scheme, p.AC, p.DC); err != nil {
+		return err
+	}
+	logInfof("Lid close action restored")
+	if err := os.Remove(lidPolicyPath()); err != nil && !os.IsNotExist(err) {
+		logWarnf("could not remove lid.json: %v", err)
+	}
+	return nil
+}
+
+// activeScheme returns the GUID of the active power plan.
+func activeScheme() (windows.GUID, error) {
+	var scheme *windows.GUID
+	if r, _, _ := procPowerGetActiveScheme.Call(0, uintptr(unsafe.Pointer(&scheme))); r != 0 {
+		return windows.GUID{}, fmt.Errorf("PowerGetActiveScheme: %w", windows.Errno(r))
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(scheme)))
+	return *scheme, nil
+}
+
+// lidValue reads the lid close action of scheme with read, which is
+// PowerReadACValueIndex or PowerReadDCValueIndex.
+func lidValue(read *windows.LazyProc, scheme *windows.GUID, v *uint32) error {
+	r, _, _ := read.Call(0, uintptr(unsafe.Pointer(scheme)),
+		uintptr(unsafe.Pointer(&GUID_SYSTEM_BUTTON_SUBGROUP)), uintptr(unsafe.Pointer(&GUID_LIDCLOSE_ACTION)),
+		uintptr(unsafe.Pointer(v)))
+	if r != 0 {
+		return fmt.Errorf("%s: %w", read.Name, windows.Errno(r))
+	}
+	return nil
+}
+
+// setLidAction sets the lid close action of scheme and applies it if the
+// scheme is active.
+func setLidAction(scheme *windows.GUID, ac, dc uint32) error {
+	for _, w := range []struct {
+		proc *windows.LazyProc
+		v    uint32
+	}{{procPowerWriteACValueIndex, ac}, {procPowerWriteDCValueIndex, dc}} {
+		r, _, _ := w.proc.Call(0, uintptr(unsafe.Pointer(scheme)),
+			uintptr(unsafe.Pointer(&GUID_SYSTEM_BUTTON_SUBGROUP)), uintptr(unsafe.Pointer(&GUID_LIDCLOSE_ACTION)),
+			uintptr(w.v))
+		if r != 0 {
+			return fmt.Errorf("%s: %w", w.proc.Name, windows.Errno(r))
+		}
+	}
+	// Written values only take effect once the scheme is made active
+	// again; a plan the user has since switched away from stays inactive.
+	if active, err := activeScheme(); err != nil || active != *scheme {
+		return err
+	}
+	if r, _, _ := procPowerSetActiveScheme.Call(0, uintptr(unsafe.Pointer(scheme))); r != 0 {
+		return fmt.Errorf("PowerSetActiveScheme: %w", windows.Errno(r))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Location Condition ---
+
+// A trigger with "location": "office" only holds while the Windows location
+// service puts the machine inside the "office" place, and "!home" only while
+// it is outside "home". Places are defined in settings.json, and nothing
+// asks Windows for the location unless location_access is set.
+
+const (
+	locationCacheTTL    = 5 * time.Minute
+	locationTimeout     = 30 * time.Second
+	defaultPlaceRadiusM = 200
+	earthRadiusM        = 6371000
+)
+
+// PlaceConfig is a circular geofence.
+type PlaceConfig struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	RadiusM   float64 `json:"radius_m,omitempty"` // 0 for defaultPlaceRadiusM
+}
+
+func (p PlaceConfig) radius() float64 {
+	if p.RadiusM <= 0 {
+		return defaultPlaceRadiusM
+	}
+	return p.RadiusM
+}
+
+// contains reports whether the point is within the place's radius.
+func (p PlaceConfig) contains(lat, lon float64) bool {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(lat - p.Latitude)
+	dLon := rad(lon - p.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(p.Latitude))*math.Cos(rad(lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2*earthRadiusM*math.Asin(math.Sqrt(h)) <= p.radius()
+}
+
+// The location is read with the .NET GeoCoordinateWatcher, which goes
+// through the Windows location service and honours its privacy settings.
+const locationScript = `
+Add-Type -AssemblyName System.Device
+$w = New-Object System.Device.Location.GeoCoordinateWatcher
+if (-not $w.TryStart($false, [TimeSpan]::FromSeconds(20))) { exit 2 }
+$c = $w.Position.Location
+if ($c.IsUnknown) { exit 3 }
+[Console]::Out.Write(('{0} {1}' -f $c.Latitude, $c.Longitude).Replace(',', '.'))
+`
+
+var (
+	locationMu      sync.Mutex
+	locationChecked time.Time
+	locationLat     float64
+	locationLon     float64
+	locationErr     error
+)
+
+// currentLocation returns the machine's coordinates. Fixes are slow and may
+// prompt Windows to show the location-in-use icon, so they are cached for
+// locationCacheTTL and shared by all triggers.
+func currentLocation() (float64, float64, error) {
+	locationMu.Lock()
+	defer locationMu.Unlock()
+	if time.Since(locationChecked) < locationCacheTTL {
+		return locationLat, locationLon, locationErr
+	}
+	locationLat, locationLon, locationErr = queryLocation()
+	locationChecked = time.Now()
+	return locationLat, locationLon, locationErr
+}
+
+func queryLocation() (float64, float64, error) {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", locationScript)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+
+	done := make(chan struct{})
+	timer := time.AfterFunc(locationTimeout, func() {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		close(done)
+	})
+	out, err := cmd.Output()
+	if !timer.Stop() {
+		<-done
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("location unavailable (is location access on in Windows privacy settings?): %v", err)
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected location output %q", out)
+	}
+	lat, err1 := strconv.ParseFloat(fields[0], 64)
+	lon, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("unexpected location output %q", out)
+	}
+	return lat, lon, nil
+}
+
+// locationTrigger holds only while the inner trigger holds and the machine
+// is inside (or, with outside set, outside) the place.
+type locationTrigger struct {
+	inner   trigger
+	place   PlaceConfig
+	outside bool
+}
+
+// newLocationCondition wraps t with the place named by location. Location
+// conditions are refused unless access is true.
+func newLocationCondition(t trigger, location string, places map[string]PlaceConfig, access bool) (trigger, error) {
+	if location == "" {
+		return t, nil
+	}
+	if !access {
+		return nil, fmt.Errorf("location %q requires \"location_access\": true", location)
+	}
+	name := strings.TrimPrefix(location, "!")
+	place, ok := places[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown place %q", name)
+	}
+	return locationTrigger{inner: t, place: place, outside: name != location}, nil
+}
+
+func (t locationTrigger) check() (bool, string, error) {
+	lat, lon, err := currentLocation()
+	if err != nil {
+		return false, "", err
+	}
+	if t.place.contains(lat, lon) == t.outside {
+		return false, "", nil
+	}
+	return t.inner.check()
+}
@@ -0,0 +1,99 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Idle Lock Prevention ---
+
+// With prevent_lock, sessions also hold a display power request, which
+// Windows honours even where an execution state is overridden, and
+// default to mouse activity simulation, since a lock enforced by Group
+// Policy after a period without input ignores both requests.
+
+var (
+	procPowerCreateRequest = modkernel32.NewProc("PowerCreateRequest")
+	procPowerSetRequest    = modkernel32.NewProc("PowerSetRequest")
+	procPowerClearRequest  = modkernel32.NewProc("PowerClearRequest")
+)
+
+const (
+	POWER_REQUEST_CONTEXT_VERSION       = 0
+	POWER_REQUEST_CONTEXT_SIMPLE_STRING = 0x1
+	PowerRequestDisplayRequired         = 0
+)
+
+// reasonContext mirrors REASON_CONTEXT with a simple reason string.
+type reasonContext struct {
+	Version uint32
+	Flags   uint32
+	Reason  *uint16
+	_       [2]uintptr // rest of the Detailed union member
+}
+
+// displayRequest is a PowerRequestDisplayRequired power request, created
+// on first use and set or cleared as needed. It shows up in
+// "powercfg /requests" with Espresso's reason.
+type displayRequest struct {
+	handle windows.Handle
+	active bool
+}
+
+func (r *displayRequest) set(on bool) error {
+	if on == r.active {
+		return nil
+	}
+	if r.handle == 0 {
+		if !procAvailable(procPowerCreateRequest) {
+			return fmt.Errorf("power requests are not supported on this system")
+		}
+		reason, _ := windows.UTF16PtrFromString("Espresso is keeping the display on to prevent the idle lock")
+		ctx := reasonContext{Version: POWER_REQUEST_CONTEXT_VERSION, Flags: POWER_REQUEST_CONTEXT_SIMPLE_STRING, Reason: reason}
+		h, _, err := procPowerCreateRequest.Call(uintptr(unsafe.Pointer(&ctx)))
+		if windows.Handle(h) == windows.InvalidHandle {
+			return fmt.Errorf("PowerCreateRequest failed: %w", err)
+		}
+		r.handle = windows.Handle(h)
+	}
+
+	proc := procPowerClearRequest
+	if on {
+		proc = procPowerSetRequest
+	}
+	if ret, _, err := proc.Call(uintptr(r.handle), PowerRequestDisplayRequired); ret == 0 {
+		return fmt.Errorf("%s failed: %w", proc.Name, err)
+	}
+	r.active = on
+	return nil
+}
+
+// setLockGuard holds the display request while on and prevent_lock is set.
+func (a *app) setLockGuard(on bool) {
+	if err := a.lockGuard.set(on && a.cfg.PreventLock); err != nil {
+		logWarnf("%v", err)
+		logInhibitFailed(ES_DISPLAY_REQUIRED, err)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Logging ---
+
+// Diagnostic messages go to espresso.log next to settings.json, rotated
+// at maxLogSize, or to the Application event log for the service, and to
+// the console when there is one. Only messages at or above log_level are
+// written; "debug" also records every change of the execution state, for
+// reports of a PC that went to sleep anyway.
+
+const maxLogSize = 5 << 20 // 5 MB
+
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+var (
+	minLogLevel atomic.Int32 // a logLevel, levelInfo until the config is loaded
+
+	logMu   sync.Mutex
+	logFile *os.File
+	logSize int64
+	logFail bool // the log file could not be opened; stop trying
+
+	// logEvents is the event source the service logs to instead of a file
+	// in a folder its user controls; see openServiceLog.
+	logEvents windows.Handle
+)
+
+func init() {
+	minLogLevel.Store(int32(levelInfo))
+}
+
+func logPath() string {
+	return filepath.Join(filepath.Dir(settingsPath()), "espresso.log")
+}
+
+// setLogLevel applies the log_level setting; empty means "info".
+func setLogLevel(s string) {
+	if s == "" {
+		s = "info"
+	}
+	for i, name := range logLevelNames {
+		if strings.EqualFold(s, name) {
+			minLogLevel.Store(int32(i))
+			return
+		}
+	}
+	logWarnf("unknown log_level %q (use %s)", s, strings.Join(logLevelNames, ", "))
+}
+
+func debugLogging() bool {
+	return logLevel(minLogLevel.Load()) == levelDebug
+}
+
+func logDebugf(format string, args ...any) { logf(levelDebug, format, args...) }
+func logInfof(format string, args ...any)  { logf(levelInfo, format, args...) }
+func logWarnf(format string, args ...any)  { logf(levelWarn, format, args...) }
+func logErrorf(format string, args ...any) { logf(levelError, format, args...) }
+
+func logf(level logLevel, format string, args ...any) {
+	if level < logLevel(minLogLevel.Load()) {
+		return
+	}
+	line := fmt.Sprintf("%s %-5s %s\n", time.Now().Format("2006-01-02 15:04:05.000"),
+		strings.ToUpper(logLevelNames[level]), fmt.Sprintf(format, args...))
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	fmt.Print(line)
+	if logEvents != 0 {
+		writeLogEvent(level, fmt.Sprintf(format, args...))
+		return
+	}
+	writeLogLine(line)
+}
+
+// writeLogEvent reports msg in the Application event log. Failures are
+// ignored, as for the log file.
+func writeLogEvent(level logLevel, msg string) {
+	kind := uint16(windows.EVENTLOG_INFORMATION_TYPE)
+	switch level {
+	case levelWarn:
+		kind = windows.EVENTLOG_WARNING_TYPE
+	case levelError:
+		kind = windows.EVENTLOG_ERROR_TYPE
+	}
+	text, _ := windows.UTF16PtrFromString(msg)
+	strs := []*uint16{text}
+	_ = windows.ReportEvent(logEvents, kind, 0, 1, 0, 1, 0, &strs[0], nil)
+}
+
+// writeLogLine appends line to the log file, rotating it first if it
+// would grow past maxLogSize. Failures only show on the console; logging
+// must never disturb the app.
+func writeLogLine(line string) {
+	if logFail {
+		return
+	}
+	if logFile != nil && logSize+int64(len(line)) > maxLogSize {
+		logFile.Close()
+		logFile = nil
+		_ = os.Rename(logPath(), filepath.Join(filepath.Dir(logPath()), "espresso.1.log"))
+	}
+	if logFile == nil {
+		f, err := os.OpenFile(logPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			fmt.Printf("could not open log file: %v\n", err)
+			logFail = true
+			return
+		}
+		logFile = f
+		logSize = 0
+		if info, err := f.Stat(); err == nil {
+			logSize = info.Size()
+		}
+	}
+	n, _ := logFile.WriteString(line)
+	logSize += int64(n)
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 /*
    Espresso - A lightweight utility to keep your screen on and your system active.
    Copyright (C) 2025  Rodrigo Toraño Valle
@@ -22,11 +24,13 @@ import (
 	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"unsafe"
@@ -34,7 +38,8 @@ import (
 	"golang.org/x/sys/windows"
 
 	"github.com/getlantern/systray"
-	"github.com/go-toast/toast"
+
+	"espresso/pkg/espresso"
 )
 
 //go:embed assets/icon.ico
@@ -49,24 +54,23 @@ var licenseData []byte
 //go:embed THIRD_PARTY_LICENSES
 var thirdPartyLicenses embed.FS
 
-var execStateCh = make(chan func())
-
 // --- Constants & Config ---
 
 const (
-	defaultLanguage = "en-US"
+	defaultLanguage         = "en-US"
+	defaultInfiniteCapGrace = 1 * time.Hour
 )
 
 var (
-	modkernel32                 = windows.NewLazySystemDLL("kernel32.dll")
-	procSetThreadExecutionState = modkernel32.NewProc("SetThreadExecutionState")
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
 )
 
 const (
-	ES_CONTINUOUS       = 0x80000000
-	ES_SYSTEM_REQUIRED  = 0x00000001
-	ES_DISPLAY_REQUIRED = 0x00000002
-	MB_ICONINFORMATION  = 0x00000040
+	MB_ICONINFORMATION = 0x00000040
+	MB_ICONQUESTION    = 0x00000020
+	MB_ICONWARNING     = 0x00000030
+	MB_YESNO           = 0x00000004
+	IDYES              = 6
 )
 
 var (
@@ -75,38 +79,253 @@ var (
 )
 
 type Config struct {
-	Language string `json:"language"`
+	Language string          `json:"language"`
+	Triggers []TriggerConfig `json:"triggers,omitempty"`
+	Overlay  OverlayConfig   `json:"overlay"`
+
+	// InfiniteReminderHours is how often an infinite session reminds the
+	// user that it is still running. 0 disables the reminder.
+	InfiniteReminderHours float64 `json:"infinite_reminder_hours,omitempty"`
+
+	// InfiniteCapHours turns an infinite session into a timed one ending
+	// InfiniteCapGraceMinutes later (default 60), as a failsafe against
+	// machines left caffeinated for days. 0 disables the cap.
+	InfiniteCapHours        float64 `json:"infinite_cap_hours,omitempty"`
+	InfiniteCapGraceMinutes int     `json:"infinite_cap_grace_minutes,omitempty"`
+
+	API       APIConfig  `json:"api"`
+	APITokens []APIToken `json:"api_tokens,omitempty"`
+
+	// Webhooks let other systems start and stop sessions through the API.
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+
+	// Modes are added to the tray menu after the built-in presets, or
+	// replace them if ReplaceBuiltinModes is set.
+	Modes               []ModeConfig `json:"modes,omitempty"`
+	ReplaceBuiltinModes bool         `json:"replace_builtin_modes,omitempty"`
+
+	// Keep is what manual sessions keep awake ("system", "display" or
+	// both, the default), as chosen in the Keep Awake menu.
+	Keep []string `json:"keep,omitempty"`
+
+	// EndAction is what timed sessions do when they expire unless their
+	// mode says otherwise, as chosen in the When Session Ends menu; see
+	// parseEndAction. EndActionDelay is how long it can be cancelled
+	// before it runs, e.g. "1m" (default 30s); "none" runs it at once.
+	EndAction      string `json:"end_action,omitempty"`
+	EndActionDelay string `json:"end_action_delay,omitempty"`
+
+	// Milestones announce the countdown of timed sessions, e.g.
+	// ["50%", "30m", "10m"] for halfway, 30 and 10 minutes left.
+	Milestones []string `json:"milestones,omitempty"`
+
+	SharedMachine SharedMachineConfig `json:"shared_machine"`
+	GuestLinks    GuestLinkConfig     `json:"guest_links"`
+
+	Schedules []ScheduleConfig `json:"schedules,omitempty"`
+
+	// Profiles are named sets of schedules, triggers and a hotkey mode;
+	// Profile is the one picked in the Profile menu, profileAuto or "".
+	Profiles []ProfileConfig `json:"profiles,omitempty"`
+	Profile  string          `json:"profile,omitempty"`
+
+	// StartWithWindows mirrors the Run entry managed from the menu. With
+	// RestoreLastMode, a login start also restarts LastMode, the preset
+	// most recently picked.
+	StartWithWindows bool   `json:"start_with_windows,omitempty"`
+	RestoreLastMode  bool   `json:"restore_last_mode,omitempty"`
+	LastMode         string `json:"last_mode,omitempty"`
+
+	// Places are geofences for trigger location conditions. The Windows
+	// location service is only queried when LocationAccess is true, and
+	// remote calendar feeds are only downloaded when CalendarAccess is.
+	// Both are nil until the user has been asked; see askAccess.
+	Places         map[string]PlaceConfig `json:"places,omitempty"`
+	LocationAccess *bool                  `json:"location_access,omitempty"`
+	CalendarAccess *bool                  `json:"calendar_access,omitempty"`
+
+	Energy EnergyConfig `json:"energy"`
+
+	Hotkey HotkeyConfig `json:"hotkey"`
+
+	Battery BatteryConfig `json:"battery"`
+
+	// Simulate is the activity simulation used by sessions whose mode does
+	// not set one: "mouse", "key" or "none" (the default).
+	Simulate string `json:"simulate,omitempty"`
+
+	// PreventLock keeps the display on with a power request during
+	// sessions and simulates mouse activity unless Simulate or the mode
+	// says otherwise, to defeat idle locks enforced by policy.
+	PreventLock bool `json:"prevent_lock,omitempty"`
+
+	// SoftLanding dims the displays during the last minute of timed
+	// sessions.
+	SoftLanding bool `json:"soft_landing,omitempty"`
+
+	NightLight NightLightConfig `json:"night_light"`
+
+	// Displays picks the displays that sessions keep on; see
+	// updateDisplayPower.
+	Displays DisplaysConfig `json:"displays"`
+
+	// PresentationAutoStart keeps the system awake while the user is
+	// presenting or running a full-screen app.
+	PresentationAutoStart bool `json:"presentation_auto_start,omitempty"`
+
+	// BlockRestarts asks Windows not to restart, e.g. for updates, while
+	// a session runs; see shutdownBlocker.
+	BlockRestarts bool `json:"block_restarts,omitempty"`
+
+	// IgnoreLid sets the lid close action to do nothing while a session
+	// runs; see syncLidPolicy.
+	IgnoreLid bool `json:"ignore_lid,omitempty"`
+
+	// AccentIcon tints the active tray icon with the Windows accent color.
+	AccentIcon bool `json:"accent_icon,omitempty"`
+
+	// TrayCountdown shows the time left on the tray icon during timed
+	// sessions.
+	TrayCountdown bool `json:"tray_countdown,omitempty"`
+
+	// ExpiryWarning is how long before the end of a timed session to offer
+	// extending it, e.g. "10m"; "none" turns the warning off. The default
+	// is defaultExpiryWarning.
+	ExpiryWarning string `json:"expiry_warning,omitempty"`
+
+	Indicator IndicatorConfig `json:"indicator"`
+
+	Discord DiscordConfig `json:"discord"`
+
+	// WatchProcesses are the processes last watched with Watch Process.
+	WatchProcesses []string `json:"watch_processes,omitempty"`
+
+	// BackMinutes is how long an Until I'm Back session goes on once the
+	// user returns (default 10); see checkBack.
+	BackMinutes int `json:"back_minutes,omitempty"`
+
+	// Service configures "espresso --service"; see runService.
+	Service ServiceConfig `json:"service"`
+
+	// LastSeenVersion is the version whose release notes were announced.
+	LastSeenVersion string `json:"last_seen_version,omitempty"`
+
+	// Notifications is "toast" or "balloon"; by default balloons, with a
+	// copy in the event log, on Windows Server and toasts elsewhere.
+	Notifications string `json:"notifications,omitempty"`
+
+	// ReassertSeconds is how often the keep-awake request is repeated and
+	// checked, at least 10; 0 means every minute.
+	ReassertSeconds int `json:"reassert_seconds,omitempty"`
+
+	// LogLevel is the least severe level written to espresso.log:
+	// "debug", "info" (the default), "warn" or "error".
+	LogLevel string `json:"log_level,omitempty"`
+
+	// Features enables experimental subsystems; see featureNames.
+	Features map[string]bool `json:"features"`
 }
 
 // --- Mode Definitions ---
 
-type EspressoMode struct {
-	Name     string
-	Duration time.Duration // 0 for infinite
-	Desc     string
-}
+// Session sources, shown in the menu as "Started from ...".
+const (
+	sourceTray = "the tray menu"
+	sourceLink = "a link"
+	sourceCLI  = "the command line"
 
-var modes = []EspressoMode{
-	{"Milk", 3 * time.Minute, "No caffeine, just for testing purposes."},
-	{"Drop", 10 * time.Minute, "Just a drop, almost no caffeine."},
-	{"Latte", 30 * time.Minute, "Gentle boost to get you started."},
-	{"Cappuccino", 1 * time.Hour, "Noticeable caffeine, perfectly balanced."},
-	{"Americano", 3 * time.Hour, "Stronger, long-lasting alertness."},
-	{"Espresso", 6 * time.Hour, "Concentrated, powerful kick."},
-	{"Lungo", 8 * time.Hour, "Super concentrated, extended energy."},
-	{"Doppio", 12 * time.Hour, "Double espresso, full-on focus all day."},
-	{"Pure Caffeine", -1, "Maximum alertness, use with caution."}, // -1 for infinite
-}
+	sourceAPIPrefix = "API token " // followed by the token ID
+)
+
+// modes are the presets shown in the tray menu.
+var modes = builtinModes
 
-// --- Sleep Control ---
+// ModeConfig is a user-defined mode in settings.json.
+type ModeConfig struct {
+	Name        string `json:"name"`
+	Duration    string `json:"duration"` // e.g. "45m", "2h30m" or "infinite"
+	Description string `json:"description,omitempty"`
 
-func allowSleep() {
-	procSetThreadExecutionState.Call(uintptr(ES_CONTINUOUS))
+	// Keep overrides the Keep Awake menu setting for this mode, as in
+	// TriggerConfig.
+	Keep  []string `json:"keep,omitempty"`
+	OnEnd string   `json:"on_end,omitempty"`
+	Note  string   `json:"note,omitempty"`
+
+	// Milestones override Config.Milestones for this mode; ["none"] turns
+	// them off.
+	Milestones []string `json:"milestones,omitempty"`
+
+	// Simulate overrides Config.Simulate for this mode.
+	Simulate string `json:"simulate,omitempty"`
+
+	// AwayPrevention keeps chat apps from showing the user as away.
+	AwayPrevention bool `json:"away_prevention,omitempty"`
 }
 
-func preventSleep() {
-	// Prevent system sleep and display sleep
-	procSetThreadExecutionState.Call(uintptr(ES_CONTINUOUS | ES_SYSTEM_REQUIRED | ES_DISPLAY_REQUIRED))
+// mergeModes returns the menu presets: the built-in ones, unless replace is
+// set, followed by the user's. A user mode with the name of a built-in one
+// takes its place.
+func mergeModes(user []ModeConfig, replace bool) []EspressoMode {
+	var merged []EspressoMode
+	if !replace {
+		merged = append(merged, builtinModes...)
+	}
+
+	for _, mc := range user {
+		if mc.Name == "" {
+			logWarnf("skipping mode without a name")
+			continue
+		}
+		d := time.Duration(-1)
+		var err error
+		if !strings.EqualFold(mc.Duration, "infinite") {
+			if d, err = parseSessionDuration(mc.Duration); err != nil {
+				logWarnf("skipping mode %s: %v", mc.Name, err)
+				continue
+			}
+		}
+		m := EspressoMode{Name: mc.Name, Duration: d, Desc: mc.Description, Note: mc.Note, Milestones: mc.Milestones}
+		if _, err := parseMilestones(mc.Milestones, time.Hour); err != nil {
+			logWarnf("skipping mode %s: %v", mc.Name, err)
+			continue
+		}
+		if m.OnEnd, err = parseEndAction(mc.OnEnd); err != nil {
+			logWarnf("skipping mode %s: %v", mc.Name, err)
+			continue
+		}
+		if m.OnEnd == "" && mc.OnEnd != "" {
+			m.OnEnd = "none" // overrides Config.EndAction
+		}
+		if _, err = parseSimulate(mc.Simulate); err != nil {
+			logWarnf("skipping mode %s: %v", mc.Name, err)
+			continue
+		}
+		// "none" is kept so that it overrides Config.Simulate.
+		m.Simulate = mc.Simulate
+		m.AwayPrevention = mc.AwayPrevention
+		if len(mc.Keep) > 0 {
+			flags, err := parseKeep(mc.Keep)
+			if err != nil {
+				logWarnf("skipping mode %s: %v", mc.Name, err)
+				continue
+			}
+			m.Flags = flags
+		}
+
+		i := slices.IndexFunc(merged, func(e EspressoMode) bool { return strings.EqualFold(e.Name, mc.Name) })
+		if i >= 0 {
+			merged[i] = m
+		} else {
+			merged = append(merged, m)
+		}
+	}
+
+	if len(merged) == 0 {
+		logWarnf("no valid modes configured; using the built-in ones")
+		return builtinModes
+	}
+	return merged
 }
 
 // --- File System & Config ---
@@ -125,8 +344,8 @@ func settingsPath() string {
 		}
 	}
 	dir := filepath.Join(appdata, "Espresso")
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("Warning: could not create config dir %s: %v\n", dir, err)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logWarnf("could not create config dir %s: %v", dir, err)
 	}
 	return filepath.Join(dir, "settings.json")
 }
@@ -157,7 +376,7 @@ func ensureResourceFiles() {
 	}
 
 	if writeRequired {
-		_ = os.WriteFile(p, licenseData, 0644)
+		_ = writeFileAtomic(p, licenseData, 0644)
 	}
 
 	// 2. Ensure Icon File (Required on disk for Toast notifications)
@@ -170,7 +389,7 @@ func ensureResourceFiles() {
 	}
 
 	if writeRequired {
-		_ = os.WriteFile(p, iconData, 0644)
+		_ = writeFileAtomic(p, iconData, 0644)
 	}
 
 	p = icoffPath()
@@ -182,7 +401,7 @@ func ensureResourceFiles() {
 	}
 
 	if writeRequired {
-		_ = os.WriteFile(p, icoffData, 0644)
+		_ = writeFileAtomic(p, icoffData, 0644)
 	}
 
 	// 3. Ensure Third Party Licenses
@@ -202,7 +421,7 @@ func ensureResourceFiles() {
 		diskContent, diskErr := os.ReadFile(destPath)
 
 		if os.IsNotExist(diskErr) || !bytes.Equal(diskContent, embeddedContent) {
-			_ = os.WriteFile(destPath, embeddedContent, 0644)
+			_ = writeFileAtomic(destPath, embeddedContent, 0644)
 		}
 		return nil
 	})
@@ -211,41 +430,87 @@ func ensureResourceFiles() {
 func loadConfig() Config {
 	defaultCfg := Config{
 		Language: defaultLanguage,
+		Features: Config{}.defaultFeatures(false),
 	}
 
 	p := settingsPath()
+	// Refuse before hardening the folder, which would hide the problem.
+	if err := checkConfigWriters(p); err != nil {
+		logWarnf("%v, so it was not loaded and the defaults are used", err)
+		go showMessage("Espresso", tr("config.untrusted", err))
+		return defaultCfg
+	}
+	hardenConfigDir(filepath.Dir(p))
+
 	data, err := os.ReadFile(p)
 	if err != nil {
 		_ = saveConfig(defaultCfg)
 		return defaultCfg
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	cfg, needsSave, err := parseConfig(data)
+	if err != nil {
 		_ = saveConfig(defaultCfg)
 		return defaultCfg
 	}
+	if needsSave {
+		saveConfig(cfg)
+	}
+
+	modes = mergeModes(cfg.Modes, cfg.ReplaceBuiltinModes)
+	return cfg
+}
+
+// checkConfigWriters fails if, in managed mode, settings.json at p can be
+// modified by someone other than the user and administrators.
+func checkConfigWriters(p string) error {
+	if !managedMode() {
+		return nil
+	}
+	who, err := untrustedWriter(p)
+	if err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		who = fmt.Sprintf("unknown (%v)", err)
+	}
+	if who != "" {
+		return fmt.Errorf("%s can be modified by %s", p, who)
+	}
+	return nil
+}
 
-	needsSave := false
+// parseConfig decodes settings.json and fills in missing defaults, which
+// needsSave reports.
+func parseConfig(data []byte) (cfg Config, needsSave bool, err error) {
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, false, err
+	}
 
 	if cfg.Language == "" {
 		cfg.Language = defaultLanguage
 		needsSave = true
 	}
 
-	if needsSave {
-		saveConfig(cfg)
+	if cfg.Features == nil {
+		cfg.Features = cfg.defaultFeatures(true)
+		needsSave = true
 	}
+	return cfg, needsSave, nil
+}
 
-	return cfg
+// configData is settings.json as saveConfig writes it.
+func configData(cfg Config) ([]byte, error) {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
 }
 
 func saveConfig(cfg Config) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	data, err := configData(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
-	return os.WriteFile(settingsPath(), data, 0644)
+	return writeFileAtomic(settingsPath(), data, 0600)
 }
 
 // --- UI Helpers ---
@@ -260,22 +525,54 @@ func showMessage(title, message string) {
 		uintptr(MB_ICONINFORMATION))
 }
 
-func showToast(title, message string, iconPath string) error {
-	notification := toast.Notification{
-		AppID:   "Espresso",
+// confirm asks a yes/no question and reports whether the user said yes.
+func confirm(title, message string) bool {
+	t, _ := windows.UTF16PtrFromString(title)
+	m, _ := windows.UTF16PtrFromString(message)
+
+	ret, _, _ := procMessageBoxW.Call(0,
+		uintptr(unsafe.Pointer(m)),
+		uintptr(unsafe.Pointer(t)),
+		uintptr(MB_YESNO|MB_ICONQUESTION))
+	return ret == IDYES
+}
+
+func showToast(tag, title, message string, iconPath string) error {
+	return showToastActions(tag, title, message, iconPath, []toastAction{
+		{Label: "OK", Arguments: ""},
+	})
+}
+
+// showToastActions is showToast with custom buttons. The fallbacks cannot
+// show buttons.
+func showToastActions(tag, title, message string, iconPath string, actions []toastAction) error {
+	if !useToasts.Load() {
+		// See setNotificationStyle.
+		reportEvent(title, message)
+		if err := showBalloon(title, message); err != nil {
+			logErrorf("could not show balloon notification: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	notification := toastNotification{
 		Title:   title,
 		Message: message,
 		Icon:    iconPath,
-		// Audio:    toast.IM,
-		Duration: toast.Short,
-		Actions: []toast.Action{
-			{Type: "protocol", Label: "OK", Arguments: ""},
-		},
+		Tag:     tag,
+		Actions: actions,
 	}
 
-	err := notification.Push()
+	err := notification.push()
 	if err != nil {
-		fmt.Printf("Error showing toast notification: %v\n", err)
+		// Fall back to a balloon tip, then to a self-closing message box,
+		// so state changes are never silent.
+		logErrorf("could not show toast notification: %v", err)
+		if berr := showBalloon(title, message); berr != nil {
+			logErrorf("could not show balloon notification: %v", berr)
+			go showTransientMessage(title, message)
+		}
 	}
 	return err
 }
@@ -284,18 +581,21 @@ func showAbout() {
 	mainLicensePath := licenseFilePath()
 	thirdPartyLicensesDir := filepath.Join(filepath.Dir(settingsPath()), "THIRD_PARTY_LICENSES")
 
+	// Other languages show an unofficial translation of the license
+	// notice, as the FSF allows, saying that only the English license
+	// applies; LICENSE.txt always holds the English text.
 	aboutMessage := fmt.Sprintf(
-		"Espresso - A lightweight utility to keep your screen on and your system active.\n\n"+
+		"%s\n\n"+
 			"Copyright (C) 2025  Rodrigo Toraño Valle\n\n"+
-			"This program is free software: you can redistribute it and/or modify it under the terms of the GNU General Public License as published by the Free Software Foundation, either version 3 of the License, or (at your option) any later version.\n\n"+
-			"This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for more details.\n\n"+
-			"You should have received a copy of the GNU General Public License along with this program.  If not, see <https://www.gnu.org/licenses/>.\n\n"+
-			"You can find the full GPLv3 license text in:\n%s\n\n"+
-			"Required notices for third-party components (Apache-2.0, BSD-3-Clause) are located in the following folder:\n%s\n\n\n\n",
-		mainLicensePath,
-		thirdPartyLicensesDir,
+			"%s\n\n"+
+			"%s\n\n"+
+			"%s\n\n\n\n",
+		tr("about.tagline"),
+		tr("about.notice"),
+		tr("about.license", mainLicensePath),
+		tr("about.third_party", thirdPartyLicensesDir),
 	)
-	showMessage("About Espresso", aboutMessage)
+	showMessage(tr("about.title"), aboutMessage)
 	// go func() {
 	// 	_ = exec.Command("notepad", mainLicensePath).Start()
 	// }()
@@ -331,140 +631,419 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh %dm %ds", h, m, s)
 }
 
-func formatFriendlyDuration(d time.Duration) string {
-	if d < 0 {
-		return "Infinity"
+// parseSessionDuration parses a custom session length as typed by the user:
+// a Go duration such as "2h15m" or "90m", "h:mm" such as "2:15", or a bare
+// number of minutes.
+func parseSessionDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	var d time.Duration
+	if n, err := strconv.Atoi(s); err == nil {
+		d = time.Duration(n) * time.Minute
+	} else if h, m, ok := strings.Cut(s, ":"); ok {
+		hours, err1 := strconv.Atoi(h)
+		mins, err2 := strconv.Atoi(m)
+		if err1 != nil || err2 != nil || mins < 0 || mins > 59 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(hours)*time.Hour + time.Duration(mins)*time.Minute
+	} else if d, err = time.ParseDuration(s); err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
 	}
-	if d >= time.Hour && d%time.Hour == 0 {
-		return fmt.Sprintf("%dh", int(d.Hours()))
+	if d < time.Minute {
+		return 0, fmt.Errorf("duration must be at least one minute")
 	}
-	return fmt.Sprintf("%dm", int(d.Minutes()))
+	return d, nil
 }
 
-// execOnMainThread ensures the Windows API call happens on the locked OS thread
-func execOnMainThread(fn func()) {
-	done := make(chan struct{})
-	execStateCh <- func() {
-		fn()
-		close(done)
+func main() {
+	espresso.Logf = logDebugf
+	if len(os.Args) > 1 && os.Args[1] == "ci" {
+		os.Exit(runCI(os.Args[2:]))
 	}
-	<-done
-}
-
-func startExecThread() {
-	go func() {
-		runtime.LockOSThread()
-		defer runtime.UnlockOSThread()
-		for fn := range execStateCh {
-			if fn != nil {
-				fn()
-			}
+	if len(os.Args) > 1 && os.Args[1] == "endguard" {
+		os.Exit(runEndGuard(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "--service" {
+		os.Exit(runServiceCommand(os.Args[2:]))
+	}
+	cmd, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		attachConsole()
+		var help errHelp
+		if errors.As(err, &help) {
+			fmt.Print(help)
+			return
 		}
-	}()
-}
+		fmt.Fprintln(os.Stderr, "espresso:", err)
+		os.Exit(2)
+	}
 
-func main() {
-	startExecThread()
+	sleepThread = espresso.NewThread()
 	if !enforceSingleInstance() {
+		// Hand links and commands to the instance that is already running.
+		if len(os.Args) > 1 {
+			os.Exit(runRemote(os.Args[1:]))
+		}
 		return
 	}
+	if cmd.stop || cmd.status {
+		attachConsole()
+		os.Exit(printReply(ipcReply{Output: "Espresso is not running."}))
+	}
+	if cmd.dryRun {
+		os.Exit(runDryRun(cmd))
+	}
 	// Ensure we start allowing sleep
-	execOnMainThread(func() { allowSleep() })
+	sleepThread.Do(func() { allowSleep() })
+	prepareTray()
 	systray.Run(onReady, onExit)
 }
 
+// app holds the tray state. Its fields are only touched from the main loop
+// goroutine started in onReady.
+type app struct {
+	cfg Config
+
+	// mMode and mSource form the status block at the top of the menu.
+	mMode   *systray.MenuItem
+	mSource *systray.MenuItem
+	mPause  *systray.MenuItem // "Pause" or "Resume"; hidden without a session
+	mExtend *systray.MenuItem // hidden without a timed session
+
+	// mCancelEnd cancels pendingEnd and is hidden without one.
+	mCancelEnd *systray.MenuItem
+
+	overlay   *overlay
+	tokenMenu *tokenMenu
+	modeMenu  *modeMenu
+	simulator activitySimulator
+	lockGuard displayRequest
+
+	shutdownBlock *shutdownBlocker // see syncShutdownBlock
+	titleWindow   *titleWindow     // see updateTitle
+	lidSaved      *lidPolicy       // lid close action replaced, see syncLidPolicy
+
+	dimmer  *displayDimmer // started on first use
+	dimming bool
+
+	displayPower *displayPower // started on first use
+	displaysOff  bool
+
+	iconText string  // countdown shown on the tray icon, see setActiveIcon
+	cupIcon  []byte  // active icon, see loadCupIcon
+	accent   [3]byte // color cupIcon was tinted with
+
+	quickFlags uint32 // held by the quick toggles, see setQuickToggle
+
+	presentation triggerEvent // latest presentation state, see handlePresentationEvent
+
+	inhibitFailed bool      // Windows refused the last keep-awake request
+	lastAssert    time.Time // of the keep-awake request, see reassertKeepAwake
+	heldLost      uint32    // requested flags Windows no longer reports, see checkHeld
+
+	nightLightHeld bool // the session wants Night Light, see updateNightLight
+	nightLightSet  bool // Espresso turned Night Light on
+
+	indicator *indicator
+	discord   *discordPresence // nil unless enabled
+
+	// session is the manual session, nil if there is none. Its mode holds
+	// the name, length and flags; its clock the time left, paused or not.
+	session         *espresso.Session
+	sessionUntil    bool // the session ends at a wall-clock time, see untilMode
+	sessionSource   string
+	sessionNote     string
+	sessionOnEnd    string
+	sessionSimulate string          // activity simulation method, "" for none
+	releaseNonce    string          // authorizes the "Release Now" button, see announceRemote
+	actionNonce     string          // authorizes session notification buttons, see sessionLink
+	expiryWarned    bool            // the expiry warning was shown
+	milestones      []time.Duration // time left at each pending milestone
+	lastReminder    time.Time
+	sessionWatch    []string // processes watched by the session, see checkWatch
+	sessionBack     bool     // waiting for the user to return, see checkBack
+	backAway        bool     // the user has left since the session started
+	watchChecked    time.Time
+	lastCustom      string // last custom duration entered, to prefill the prompt
+	lastUntil       string // last Until time entered, likewise
+
+	// pendingEnd is the end action of an expired session during its
+	// cancel window.
+	pendingEnd *pendingEndAction
+	endGuard   endGuard // runs a sleep or hibernate end action if Espresso dies
+
+	// activeTriggers holds every trigger whose condition currently holds,
+	// keyed by trigger name.
+	activeTriggers map[string]*activeTrigger
+	// triggerToasts is when each trigger last notified that it started
+	// and that it released; see triggerToastQuiet.
+	triggerToasts map[triggerToast]time.Time
+
+	// The triggers of runningTriggers send on triggerCh until triggerStop
+	// is closed; see restartTriggers.
+	triggerCh       <-chan triggerEvent
+	triggerStop     chan struct{}
+	runningTriggers triggerSetup
+
+	schedules       []schedule
+	scheduleStarted []time.Time // start of the last window acted on, per schedule
+
+	lastTick  time.Time     // wall clock, see checkSuspend
+	lastAwake time.Duration // awakeTime at lastTick
+
+	// profile is the profile in effect, "" for none; see selectProfile.
+	profile      string
+	profileMenu  *profileMenu
+	network      networkState
+	networkKnown bool
+	networkCh    <-chan networkState // nil until Automatic is picked
+
+	lastPower       powerState // for battery auto-stop
+	lastBudgetCheck time.Time
+
+	// pendingResume is the session from session.json while the user is
+	// asked whether to resume it.
+	pendingResume *savedSession
+}
+
 func onReady() {
+	close(trayReady)
+	go keepTrayIcon()
 	ensureResourceFiles()
 	systray.SetIcon(icoffData)
 	systray.SetTitle("Espresso")
-	systray.SetTooltip("Espresso: Decaf (Sleep allowed)")
-
+	_, err := os.Stat(settingsPath())
+	upgraded := err == nil // settings.json predates this run
 	cfg := loadConfig()
-	fmt.Printf("Loaded config: %+v\n", cfg)
+	setLogLevel(cfg.LogLevel)
+	setNotificationStyle(cfg.Notifications)
+	logArchitecture()
+	logInfof("Espresso %s started; settings from %s", appVersion(), settingsPath())
+	setLanguage(cfg.Language)
+	systray.SetTooltip(tr("tooltip.decaf"))
+	cfg.warnDisabledFeatures()
+
+	a := &app{
+		cfg:            cfg,
+		activeTriggers: make(map[string]*activeTrigger),
+		triggerToasts:  make(map[triggerToast]time.Time),
+		overlay:        startOverlay(),
+		shutdownBlock:  startShutdownBlocker(),
+		titleWindow:    startTitleWindow(),
+		indicator:      startIndicator(cfg.Indicator),
+		discord:        startDiscordPresence(cfg.Discord),
+	}
+	a.loadCupIcon()
+	startAccentWatcher()
+	startPowerWatcher()
+	startDeviceWatcher()
 
 	// --- Menu Items ---
-	mInfo := systray.AddMenuItem("About Espresso", "Show info")
+	mInfo := addMenuItem(nil, "menu.about")
 	systray.AddSeparator()
 
-	mMode := systray.AddMenuItem("Mode: Decaf", "Current mode")
-	mMode.Disable()
+	a.mMode = systray.AddMenuItem("Mode: Decaf", "Current mode")
+	a.mMode.Disable()
+
+	a.mSource = systray.AddMenuItem("", "What started the current mode")
+	a.mSource.Disable()
+	a.mSource.Hide()
 
-	mTimeLeft := systray.AddMenuItem("", "")
-	mTimeLeft.Hide()
+	mStatus := addMenuItem(nil, "menu.status")
+	mHistory := addMenuItem(nil, "menu.history")
+	mStats := addMenuItem(nil, "menu.stats")
+	energyCh := make(chan EnergyConfig)
+	budgetCh := make(chan budgetUsage)
 
 	systray.AddSeparator()
 
 	// --- Dynamic Menu Creation ---
-	controlCh := make(chan EspressoMode)
+	a.modeMenu = newModeMenu(modes)
+
+	mCustom := addMenuItem(nil, "menu.custom")
+	customCh := make(chan time.Duration)
+	mWatch := addMenuItem(nil, "menu.watch")
+	watchCh := make(chan []string)
+	mBack := addMenuItem(nil, "menu.back")
+
+	mUntil := addMenuItem(nil, "menu.until")
+	untilCh := make(chan time.Time)
+	mUntilHour := addMenuItem(mUntil, "menu.until.next_hour")
+	for _, hour := range untilHours {
+		item := addMenuItem(mUntil, "menu.until.at", fmt.Sprintf("%d:00", hour))
+		go func() {
+			for range item.ClickedCh {
+				untilCh <- nextClockTime(hour, 0, time.Now())
+			}
+		}()
+	}
+	mUntilCustom := addMenuItem(mUntil, "menu.until.custom")
+
+	quickCh := make(chan uint32)
+	quickItems := addQuickToggles(quickCh)
+	mPresentation := addMenuCheckbox(nil, "menu.presentation", cfg.PresentationAutoStart)
+	mBlockRestarts := addMenuCheckbox(nil, "menu.block_restarts", cfg.BlockRestarts)
+	mKeep := addMenuItem(nil, "menu.keep")
+	keepCh := make(chan uint32)
+	keepItems := make(map[uint32]*systray.MenuItem)
+	for _, opt := range keepOptions {
+		item := addMenuCheckbox(mKeep, "menu.keep."+opt.key, opt.flags == a.defaultSessionFlags())
+		keepItems[opt.flags] = item
+		go func() {
+			for range item.ClickedCh {
+				keepCh <- opt.flags
+			}
+		}()
+	}
 
-	for _, mode := range modes {
-		label := fmt.Sprintf("%s (%s)", mode.Name, formatFriendlyDuration(mode.Duration))
-		item := systray.AddMenuItem(label, mode.Desc)
+	mOnEnd := addMenuItem(nil, "menu.on_end")
+	onEndCh := make(chan string)
+	onEndItems := make(map[string]*systray.MenuItem)
+	defaultOnEnd, err := parseEndAction(cfg.EndAction)
+	if err != nil {
+		logWarnf("%v", err)
+	}
+	for _, action := range append([]string{""}, endActions...) {
+		key := action
+		if key == "" {
+			key = "none"
+		}
+		item := addMenuCheckbox(mOnEnd, "menu.on_end."+key, action == defaultOnEnd)
+		onEndItems[action] = item
 		go func() {
 			for range item.ClickedCh {
-				controlCh <- mode
+				onEndCh <- action
 			}
 		}()
 	}
 
 	systray.AddSeparator()
-	mStop := systray.AddMenuItem("Decaf (Stop)", "Allow computer to sleep")
+	a.mExtend = addMenuItem(nil, "menu.extend")
+	extendCh := make(chan time.Duration)
+	for _, d := range []time.Duration{15 * time.Minute, 30 * time.Minute, time.Hour} {
+		item := addMenuItem(a.mExtend, "menu.extend.add", formatFriendlyDuration(d))
+		go func() {
+			for range item.ClickedCh {
+				extendCh <- d
+			}
+		}()
+	}
+	a.mExtend.Hide()
+	a.mPause = systray.AddMenuItem(tr("menu.pause"), tr("menu.pause.tip"))
+	a.mPause.Hide()
+	mStop := addMenuItem(nil, "menu.stop")
+	a.mCancelEnd = systray.AddMenuItem("", tr("menu.on_end.cancel.tip"))
+	a.mCancelEnd.Hide()
 	systray.AddSeparator()
-	mQuit := systray.AddMenuItem("Quit", "Exit Espresso")
-
-	// --- State Variables ---
-	var (
-		isActive        bool
-		isInfinite      bool
-		sessionEndTime  time.Time
-		currentModeName string
-	)
 
-	resetState := func() {
-		isActive = false
-		isInfinite = false
-		currentModeName = "Decaf"
-
-		// System Call: Allow Sleep
-		execOnMainThread(func() { allowSleep() })
-
-		// Update UI
-		systray.SetIcon(icoffData)
-		mMode.SetTitle("Mode: Decaf")
-		systray.SetTooltip("Espresso: Decaf (Sleep allowed)")
-		mTimeLeft.Hide()
+	mOverlay := addMenuItem(nil, "menu.overlay")
+	mOverlayShow := addMenuCheckbox(mOverlay, "menu.overlay.show", cfg.Overlay.Enabled)
+	overlayThemeCh := make(chan string)
+	themeItems := make(map[string]*systray.MenuItem)
+	for _, theme := range overlayThemes {
+		item := addMenuCheckbox(mOverlay, "menu.overlay."+theme.key, theme.key == findOverlayTheme(cfg.Overlay.Theme).key)
+		themeItems[theme.key] = item
+		go func() {
+			for range item.ClickedCh {
+				overlayThemeCh <- theme.key
+			}
+		}()
 	}
-
-	startSession := func(d time.Duration) {
-		isActive = true
-
-		// Determine name based on duration
-		foundName := "Custom"
-		for _, m := range modes {
-			if m.Duration == d {
-				foundName = m.Name
-				break
+	mOverlayFlash := addMenuCheckbox(mOverlay, "menu.overlay.flash", cfg.Overlay.FlashOnExpiry)
+	mTrayCountdown := addMenuCheckbox(mOverlay, "menu.overlay.tray_countdown", cfg.TrayCountdown)
+	mAccentIcon := addMenuCheckbox(mOverlay, "menu.overlay.accent_icon", cfg.AccentIcon)
+
+	a.tokenMenu = newTokenMenu()
+	a.tokenMenu.update(cfg.APITokens)
+	a.profileMenu = newProfileMenu()
+	mSettings := addMenuItem(nil, "menu.settings")
+	settingsCh := make(chan settingsEdit)
+	mAutostart := addMenuCheckbox(nil, "menu.autostart", cfg.StartWithWindows)
+	mLanguage := addMenuItem(nil, "menu.language")
+	languageCh := make(chan string)
+	languageItems := make(map[string]*systray.MenuItem)
+	for _, tag := range languages {
+		// Languages are listed under their own names, whatever the
+		// current language.
+		c, _ := loadCatalog(tag)
+		item := mLanguage.AddSubMenuItemCheckbox(c["language.name"], tag, tag == cfg.Language)
+		languageItems[tag] = item
+		go func() {
+			for range item.ClickedCh {
+				languageCh <- tag
 			}
-		}
-		currentModeName = foundName
-
-		// System Call: Prevent Sleep
-		execOnMainThread(func() { preventSleep() })
+		}()
+	}
+	go syncAutostart(cfg.StartWithWindows)
+	mDiagnostics := addMenuItem(nil, "menu.diagnostics")
+
+	mAdvanced := addMenuItem(nil, "menu.advanced")
+	mTestExpiry := addMenuItem(mAdvanced, "menu.advanced.expiry")
+	mTestToast := addMenuItem(mAdvanced, "menu.advanced.toast")
+	mReassert := addMenuItem(mAdvanced, "menu.advanced.reassert")
+	mReload := addMenuItem(mAdvanced, "menu.advanced.reload")
+	mPreview := addMenuItem(mAdvanced, "menu.advanced.preview")
+	mIgnoreLid := addMenuCheckbox(mAdvanced, "menu.advanced.ignore_lid", cfg.IgnoreLid)
+	expireCh := make(chan *espresso.Session)
+	guestCh := make(chan time.Duration)
+	mQuit := addMenuItem(nil, "menu.quit")
+
+	a.askAccess()
+	hotkeyCh := startHotkey(cfg.Hotkey)
+	presentationCh := startPresentationWatch()
+	a.selectProfile(false)
+	quitCh := make(chan struct{})
+	apiCh := make(chan apiCall)
+	if cfg.feature(featureAPI) {
+		startAPI(a.cfg.API, apiCh)
+	}
 
-		systray.SetIcon(iconData)
+	ipcCh := startIPC()
+	var pipeCh <-chan pipeCall
+	if cfg.feature(featurePipe) {
+		pipeCh = startPipe()
+	}
+	linkCh := make(chan EspressoMode)
+	go func() {
+		if err := registerURLScheme(); err != nil {
+			logWarnf("could not register %s:// links: %v", urlScheme, err)
+		}
+	}()
+	a.announceUpdate(upgraded)
+	restoreSavedLid()
+	resumeCh := make(chan bool)
+	if a.pendingResume = loadSavedSession(); a.pendingResume != nil {
+		go offerResume(*a.pendingResume, resumeCh)
+	}
+	if rep := a.handleArgs(os.Args[1:], linkCh); rep.Error != "" {
+		logWarnf("%s", rep.Error)
+	}
 
-		if d < 0 {
-			isInfinite = true
-			mMode.SetTitle(fmt.Sprintf("Mode: %s (Infinite)", foundName))
-			mTimeLeft.Hide()
-			systray.SetTooltip("Espresso: Caffeine High (No Sleep)")
-		} else {
-			isInfinite = false
-			sessionEndTime = time.Now().Add(d)
-			mMode.SetTitle(fmt.Sprintf("Mode: %s (%s)", foundName, formatFriendlyDuration(d)))
-			mTimeLeft.Show()
+	// syncMenuChecks updates the check marks after a settings reload.
+	syncMenuChecks := func() {
+		for f, item := range keepItems {
+			setChecked(item, f == a.defaultSessionFlags())
+		}
+		onEnd, _ := parseEndAction(a.cfg.EndAction)
+		for act, item := range onEndItems {
+			setChecked(item, act == onEnd)
+		}
+		setChecked(mOverlayShow, a.cfg.Overlay.Enabled)
+		for key, item := range themeItems {
+			setChecked(item, key == findOverlayTheme(a.cfg.Overlay.Theme).key)
+		}
+		setChecked(mOverlayFlash, a.cfg.Overlay.FlashOnExpiry)
+		setChecked(mTrayCountdown, a.cfg.TrayCountdown)
+		setChecked(mAccentIcon, a.cfg.AccentIcon)
+		setChecked(mPresentation, a.cfg.PresentationAutoStart)
+		setChecked(mBlockRestarts, a.cfg.BlockRestarts)
+		setChecked(mIgnoreLid, a.cfg.IgnoreLid)
+		setChecked(mAutostart, a.cfg.StartWithWindows)
+		for tag, item := range languageItems {
+			setChecked(item, tag == a.cfg.Language)
 		}
 	}
+	go watchSettings()
 
 	// --- Main Loop ---
 	go func() {
@@ -476,59 +1055,919 @@ func onReady() {
 			case <-mInfo.ClickedCh:
 				go showAbout()
 
+			case <-mStatus.ClickedCh:
+				go showMessage(tr("menu.status"), a.statusReport())
+
+			case <-mHistory.ClickedCh:
+				go showMessage(tr("menu.history"), sessionHistory())
+
+			case <-mStats.ClickedCh:
+				go showStats(a.cfg.Energy, energyCh)
+
+			case e := <-energyCh:
+				// Only the wattages and price are edited in the window.
+				a.cfg.Energy.SystemWatts, a.cfg.Energy.DisplayWatts, a.cfg.Energy.PricePerKWh = e.SystemWatts, e.DisplayWatts, e.PricePerKWh
+				a.saveConfig()
+
+			case u := <-budgetCh:
+				a.handleBudgetUsage(u)
+
+			case ok := <-resumeCh:
+				if ok && a.pendingResume != nil {
+					a.resumeSaved(*a.pendingResume)
+				}
+				a.pendingResume = nil
+
 			case <-mQuit.ClickedCh:
+				if !a.active() && a.triggerFlags() == 0 && a.quickFlags == 0 {
+					a.logSessionEnd("quit")
+					a.releaseDevices()
+					systray.Quit()
+					return
+				}
+				// Quitting lets the system sleep, so confirm first.
+				msg := a.quitConfirmation()
+				go func() {
+					if confirm(tr("quit.title"), msg) {
+						quitCh <- struct{}{}
+					}
+				}()
+
+			case <-quitCh:
+				// Quitting ends the session on purpose, so it is not
+				// offered again at the next start.
+				a.logSessionEnd("quit")
+				a.session = nil
+				a.saveSession()
+				a.releaseDevices()
 				systray.Quit()
 				return
 
+			case d := <-extendCh:
+				a.extend(d)
+
+			case <-a.mPause.ClickedCh:
+				if a.paused() {
+					a.resume()
+				} else {
+					a.pause()
+				}
+
+			case <-a.mCancelEnd.ClickedCh:
+				a.cancelEndAction()
+
 			case <-mStop.ClickedCh:
-				resetState()
-				showToast("Espresso Stopped", "System is now allowed to sleep.", icoffPath())
+				a.resetState("stopped")
+				showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
 
-			case m := <-controlCh:
+			case i := <-a.modeMenu.clickCh:
+				if i >= len(modes) {
+					continue
+				}
+				m := modes[i]
 				d := m.Duration
-				startSession(d)
+				a.startSession(m, sourceTray)
+				a.cfg.LastMode = m.Name
+				a.saveConfig()
 				var durationText string
 				if d < 0 {
-					durationText = "Preventing sleep indefinitely."
+					durationText = tr("toast.indefinite")
 				} else {
-					durationText = fmt.Sprintf("%s\nPreventing sleep for %s", m.Desc, formatFriendlyDuration(d))
+					durationText = m.Desc + "\n" + tr("toast.for", formatFriendlyDuration(d))
+				}
+				showToast(tagSession, tr("toast.started", m.Name), durationText, iconPath())
+
+			case <-mWatch.ClickedCh:
+				go askWatchProcesses(a.cfg.WatchProcesses, watchCh)
+
+			case names := <-watchCh:
+				a.cfg.WatchProcesses = names
+				a.saveConfig()
+				a.startSession(watchMode(names), sourceTray)
+				go showToast(tagSession, tr("toast.started", a.modeName()), tr("watch.started"), iconPath())
+
+			case <-mBack.ClickedCh:
+				a.startSession(backMode(), sourceTray)
+				go showToast(tagSession, tr("toast.started", a.modeName()),
+					tr("back.started", formatFriendlyDuration(a.cfg.backSettle())), iconPath())
+
+			case <-mUntilHour.ClickedCh:
+				now := time.Now()
+				a.startUntil(nextClockTime(now.Hour()+1, 0, now))
+
+			case <-mUntilCustom.ClickedCh:
+				go askUntilTime(a.lastUntil, untilCh)
+
+			case t := <-untilCh:
+				a.lastUntil = t.Format("15:04")
+				a.startUntil(t)
+
+			case <-mCustom.ClickedCh:
+				go askCustomDuration(a.lastCustom, customCh)
+
+			case d := <-customCh:
+				a.lastCustom = formatFriendlyDuration(d)
+				a.startSession(EspressoMode{Duration: d}, sourceTray)
+				showToast(tagSession, tr("toast.started", a.modeName()),
+					tr("toast.for", formatFriendlyDuration(d)), iconPath())
+
+			case action := <-onEndCh:
+				a.cfg.EndAction = action
+				for act, item := range onEndItems {
+					setChecked(item, act == action)
+				}
+				a.saveConfig()
+
+			case flag := <-quickCh:
+				a.setQuickToggle(flag, a.quickFlags&flag == 0)
+				setChecked(quickItems[flag], a.quickFlags&flag != 0)
+
+			case flags := <-keepCh:
+				a.cfg.Keep = keepList(flags)
+				for f, item := range keepItems {
+					setChecked(item, f == flags)
+				}
+				a.saveConfig()
+				if a.session != nil {
+					a.session.Mode.Flags = espresso.Flags(flags)
+					a.applyState()
+				}
+
+			case <-a.tokenMenu.generate.ClickedCh:
+				a.generateAPIToken()
+
+			case act := <-a.tokenMenu.actionCh:
+				a.handleTokenAction(act)
+
+			case <-a.tokenMenu.guest.ClickedCh:
+				go askGuestLength(guestCh)
+
+			case d := <-guestCh:
+				link, reachable, err := a.createGuestLink(d)
+				if err != nil {
+					go showMessage(tr("guest.title"), tr("guest.failed", err))
+					continue
+				}
+				go revealGuestLink(link, reachable, d, a.cfg.GuestLinks.validHours())
+
+			case <-mDiagnostics.ClickedCh:
+				cfg, status := a.cfg, a.statusReport()
+				go func() {
+					p, err := exportDiagnostics(cfg, status)
+					if err != nil {
+						showMessage(tr("menu.diagnostics"), tr("diagnostics.failed", err))
+						return
+					}
+					revealInExplorer(p)
+				}()
+
+			case <-mOverlayShow.ClickedCh:
+				a.cfg.Overlay.Enabled = !a.cfg.Overlay.Enabled
+				setChecked(mOverlayShow, a.cfg.Overlay.Enabled)
+				a.saveConfig()
+				a.updateStatus()
+
+			case key := <-overlayThemeCh:
+				a.cfg.Overlay.Theme = key
+				for k, item := range themeItems {
+					setChecked(item, k == key)
+				}
+				a.saveConfig()
+				a.updateStatus()
+
+			case <-mAutostart.ClickedCh:
+				enabled := !a.cfg.StartWithWindows
+				if err := setAutostart(enabled); err != nil {
+					go showMessage(tr("menu.autostart"), tr("autostart.failed", err))
+					continue
+				}
+				a.cfg.StartWithWindows = enabled
+				setChecked(mAutostart, enabled)
+				a.saveConfig()
+
+			case tag := <-languageCh:
+				a.cfg.Language = tag
+				for t, item := range languageItems {
+					setChecked(item, t == tag)
+				}
+				a.saveConfig()
+				setLanguage(tag)
+				relabelMenu()
+				a.tokenMenu.update(a.cfg.APITokens)
+				a.profileMenu.update(a.cfg, a.profile)
+				a.applyState()
+
+			case <-mOverlayFlash.ClickedCh:
+				a.cfg.Overlay.FlashOnExpiry = !a.cfg.Overlay.FlashOnExpiry
+				setChecked(mOverlayFlash, a.cfg.Overlay.FlashOnExpiry)
+				a.saveConfig()
+
+			case <-mTrayCountdown.ClickedCh:
+				a.cfg.TrayCountdown = !a.cfg.TrayCountdown
+				setChecked(mTrayCountdown, a.cfg.TrayCountdown)
+				a.saveConfig()
+				a.applyState()
+
+			case <-mAccentIcon.ClickedCh:
+				a.cfg.AccentIcon = !a.cfg.AccentIcon
+				setChecked(mAccentIcon, a.cfg.AccentIcon)
+				a.saveConfig()
+				a.loadCupIcon()
+				a.applyState()
+
+			case <-mTestExpiry.ClickedCh:
+				if !a.active() {
+					go showMessage(tr("menu.advanced.expiry"), tr("expiry.simulate_none"))
+					continue
+				}
+				if a.sessionOnEnd == "" {
+					a.expire("simulated")
+					continue
+				}
+				// The end action really runs, so make sure. The session is
+				// sent back so that one started while asking is left alone.
+				msg := tr("expiry.simulate_confirm", a.modeName(), endActionName(a.sessionOnEnd))
+				session := a.session
+				go func() {
+					if confirm(tr("menu.advanced.expiry"), msg) {
+						expireCh <- session
+					}
+				}()
+
+			case session := <-expireCh:
+				if a.active() && a.session == session {
+					a.expire("simulated")
+				}
+
+			case <-mTestToast.ClickedCh:
+				go func() {
+					if err := showToast(tagSession, tr("toast.test_title"), tr("toast.test"), iconPath()); err != nil {
+						showMessage(tr("menu.advanced.toast"), tr("toast.test_failed", err))
+					}
+				}()
+
+			case <-mSettings.ClickedCh:
+				names := make([]string, len(modes))
+				for i, m := range modes {
+					names[i] = m.Name
+				}
+				go showSettings(a.settingsEdit(), names, settingsCh, a.tokenMenu.actionCh)
+
+			case e := <-settingsCh:
+				restart := a.applySettingsEdit(e)
+				syncMenuChecks()
+				if len(restart) > 0 {
+					go showToast(tagSession, tr("settings.title"), tr("reload.restart", strings.Join(restart, ", ")), iconPath())
+				}
+
+			case <-mPreview.ClickedCh:
+				go showMessage(tr("menu.advanced.preview"), a.previewSession())
+
+			case <-mIgnoreLid.ClickedCh:
+				a.cfg.IgnoreLid = !a.cfg.IgnoreLid
+				setChecked(mIgnoreLid, a.cfg.IgnoreLid)
+				a.saveConfig()
+				a.syncLidPolicy()
+
+			case <-mReload.ClickedCh:
+				if a.reloadConfig(true) {
+					syncMenuChecks()
 				}
-				showToast(fmt.Sprintf("%s Mode Started", m.Name), durationText, iconPath())
+
+			case <-settingsChangedCh:
+				if a.reloadConfig(false) {
+					syncMenuChecks()
+				}
+
+			case <-mReassert.ClickedCh:
+				a.applyState()
+				flags := a.triggerFlags()
+				if a.active() {
+					flags |= a.sessionFlags()
+				}
+				msg := tr("reassert.done", keepName(flags))
+				if a.inhibitFailed {
+					msg = tr("reassert.refused")
+				}
+				go showMessage(tr("menu.advanced.reassert"), msg)
+
+			case c := <-apiCh:
+				a.handleAPICall(c)
+
+			case c := <-ipcCh:
+				c.reply <- a.handleArgs(c.args, linkCh)
+
+			case c := <-pipeCh:
+				a.handlePipeCall(c)
+
+			case m := <-linkCh:
+				a.startSession(m, sourceLink)
+				showToast(tagSession, tr("toast.started", m.Name), a.startedMessage(), iconPath())
+
+			case <-hotkeyCh:
+				a.toggleFromHotkey()
+
+			case <-accentChangedCh:
+				if a.cfg.AccentIcon && a.accentChanged() {
+					a.loadCupIcon()
+					a.applyState()
+				}
+
+			case <-trayRestoredCh:
+				a.refreshTray()
+
+			case i := <-a.profileMenu.clickCh:
+				if name, ok := a.profileClicked(i); ok {
+					a.setProfile(name)
+				}
+
+			case n := <-a.networkCh:
+				a.handleNetwork(n)
+
+			case ev := <-presentationCh:
+				a.handlePresentationEvent(ev)
+
+			case <-mPresentation.ClickedCh:
+				a.setPresentationAutoStart(!a.cfg.PresentationAutoStart)
+				setChecked(mPresentation, a.cfg.PresentationAutoStart)
+
+			case <-mBlockRestarts.ClickedCh:
+				a.cfg.BlockRestarts = !a.cfg.BlockRestarts
+				setChecked(mBlockRestarts, a.cfg.BlockRestarts)
+				a.saveConfig()
+				a.syncShutdownBlock()
+
+			case ev := <-a.triggerCh:
+				a.handleTriggerEvent(ev)
+
+			case <-resumedCh:
+				a.checkSuspend()
 
 			case <-ticker.C:
-				if !isActive {
+				a.checkSuspend()
+				a.enforceTriggerCaps()
+				a.checkSchedules()
+				a.checkBattery()
+				a.checkEnergyBudget(budgetCh)
+				a.updateSoftLanding()
+				a.updateNightLight()
+				a.updateDisplayPower()
+				a.updatePresence()
+				a.checkWatch()
+				a.checkBack()
+				a.checkEndAction()
+				a.reassertKeepAwake()
+
+				if !a.active() || a.paused() {
 					continue
 				}
 
-				if isInfinite {
+				if a.infinite() {
+					a.remindInfinite()
+					a.capInfinite()
 					continue
 				}
 
-				remaining := time.Until(sessionEndTime)
+				remaining := a.timeLeft()
 
 				if remaining <= 0 {
 					// Time is up!
-					resetState()
-
-					// Notify User
-					go func() {
-						showToast("Espresso Finished", "System is now allowed to sleep.", icoffPath())
-					}()
+					a.expire("expired")
 				} else {
+					a.announceMilestones(remaining)
+					a.warnExpiry(remaining)
+
 					// Update UI Countdown
-					timeStr := formatDuration(remaining)
-					mTimeLeft.SetTitle(fmt.Sprintf("Time left: %s", timeStr))
-					systray.SetTooltip(fmt.Sprintf("%s mode: %s remaining", currentModeName, timeStr))
+					a.updateStatus()
+					a.setActiveIcon()
+					a.updateTooltip()
+					a.updateTitle()
 				}
 			}
 		}
 	}()
 }
 
+// releaseDevices restores the display brightness and power, Night Light,
+// lid close action and hardware indicator, and stops the end action guard,
+// before Espresso exits.
+func (a *app) releaseDevices() {
+	if a.dimmer != nil {
+		a.dimmer.close()
+	}
+	if a.displayPower != nil {
+		a.displayPower.close()
+	}
+	a.restoreNightLight()
+	a.restoreLid()
+	a.indicator.close()
+	a.endGuard.stop()
+}
+
+// expire ends the manual session as if its time were up: it flashes the
+// overlay, notifies and runs the end action. reason is recorded in the
+// event log.
+func (a *app) expire(reason string) {
+	finished, onEnd := a.modeName(), a.sessionOnEnd
+	a.resetState(reason)
+	if a.cfg.Overlay.FlashOnExpiry {
+		a.overlay.flash(tr("overlay.finished", finished))
+	}
+
+	// Notify User
+	msg := a.releasedMessage()
+	if onEnd != "" {
+		a.scheduleEndAction(onEnd, msg)
+		return
+	}
+	go showToast(tagSession, tr("toast.finished"), msg, icoffPath())
+}
+
+// askCustomDuration prompts for a session length, starting from text, until
+// the user enters a valid one or cancels, and sends it on ch.
+func askCustomDuration(text string, ch chan<- time.Duration) {
+	for {
+		var ok bool
+		text, ok = inputBox(tr("custom.title"), tr("custom.prompt"), text)
+		if !ok {
+			return
+		}
+		d, err := parseSessionDuration(text)
+		if err != nil {
+			showMessage(tr("custom.title"), tr("custom.invalid", err))
+			continue
+		}
+		ch <- d
+		return
+	}
+}
+
+// startedMessage describes the session that has just started.
+func (a *app) startedMessage() string {
+	msg := tr("toast.indefinite")
+	if a.timed() {
+		msg = tr("toast.for", formatFriendlyDuration(a.session.Mode.Duration))
+	}
+	if a.sessionOnEnd != "" {
+		msg += tr("toast.then", a.sessionOnEnd)
+	}
+	if a.sessionNote != "" {
+		msg += "\n" + a.sessionNote
+	}
+	return msg
+}
+
+// remindInfinite nags about a forgotten infinite session every
+// InfiniteReminderHours.
+func (a *app) remindInfinite() {
+	interval := time.Duration(a.cfg.InfiniteReminderHours * float64(time.Hour))
+	if interval <= 0 || time.Since(a.lastReminder) < interval {
+		return
+	}
+	a.lastReminder = time.Now()
+
+	held := time.Since(a.session.Started).Round(time.Minute)
+	go showToastActions(tagReminder, tr("toast.still_running", a.modeName()),
+		tr("toast.held_so_far", formatFriendlyDuration(held)), iconPath(),
+		[]toastAction{a.stopAction(), {Label: tr("action.dismiss")}})
+}
+
+// capInfinite converts an infinite session that has run for
+// InfiniteCapHours into a timed one.
+func (a *app) capInfinite() {
+	limit := time.Duration(a.cfg.InfiniteCapHours * float64(time.Hour))
+	if limit <= 0 || time.Since(a.session.Started) < limit {
+		return
+	}
+
+	grace := time.Duration(a.cfg.InfiniteCapGraceMinutes) * time.Minute
+	if grace <= 0 {
+		grace = defaultInfiniteCapGrace
+	}
+	now := time.Now()
+	a.session.EndAt(now.Add(grace), now)
+	a.applyState()
+
+	go showToast(tagSession, tr("toast.capped", a.modeName()),
+		tr("toast.capped_message", formatFriendlyDuration(limit), formatFriendlyDuration(grace)), iconPath())
+}
+
+// sessionName is the name of a session of mode m: m.Name, or else the
+// preset with the same duration, if any.
+func sessionName(m EspressoMode) string {
+	if m.Name != "" {
+		return m.Name
+	}
+	for _, p := range modes {
+		if p.Duration == m.Duration {
+			return p.Name
+		}
+	}
+	return "Custom"
+}
+
+// sessionOnEnd returns the end action of a session of mode m, "" for none.
+func (cfg Config) sessionOnEnd(m EspressoMode) string {
+	switch {
+	case m.OnEnd == "none":
+		return ""
+	case m.OnEnd == "" && m.Duration > 0:
+		action, _ := parseEndAction(cfg.EndAction)
+		return action
+	default:
+		return m.OnEnd
+	}
+}
+
+// sessionSimulate returns the activity simulation of a session of mode m,
+// before parseSimulate.
+func (cfg Config) sessionSimulate(m EspressoMode) string {
+	simulate := m.Simulate
+	if simulate == "" {
+		simulate = cfg.Simulate
+	}
+	if simulate == "" && cfg.PreventLock {
+		simulate = simulateMouse
+	}
+	if m.AwayPrevention && (simulate == "" || strings.EqualFold(simulate, simulateNone)) {
+		simulate = simulatePresence
+	}
+	if !cfg.feature(featureSimulation) {
+		return ""
+	}
+	return simulate
+}
+
+// resetState ends the manual session. Sleep stays blocked if a trigger is
+// still holding it. reason is recorded in the event log.
+func (a *app) resetState(reason string) {
+	a.logSessionEnd(reason)
+	a.session = nil
+	a.applyState()
+}
+
+// startSession starts a manual session of mode m; m.Duration is negative
+// for no limit. source describes what started it, e.g. sourceTray. If
+// m.Name is empty the mode is named after the preset with that duration,
+// if any, and m.Flags of 0 use the Keep Awake menu setting.
+func (a *app) startSession(m EspressoMode, source string) {
+	a.logSessionEnd("replaced")
+	a.pendingResume = nil
+	a.sessionSource = source
+	a.sessionNote = m.Note
+	a.sessionOnEnd = a.cfg.sessionOnEnd(m)
+	a.clearEndAction()
+	a.releaseNonce = ""
+	a.actionNonce = ""
+	a.expiryWarned = false
+	a.sessionWatch = m.Watch
+	a.sessionBack = m.Back
+	a.backAway = false
+	flags := m.Flags
+	if flags == 0 {
+		flags = a.defaultSessionFlags()
+	}
+	var err error
+	if a.sessionSimulate, err = parseSimulate(a.cfg.sessionSimulate(m)); err != nil {
+		logWarnf("%v", err)
+	}
+
+	d := m.Duration
+	now := time.Now()
+	a.session = espresso.NewSession(espresso.Mode{Name: sessionName(m), Duration: d, Flags: espresso.Flags(flags)}, now)
+	a.lastReminder = now
+	if a.sessionUntil = !m.Until.IsZero(); a.sessionUntil {
+		a.session.EndAt(m.Until, now)
+	}
+
+	a.milestones = nil
+	if a.session.Limited() {
+		specs := a.cfg.Milestones
+		if m.Milestones != nil {
+			specs = m.Milestones
+		}
+		if a.milestones, err = parseMilestones(specs, d); err != nil {
+			logWarnf("%v", err)
+		}
+	}
+	logSessionStart(a.modeName(), d, source)
+	a.applyState()
+}
+
+// keepOptions are the choices of the Keep Awake menu.
+var keepOptions = []struct {
+	flags uint32
+	key   string // menu.keep.<key> in the locale files
+}{
+	{ES_SYSTEM_REQUIRED | ES_DISPLAY_REQUIRED, "both"},
+	{ES_SYSTEM_REQUIRED, "system"},
+	{ES_DISPLAY_REQUIRED, "display"},
+}
+
+// defaultSessionFlags returns what manual sessions keep awake unless their
+// mode says otherwise.
+func (a *app) defaultSessionFlags() uint32 {
+	if len(a.cfg.Keep) == 0 && isServer() {
+		return ES_SYSTEM_REQUIRED
+	}
+	flags, err := parseKeep(a.cfg.Keep)
+	if err != nil {
+		logWarnf("%v", err)
+		return ES_SYSTEM_REQUIRED | ES_DISPLAY_REQUIRED
+	}
+	return flags
+}
+
+// keepName is the Keep Awake menu label for flags.
+func keepName(flags uint32) string {
+	for _, o := range keepOptions {
+		if o.flags == flags {
+			return tr("menu.keep." + o.key)
+		}
+	}
+	return flagsText(flags)
+}
+
+// keepList is the inverse of parseKeep.
+func keepList(flags uint32) []string {
+	var keep []string
+	if flags&ES_SYSTEM_REQUIRED != 0 {
+		keep = append(keep, "system")
+	}
+	if flags&ES_DISPLAY_REQUIRED != 0 {
+		keep = append(keep, "display")
+	}
+	return keep
+}
+
+// extend adds d to the timed session without restarting it.
+func (a *app) extend(d time.Duration) {
+	if !a.timed() {
+		return
+	}
+	a.session.Extend(d)
+	logSessionExtended(a.modeName(), d)
+	// applyState also moves the end action guard, the title and the
+	// tooltip to the new end.
+	a.applyState()
+}
+
+// pause lets the system sleep while keeping the rest of the manual
+// session for resume.
+func (a *app) pause() {
+	if !a.active() || a.paused() {
+		return
+	}
+	a.session.Pause(time.Now())
+	a.sessionUntil = false // it resumes with the time it had left
+	logSessionPaused(a.modeName(), a.pausedLeft())
+	a.applyState()
+	go showToast(tagSession, tr("toast.paused", a.modeName()), a.releasedMessage(), icoffPath())
+}
+
+// resume continues a paused session with the time it had left.
+func (a *app) resume() {
+	if !a.paused() {
+		return
+	}
+	a.session.Resume(time.Now())
+	logSessionResumed(a.modeName())
+	a.applyState()
+	msg := tr("toast.indefinite")
+	if left, ok := a.session.Remaining(time.Now()); ok {
+		msg = tr("toast.remaining", formatFriendlyDuration(left))
+	}
+	go showToast(tagSession, tr("toast.resumed", a.modeName()), msg, iconPath())
+}
+
+// logSessionEnd records the end of the manual session, if one is active.
+func (a *app) logSessionEnd(reason string) {
+	if a.session != nil {
+		logSessionEnd(a.modeName(), reason, time.Since(a.session.Started))
+	}
+}
+
+// --- Manual Session State ---
+
+// The manual session's state lives in a.session. These report on it for
+// the menu, tooltip, API and the rest, and are safe to call without one.
+
+// active reports whether a manual session runs, paused or not.
+func (a *app) active() bool {
+	return a.session != nil
+}
+
+// timed reports whether the manual session has a time limit.
+func (a *app) timed() bool {
+	return a.session != nil && a.session.Limited()
+}
+
+// infinite reports whether the manual session has no time limit.
+func (a *app) infinite() bool {
+	return a.session != nil && !a.session.Limited()
+}
+
+func (a *app) paused() bool {
+	return a.session != nil && a.session.Paused()
+}
+
+// timeLeft is how long the timed session has left, or had when paused.
+func (a *app) timeLeft() time.Duration {
+	if a.session == nil {
+		return 0
+	}
+	left, _ := a.session.Remaining(time.Now())
+	return left
+}
+
+// pausedLeft is the time left of the paused session, or -1 without a time
+// limit, as the event log records it.
+func (a *app) pausedLeft() time.Duration {
+	if !a.timed() {
+		return -1
+	}
+	return a.timeLeft()
+}
+
+// sessionEnd is when the running timed session ends.
+func (a *app) sessionEnd() time.Time {
+	if a.session == nil {
+		return time.Time{}
+	}
+	end, _ := a.session.End()
+	return end
+}
+
+// modeName is the name of the manual session's mode, "Decaf" without one.
+func (a *app) modeName() string {
+	if a.session == nil {
+		return "Decaf"
+	}
+	return a.session.Mode.Name
+}
+
+// sessionFlags is what the manual session keeps awake, ES_SYSTEM_REQUIRED
+// and/or ES_DISPLAY_REQUIRED.
+func (a *app) sessionFlags() uint32 {
+	if a.session == nil {
+		return 0
+	}
+	return uint32(a.session.Mode.Flags)
+}
+
+// applyState sets the execution state and the tray UI from the manual
+// session and the active triggers.
+func (a *app) applyState() {
+	switch {
+	case a.active() && !a.paused():
+		// System Call: Prevent Sleep
+		a.keepAwake(a.heldFlags())
+		a.simulator.set(a.sessionSimulate)
+		a.setLockGuard(true)
+
+		a.setActiveIcon()
+
+	case a.triggerFlags() != 0 || a.quickFlags != 0:
+		a.keepAwake(a.heldFlags())
+		a.simulator.set("")
+		a.setLockGuard(false)
+
+		a.setActiveIcon()
+
+	default:
+		// System Call: Allow Sleep
+		sleepThread.Do(func() { allowSleep() })
+		a.simulator.set("")
+		a.setLockGuard(false)
+
+		// Update UI
+		a.iconText = ""
+		a.inhibitFailed = false // nothing to warn about while sleep is allowed
+		a.heldLost = 0
+		systray.SetIcon(icoffData)
+	}
+	a.updateTooltip()
+	a.updateTitle()
+	a.syncEndGuard()
+	a.syncShutdownBlock()
+	a.syncLidPolicy()
+
+	switch {
+	case a.paused():
+		a.mPause.SetTitle(tr("menu.resume"))
+		a.mPause.SetTooltip(tr("menu.resume.tip"))
+		a.mPause.Show()
+	case a.active():
+		a.mPause.SetTitle(tr("menu.pause"))
+		a.mPause.SetTooltip(tr("menu.pause.tip"))
+		a.mPause.Show()
+	default:
+		a.mPause.Hide()
+	}
+	if a.timed() {
+		a.mExtend.Show()
+	} else {
+		a.mExtend.Hide()
+	}
+	a.indicator.set(a.indicatorState(), a.modeName())
+	a.saveSession()
+	a.updateStatus()
+}
+
+// keepAwake sets the execution state on sleepThread and reports
+// whether Windows accepted it; see inhibitResult.
+func (a *app) keepAwake(flags uint32) {
+	var err error
+	sleepThread.Do(func() { err = preventSleep(flags) })
+	a.lastAssert = time.Now()
+	if err != nil {
+		logErrorf("%v", err)
+		logInhibitFailed(flags, err)
+	}
+	a.inhibitResult(err)
+}
+
+// updateStatus refreshes the status block at the top of the menu: the mode
+// with its countdown, and what started or is holding it.
+func (a *app) updateStatus() {
+	holding := a.triggerFlags() != 0
+
+	var overlayText string
+	switch {
+	case a.paused() && a.infinite():
+		a.mMode.SetTitle(tr("status.paused", a.modeName()))
+	case a.paused():
+		a.mMode.SetTitle(tr("status.paused_left", a.modeName(), formatDuration(a.timeLeft())))
+	case a.infinite():
+		a.mMode.SetTitle(tr("status.infinite", a.modeName()))
+		overlayText = tr("overlay.infinite", a.modeName())
+	case a.active():
+		left := formatDuration(a.timeLeft())
+		a.mMode.SetTitle(tr("status.timed", a.modeName(), formatFriendlyDuration(a.session.Mode.Duration), left))
+		overlayText = tr("overlay.timed", a.modeName(), left)
+	case holding:
+		a.mMode.SetTitle(tr("status.triggered"))
+		overlayText = tr("overlay.triggered", a.triggerReasons())
+	case a.quickFlags != 0:
+		a.mMode.SetTitle(tr("status.quick", a.quickToggleNames()))
+	default:
+		a.mMode.SetTitle(tr("status.decaf"))
+	}
+	a.overlay.set(overlayText, a.cfg.Overlay.Enabled && overlayText != "", a.cfg.Overlay)
+
+	switch {
+	case a.active() && holding:
+		a.mSource.SetTitle(tr("status.source_held", a.sessionSource, a.session.Started.Format("15:04"), a.triggerReasons()))
+		a.mSource.Show()
+	case a.active() && a.sessionNote != "":
+		a.mSource.SetTitle(tr("status.source_note", a.sessionSource, a.session.Started.Format("15:04"), a.sessionNote))
+		a.mSource.Show()
+	case a.active():
+		a.mSource.SetTitle(tr("status.source", a.sessionSource, a.session.Started.Format("15:04")))
+		a.mSource.Show()
+	case holding:
+		a.mSource.SetTitle(tr("status.held", a.triggerReasons()))
+		a.mSource.Show()
+	default:
+		a.mSource.Hide()
+	}
+}
+
+func (a *app) saveConfig() {
+	if err := saveConfig(a.cfg); err != nil {
+		logWarnf("could not save config: %v", err)
+	}
+}
+
+// setChecked sets the check mark of a checkbox menu item.
+func setChecked(item *systray.MenuItem, checked bool) {
+	if checked {
+		item.Check()
+	} else {
+		item.Uncheck()
+	}
+}
+
+// quitConfirmation describes what quitting would end.
+func (a *app) quitConfirmation() string {
+	var what string
+	switch {
+	case a.infinite():
+		what = tr("quit.infinite", a.modeName())
+	case a.active():
+		what = tr("quit.timed", a.modeName(), formatDuration(a.timeLeft()))
+	case a.triggerFlags() == 0:
+		what = tr("quit.quick", a.quickToggleNames())
+	default:
+		what = tr("quit.triggered", a.triggerReasons())
+	}
+	return what + "\n\n" + tr("quit.confirm")
+}
+
 func onExit() {
 	if instanceMutex != 0 {
 		_ = windows.CloseHandle(instanceMutex)
 		instanceMutex = 0
 	}
-	close(execStateCh)
+	sleepThread.Close()
 }
@@ -0,0 +1,88 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Countdown Milestones ---
+
+// parseMilestones converts milestone specs into the time left at which each
+// fires during a session of length total. A spec is either a percentage of
+// the session that has passed ("50%" is halfway) or the time left ("30m",
+// "10m"); "none" is ignored. Milestones that would fire at the very start
+// are dropped. The result is sorted so that the next milestone comes first.
+func parseMilestones(specs []string, total time.Duration) ([]time.Duration, error) {
+	var left []time.Duration
+	for _, s := range specs {
+		s = strings.TrimSpace(s)
+		if strings.EqualFold(s, "none") {
+			continue
+		}
+		var d time.Duration
+		if p, ok := strings.CutSuffix(s, "%"); ok {
+			pct, err := strconv.ParseFloat(p, 64)
+			if err != nil || pct <= 0 || pct >= 100 {
+				return nil, fmt.Errorf("invalid milestone %q", s)
+			}
+			d = time.Duration(float64(total) * (100 - pct) / 100)
+		} else {
+			var err error
+			if d, err = parseSessionDuration(s); err != nil {
+				return nil, fmt.Errorf("invalid milestone %q", s)
+			}
+		}
+		if d < total {
+			left = append(left, d)
+		}
+	}
+	sort.Slice(left, func(i, j int) bool { return left[i] > left[j] })
+	return left, nil
+}
+
+// announceMilestones notifies about every milestone that remaining has
+// reached since the last tick.
+func (a *app) announceMilestones(remaining time.Duration) {
+	announced := false
+	for len(a.milestones) > 0 && remaining <= a.milestones[0] {
+		a.milestones = a.milestones[1:]
+		announced = true
+	}
+	if !announced {
+		return
+	}
+
+	left := remaining.Round(time.Minute)
+	if left < time.Minute {
+		left = time.Minute
+	}
+	msg := tr("expiry.message", formatFriendlyDuration(left), a.sessionEnd().Format("15:04"))
+	if a.sessionOnEnd != "" {
+		msg = tr("expiry.message_action", formatFriendlyDuration(left), a.sessionOnEnd, a.sessionEnd().Format("15:04"))
+	}
+	actions := append(a.extendActions(), a.stopAction())
+	go showToastActions(tagSession, tr("toast.mode", a.modeName()), msg, iconPath(), actions)
+}
@@ -0,0 +1,82 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// --- Modes ---
+
+type EspressoMode struct {
+	Name     string
+	Duration time.Duration // 0 for infinite
+	Desc     string
+	Flags    uint32 // 0 for the Keep Awake menu setting
+	Note     string // shown with the status, e.g. why the session runs
+	OnEnd    string // end action when the session expires, see parseEndAction; "none" overrides Config.EndAction
+
+	// Milestones override Config.Milestones if not nil.
+	Milestones []string
+
+	// Simulate overrides Config.Simulate if not empty; see parseSimulate.
+	Simulate string
+
+	// AwayPrevention simulates presence if nothing else is simulated; see
+	// simulatePresence.
+	AwayPrevention bool
+
+	// Watch lists processes whose exit ends the session; see checkWatch.
+	Watch []string
+
+	// Back holds the session until the user returns; see checkBack.
+	Back bool
+
+	// Until is the wall-clock end of the session, if it has one; Duration
+	// is then the time left when it starts. See untilMode.
+	Until time.Time
+}
+
+// builtinModes are the coffee presets. loadConfig merges the user's modes
+// into modes.
+var builtinModes = []EspressoMode{
+	{Name: "Milk", Duration: 3 * time.Minute, Desc: "No caffeine, just for testing purposes."},
+	{Name: "Drop", Duration: 10 * time.Minute, Desc: "Just a drop, almost no caffeine."},
+	{Name: "Latte", Duration: 30 * time.Minute, Desc: "Gentle boost to get you started."},
+	{Name: "Cappuccino", Duration: 1 * time.Hour, Desc: "Noticeable caffeine, perfectly balanced."},
+	{Name: "Americano", Duration: 3 * time.Hour, Desc: "Stronger, long-lasting alertness."},
+	{Name: "Espresso", Duration: 6 * time.Hour, Desc: "Concentrated, powerful kick."},
+	{Name: "Lungo", Duration: 8 * time.Hour, Desc: "Super concentrated, extended energy."},
+	{Name: "Doppio", Duration: 12 * time.Hour, Desc: "Double espresso, full-on focus all day."},
+	{Name: "Pure Caffeine", Duration: -1, Desc: "Maximum alertness, use with caution."}, // -1 for infinite
+}
+
+func formatFriendlyDuration(d time.Duration) string {
+	if d < 0 {
+		return "Infinity"
+	}
+	if d >= time.Hour {
+		if d%time.Hour == 0 {
+			return fmt.Sprintf("%dh", int(d.Hours()))
+		}
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
@@ -0,0 +1,205 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Night Light ---
+
+// For overnight jobs run at the desk, Espresso can turn on Windows Night
+// Light during sessions late in the evening, and turn it off again when
+// the session ends if it was off before. Espresso never turns Night Light
+// off otherwise. Windows keeps its state in an undocumented CloudStore
+// blob that the Settings app watches; the layout used here is the one
+// Windows 10 and 11 share.
+
+const nightLightKey = `Software\Microsoft\Windows\CurrentVersion\CloudStore\Store\DefaultAccount\Current\` +
+	`default$windows.data.bluelightreduction.bluelightreductionstate\windows.data.bluelightreduction.bluelightreductionstate`
+
+// While Night Light is on, the blob is 43 bytes long and has the flag
+// byte nightLightOn and the two bytes nightLightOnField at index 23. While
+// it is off, it is 41 bytes long, with nightLightOff and without them.
+const (
+	nightLightFlagIndex  = 18
+	nightLightOn         = 0x15
+	nightLightOff        = 0x13
+	nightLightFieldIndex = 23
+	nightLightOnLen      = 43
+	nightLightOffLen     = 41
+)
+
+var nightLightOnField = []byte{0x10, 0x00}
+
+// NightLightConfig turns Night Light on during sessions between From and
+// To, e.g. "21:00" and "07:00" (the defaults).
+type NightLightConfig struct {
+	Enabled bool   `json:"enabled,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+// lateNight reports whether t falls between From and To.
+func (c NightLightConfig) lateNight(t time.Time) bool {
+	from, to := 21*60, 7*60 // minutes after midnight
+	if c.From != "" {
+		if h, m, err := parseClockTime(c.From); err == nil {
+			from = h*60 + m
+		} else {
+			logWarnf("night_light: %v", err)
+		}
+	}
+	if c.To != "" {
+		if h, m, err := parseClockTime(c.To); err == nil {
+			to = h*60 + m
+		} else {
+			logWarnf("night_light: %v", err)
+		}
+	}
+	now := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to // spans midnight
+}
+
+// updateNightLight turns Night Light on once a session runs late at night
+// and restores it when the session ends.
+func (a *app) updateNightLight() {
+	want := a.cfg.NightLight.Enabled && a.active() && !a.paused() && a.cfg.NightLight.lateNight(time.Now())
+	if want == a.nightLightHeld {
+		return
+	}
+	a.nightLightHeld = want
+	if !want {
+		a.restoreNightLight()
+		return
+	}
+
+	on, err := nightLightEnabled()
+	if err != nil {
+		logWarnf("could not read Night Light: %v", err)
+		return
+	}
+	if on {
+		return
+	}
+	if err := setNightLight(true); err != nil {
+		logWarnf("could not turn on Night Light: %v", err)
+		return
+	}
+	a.nightLightSet = true
+}
+
+// restoreNightLight turns Night Light off again if Espresso turned it on.
+func (a *app) restoreNightLight() {
+	if !a.nightLightSet {
+		return
+	}
+	a.nightLightSet = false
+	if err := setNightLight(false); err != nil {
+		logWarnf("could not turn off Night Light: %v", err)
+	}
+}
+
+func readNightLight() ([]byte, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, nightLightKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	data, _, err := k.GetBinaryValue("Data")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := nightLightState(data); !ok {
+		return nil, errors.New("unrecognized Night Light state")
+	}
+	return data, nil
+}
+
+// nightLightState reports whether the blob data has Night Light on, and
+// whether it has a layout Espresso knows.
+func nightLightState(data []byte) (on, ok bool) {
+	switch {
+	case len(data) == nightLightOnLen && data[nightLightFlagIndex] == nightLightOn:
+		return true, true
+	case len(data) == nightLightOffLen && data[nightLightFlagIndex] == nightLightOff:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func nightLightEnabled() (bool, error) {
+	data, err := readNightLight()
+	if err != nil {
+		return false, err
+	}
+	on, _ := nightLightState(data)
+	return on, nil
+}
+
+// setNightLight rewrites the state blob with Night Light on or off.
+func setNightLight(on bool) error {
+	data, err := readNightLight()
+	if err != nil {
+		return err
+	}
+	if now, _ := nightLightState(data); now == on {
+		return nil
+	}
+
+	k, err := registry.OpenKey(registry.CURRENT_USER, nightLightKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetBinaryValue("Data", toggleNightLight(data, on))
+}
+
+// toggleNightLight returns a copy of the blob data, which must be in the
+// other state, with Night Light on or off.
+func toggleNightLight(data []byte, on bool) []byte {
+	next := make([]byte, 0, nightLightOnLen)
+	next = append(next, data[:nightLightFieldIndex]...)
+	if on {
+		next = append(next, nightLightOnField...)
+		next = append(next, data[nightLightFieldIndex:]...)
+		next[nightLightFlagIndex] = nightLightOn
+	} else {
+		next = append(next, data[nightLightFieldIndex+len(nightLightOnField):]...)
+		next[nightLightFlagIndex] = nightLightOff
+	}
+	// Bytes 10-14 are a change counter; Windows ignores a blob whose
+	// counter has not moved.
+	for i := 10; i < 15; i++ {
+		if next[i] != 0xff {
+			next[i]++
+			break
+		}
+	}
+	return next
+}
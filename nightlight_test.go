@@ -0,0 +1,77 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// nightLightOffBlob has the layout of a Night Light state blob with
+// Night Light off; only the bytes toggleNightLight looks at matter.
+var nightLightOffBlob = []byte{
+	0x43, 0x42, 0x01, 0x00, 0x0a, 0x02, 0x01, 0x00, 0x2a, 0x06,
+	0x8e, 0xa4, 0xb5, 0xbb, 0x06, 0x2a, 0x2b, 0x0e, 0x13, 0x43,
+	0x42, 0x01, 0x00, 0xca, 0x14, 0x0e, 0x15, 0x00, 0xca, 0x1e,
+	0x0e, 0x07, 0x00, 0xcf, 0x28, 0xd2, 0x93, 0x02, 0x00, 0x00,
+	0x00,
+}
+
+func TestToggleNightLight(t *testing.T) {
+	on := toggleNightLight(nightLightOffBlob, true)
+	if state, ok := nightLightState(on); !ok || !state {
+		t.Fatalf("turned on: got on=%v ok=%v for % x", state, ok, on)
+	}
+	if got := on[nightLightFieldIndex : nightLightFieldIndex+2]; !bytes.Equal(got, nightLightOnField) {
+		t.Errorf("turned on: bytes at %d are % x, want % x", nightLightFieldIndex, got, nightLightOnField)
+	}
+	if on[10] != nightLightOffBlob[10]+1 {
+		t.Errorf("turned on: change counter is %#x, want %#x", on[10], nightLightOffBlob[10]+1)
+	}
+
+	off := toggleNightLight(on, false)
+	if state, ok := nightLightState(off); !ok || state {
+		t.Fatalf("turned off: got on=%v ok=%v for % x", state, ok, off)
+	}
+	// Only the change counter differs after a round trip.
+	want := bytes.Clone(nightLightOffBlob)
+	want[10] += 2
+	if !bytes.Equal(off, want) {
+		t.Errorf("round trip:\n got % x\nwant % x", off, want)
+	}
+}
+
+func TestToggleNightLightCounterCarry(t *testing.T) {
+	blob := bytes.Clone(nightLightOffBlob)
+	blob[10] = 0xff
+	on := toggleNightLight(blob, true)
+	if on[10] != 0xff || on[11] != blob[11]+1 {
+		t.Errorf("change counter is % x, want ff %x", on[10:12], blob[11]+1)
+	}
+}
+
+func TestNightLightStateUnknown(t *testing.T) {
+	for _, data := range [][]byte{nil, nightLightOffBlob[:40], append(bytes.Clone(nightLightOffBlob), 0, 0)} {
+		if _, ok := nightLightState(data); ok {
+			t.Errorf("nightLightState(% x) accepted an unknown layout", data)
+		}
+	}
+}
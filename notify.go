@@ -0,0 +1,284 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Toast Notifications ---
+
+const (
+	toastAppID = "Espresso"
+	toastGroup = "Espresso"
+
+	CREATE_NO_WINDOW = 0x08000000
+)
+
+// Notification tags. A toast replaces the one in Action Center that has the
+// same tag, so each source of notifications keeps a single entry up to date
+// instead of piling up new ones.
+const (
+	tagSession  = "session"
+	tagReminder = "reminder"
+	tagTrigger  = "trigger-"
+)
+
+// toastNotification is shown through the WinRT ToastNotificationManager
+// from a hidden PowerShell process. The script is adapted from
+// github.com/go-toast/toast (MIT), which cannot set a tag or group.
+type toastNotification struct {
+	Title   string
+	Message string
+	Icon    string
+	Tag     string
+	Actions []toastAction
+}
+
+type toastAction struct {
+	Label     string
+	Arguments string
+}
+
+var toastTemplate = template.Must(template.New("toast").Funcs(template.FuncMap{
+	"xml": func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+	"ps": func(s string) string {
+		return strings.ReplaceAll(s, "'", "''")
+	},
+}).Parse(`
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.UI.Notifications.ToastNotification, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+
+$ErrorActionPreference = 'Stop'
+
+$template = @'
+<toast activationType="protocol" duration="short">
+    <visual>
+        <binding template="ToastGeneric">
+            {{if .Icon}}<image placement="appLogoOverride" src="{{xml .Icon}}" />{{end}}
+            <text>{{xml .Title}}</text>
+            {{if .Message}}<text>{{xml .Message}}</text>{{end}}
+        </binding>
+    </visual>
+    {{if .Actions}}
+    <actions>
+        {{range .Actions}}<action activationType="protocol" content="{{xml .Label}}" arguments="{{xml .Arguments}}" />
+        {{end}}
+    </actions>
+    {{end}}
+</toast>
+'@
+
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($template)
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+{{if .Tag}}$toast.Tag = '{{ps .Tag}}'
+$toast.Group = '{{ps .Group}}'
+{{end}}$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('{{ps .AppID}}')
+# Toasts disabled for the app, the user or by policy are dropped silently.
+if ($notifier.Setting -ne 'Enabled') { exit 2 }
+$notifier.Show($toast)
+`))
+
+func (n toastNotification) push() error {
+	var script bytes.Buffer
+	err := toastTemplate.Execute(&script, struct {
+		toastNotification
+		AppID, Group string
+	}{n, toastAppID, toastGroup})
+	if err != nil {
+		return err
+	}
+	return runPowerShell(script.String())
+}
+
+// runPowerShell runs script in a hidden Windows PowerShell process.
+func runPowerShell(script string) error {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass",
+		"-EncodedCommand", encodePowerShell(script))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: CREATE_NO_WINDOW}
+	return cmd.Run()
+}
+
+// encodePowerShell encodes script for -EncodedCommand (base64 of UTF-16LE).
+func encodePowerShell(script string) string {
+	u := utf16.Encode([]rune(script))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		b[2*i] = byte(c)
+		b[2*i+1] = byte(c >> 8)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// toastTag derives a tag from a name. Windows limits tags to 64 characters.
+func toastTag(prefix, name string) string {
+	tag := prefix + name
+	if r := []rune(tag); len(r) > 64 {
+		tag = string(r[:64])
+	}
+	return tag
+}
+
+// --- Legacy Notification Fallback ---
+
+var (
+	modshell32             = windows.NewLazySystemDLL("shell32.dll")
+	procShellNotifyIconW   = modshell32.NewProc("Shell_NotifyIconW")
+	procMessageBoxTimeoutW = user32.NewProc("MessageBoxTimeoutW")
+)
+
+const (
+	NIM_MODIFY = 0x00000001
+	NIF_INFO   = 0x00000010
+	NIIF_INFO  = 0x00000001
+	MB_TOPMOST = 0x00040000
+
+	// systray registers its window under this class and its icon with
+	// this ID.
+	systrayWindowClass = "SystrayClass"
+	systrayIconID      = 100
+
+	transientMessageTimeout = 10000 // milliseconds
+)
+
+// notifyIconData mirrors NOTIFYICONDATAW.
+type notifyIconData struct {
+	Size                       uint32
+	Wnd                        windows.HWND
+	ID, Flags, CallbackMessage uint32
+	Icon                       windows.Handle
+	Tip                        [128]uint16
+	State, StateMask           uint32
+	Info                       [256]uint16
+	Timeout, Version           uint32
+	InfoTitle                  [64]uint16
+	InfoFlags                  uint32
+	GuidItem                   windows.GUID
+	BalloonIcon                windows.Handle
+}
+
+var (
+	trayMu             sync.Mutex
+	trayHWND           windows.HWND
+	findTrayWindowProc = windows.NewCallback(findTrayWindow)
+)
+
+// trayWindow returns the hidden window that owns our tray icon, or 0 if the
+// icon has not been created yet.
+func trayWindow() windows.HWND {
+	trayMu.Lock()
+	defer trayMu.Unlock()
+	if trayHWND == 0 {
+		_ = windows.EnumWindows(findTrayWindowProc, nil)
+	}
+	return trayHWND
+}
+
+// findTrayWindow is the EnumWindows callback used by trayWindow. It runs
+// with trayMu held.
+func findTrayWindow(hwnd windows.HWND, _ uintptr) uintptr {
+	var owner uint32
+	windows.GetWindowThreadProcessId(hwnd, &owner)
+	if owner != uint32(os.Getpid()) {
+		return 1
+	}
+	var class [64]uint16
+	n, _ := windows.GetClassName(hwnd, &class[0], int32(len(class)))
+	if windows.UTF16ToString(class[:n]) == systrayWindowClass {
+		trayHWND = hwnd
+		return 0
+	}
+	return 1
+}
+
+// showBalloon shows a balloon tip on the tray icon. On Windows 10 and later
+// the shell renders it as a toast where possible, but it also works where
+// toasts are unavailable.
+func showBalloon(title, message string) error {
+	hwnd := trayWindow()
+	if hwnd == 0 {
+		return errors.New("tray icon window not found")
+	}
+
+	nid := notifyIconData{
+		Wnd:       hwnd,
+		ID:        systrayIconID,
+		Flags:     NIF_INFO,
+		InfoFlags: NIIF_INFO,
+	}
+	nid.Size = uint32(unsafe.Sizeof(nid))
+	copyUTF16(nid.InfoTitle[:], title)
+	copyUTF16(nid.Info[:], message)
+
+	if r, _, err := procShellNotifyIconW.Call(NIM_MODIFY, uintptr(unsafe.Pointer(&nid))); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// showTransientMessage shows a message box that closes itself after a few
+// seconds. It is the last resort when neither toasts nor balloons work.
+func showTransientMessage(title, message string) {
+	t, _ := windows.UTF16PtrFromString(title)
+	m, _ := windows.UTF16PtrFromString(message)
+
+	if !procAvailable(procMessageBoxTimeoutW) {
+		// Undocumented, so a stripped-down image may not export it.
+		logInfof("%s: %s", title, message)
+		return
+	}
+	procMessageBoxTimeoutW.Call(0,
+		uintptr(unsafe.Pointer(m)),
+		uintptr(unsafe.Pointer(t)),
+		uintptr(MB_ICONINFORMATION|MB_TOPMOST),
+		0,
+		transientMessageTimeout)
+}
+
+// copyUTF16 copies s into the fixed-size buffer dst, truncating it and
+// keeping the terminating NUL.
+func copyUTF16(dst []uint16, s string) {
+	u, _ := windows.UTF16FromString(s)
+	if len(u) > len(dst) {
+		u = u[:len(dst)]
+		u[len(u)-1] = 0
+	}
+	copy(dst, u)
+}
@@ -0,0 +1,249 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Mini Overlay ---
+
+// OverlayConfig controls the always-on-top countdown window.
+type OverlayConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Theme         string `json:"theme,omitempty"`  // see overlayThemes
+	Corner        string `json:"corner,omitempty"` // top_left, top_right, bottom_left, bottom_right (default)
+	FlashOnExpiry bool   `json:"flash_on_expiry,omitempty"`
+}
+
+type overlayTheme struct {
+	key    string
+	label  string
+	width  int32 // 96-DPI pixels
+	height int32
+	font   int32 // character height in 96-DPI pixels
+	weight int32
+	fg, bg uint32
+	alpha  byte
+}
+
+// overlayThemes lists the themes in menu order. The first is the default.
+var overlayThemes = []overlayTheme{
+	{"standard", "Standard", 230, 56, 18, FW_NORMAL, rgb(255, 255, 255), rgb(40, 40, 40), 220},
+	{"large", "Large Text", 420, 120, 44, FW_BOLD, rgb(255, 255, 255), rgb(24, 24, 24), 240},
+	{"high_contrast", "High Contrast", 420, 120, 44, FW_BOLD, rgb(255, 255, 0), rgb(0, 0, 0), 255},
+}
+
+func findOverlayTheme(key string) overlayTheme {
+	for _, t := range overlayThemes {
+		if t.key == key {
+			return t
+		}
+	}
+	return overlayThemes[0]
+}
+
+const (
+	overlayClassName   = "EspressoOverlay"
+	overlayMargin      = 16 // 96-DPI pixels from the screen edges
+	overlayFlashTimer  = 1
+	overlayFlashPeriod = 400 // milliseconds
+	overlayFlashCount  = 16  // color changes per flash
+
+	wmOverlayUpdate = WM_USER + 1
+	wmOverlayFlash  = WM_USER + 2
+)
+
+// overlay is a small click-through window in a screen corner that shows the
+// current mode and countdown. It runs its own message loop on a locked OS
+// thread; other goroutines talk to it through its exported-style methods,
+// which post messages to that thread.
+type overlay struct {
+	hwnd windows.HWND
+
+	mu      sync.Mutex
+	text    string
+	visible bool
+	theme   overlayTheme
+	corner  string
+
+	// Only touched on the window thread.
+	flashLeft int
+	inverted  bool
+	font      uintptr
+}
+
+func startOverlay() *overlay {
+	o := &overlay{theme: overlayThemes[0]}
+	ready := make(chan struct{})
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hwnd, err := createWindow(overlayClassName,
+			WS_EX_TOPMOST|WS_EX_TOOLWINDOW|WS_EX_LAYERED|WS_EX_TRANSPARENT|WS_EX_NOACTIVATE,
+			WS_POPUP, 0, 0, 0, 0, o.wndProc)
+		if err != nil {
+			logWarnf("could not create overlay window: %v", err)
+			close(ready)
+			return
+		}
+		o.hwnd = hwnd
+		close(ready)
+		runMessageLoop(0)
+	}()
+
+	<-ready
+	return o
+}
+
+// set updates the overlay text, visibility and appearance.
+func (o *overlay) set(text string, visible bool, cfg OverlayConfig) {
+	if o.hwnd == 0 {
+		return
+	}
+	o.mu.Lock()
+	o.text = text
+	o.visible = visible
+	o.theme = findOverlayTheme(cfg.Theme)
+	o.corner = cfg.Corner
+	o.mu.Unlock()
+	procPostMessageW.Call(uintptr(o.hwnd), wmOverlayUpdate, 0, 0)
+}
+
+// flash shows text in the overlay corner with flashing colors for a few
+// seconds, whether or not the overlay is enabled.
+func (o *overlay) flash(text string) {
+	if o.hwnd == 0 {
+		return
+	}
+	o.mu.Lock()
+	o.text = text
+	o.mu.Unlock()
+	procPostMessageW.Call(uintptr(o.hwnd), wmOverlayFlash, 0, 0)
+}
+
+func (o *overlay) wndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case wmOverlayUpdate:
+		o.layout(hwnd)
+		return 0
+
+	case wmOverlayFlash:
+		o.flashLeft = overlayFlashCount
+		o.layout(hwnd)
+		procSetTimer.Call(uintptr(hwnd), overlayFlashTimer, overlayFlashPeriod, 0)
+		return 0
+
+	case WM_TIMER:
+		o.flashLeft--
+		o.inverted = o.flashLeft > 0 && !o.inverted
+		if o.flashLeft <= 0 {
+			procKillTimer.Call(uintptr(hwnd), overlayFlashTimer)
+			o.layout(hwnd)
+		}
+		procInvalidateRect.Call(uintptr(hwnd), 0, 1)
+		return 0
+
+	case WM_PAINT:
+		o.paint(hwnd)
+		return 0
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// layout sizes and places the window for the current theme and corner, and
+// shows or hides it.
+func (o *overlay) layout(hwnd windows.HWND) {
+	o.mu.Lock()
+	theme, corner, visible := o.theme, o.corner, o.visible
+	o.mu.Unlock()
+
+	if o.font != 0 {
+		procDeleteObject.Call(o.font)
+	}
+	face, _ := windows.UTF16PtrFromString("Segoe UI")
+	o.font, _, _ = procCreateFontW.Call(
+		uintptr(-dpiScale(theme.font)), 0, 0, 0, uintptr(theme.weight),
+		0, 0, 0, DEFAULT_CHARSET, 0, 0, CLEARTYPE_QUALITY, 0,
+		uintptr(unsafe.Pointer(face)))
+
+	w, h, margin := dpiScale(theme.width), dpiScale(theme.height), dpiScale(overlayMargin)
+	area := workArea()
+	x, y := area.Right-w-margin, area.Bottom-h-margin
+	switch corner {
+	case "top_left":
+		x, y = area.Left+margin, area.Top+margin
+	case "top_right":
+		y = area.Top + margin
+	case "bottom_left":
+		x = area.Left + margin
+	}
+
+	procSetLayeredWindowAttributes.Call(uintptr(hwnd), 0, uintptr(theme.alpha), LWA_ALPHA)
+	procSetWindowPos.Call(uintptr(hwnd), HWND_TOPMOST, uintptr(x), uintptr(y), uintptr(w), uintptr(h), SWP_NOACTIVATE)
+	procInvalidateRect.Call(uintptr(hwnd), 0, 1)
+
+	if visible || o.flashLeft > 0 {
+		procShowWindow.Call(uintptr(hwnd), SW_SHOWNOACTIVATE)
+	} else {
+		procShowWindow.Call(uintptr(hwnd), SW_HIDE)
+	}
+}
+
+func (o *overlay) paint(hwnd windows.HWND) {
+	o.mu.Lock()
+	theme, text := o.theme, o.text
+	o.mu.Unlock()
+
+	fg, bg := theme.fg, theme.bg
+	if o.inverted {
+		fg, bg = bg, fg
+	}
+
+	var ps paintStruct
+	hdc, _, _ := procBeginPaint.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&ps)))
+	defer procEndPaint.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&ps)))
+
+	brush, _, _ := procCreateSolidBrush.Call(uintptr(bg))
+	var rc windows.Rect
+	procGetClientRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&rc)))
+	procFillRect.Call(hdc, uintptr(unsafe.Pointer(&rc)), brush)
+	procDeleteObject.Call(brush)
+
+	old, _, _ := procSelectObject.Call(hdc, o.font)
+	procSetBkMode.Call(hdc, TRANSPARENT)
+	procSetTextColor.Call(hdc, uintptr(fg))
+
+	// DT_VCENTER only works for single lines, so measure the text first.
+	t, _ := windows.UTF16FromString(text)
+	measured := rc
+	procDrawTextW.Call(hdc, uintptr(unsafe.Pointer(&t[0])), ^uintptr(0), uintptr(unsafe.Pointer(&measured)), DT_CENTER|DT_WORDBREAK|DT_NOPREFIX|DT_CALCRECT)
+	offset := (rc.Bottom - rc.Top - (measured.Bottom - measured.Top)) / 2
+	rc.Top += offset
+	procDrawTextW.Call(hdc, uintptr(unsafe.Pointer(&t[0])), ^uintptr(0), uintptr(unsafe.Pointer(&rc)), DT_CENTER|DT_WORDBREAK|DT_NOPREFIX)
+	procSelectObject.Call(hdc, old)
+}
@@ -0,0 +1,231 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Named Pipe ---
+
+// Scripts and tools such as Stream Deck plugins control the running
+// instance through a named pipe. Each line written to it is a JSON command,
+// e.g. {"command": "start", "mode": "Espresso"}, and is answered with one
+// line of JSON. Only the current user (and SYSTEM) may connect, so commands
+// need no token.
+
+const (
+	pipeName       = `\\.\pipe\espresso`
+	pipeBufferSize = 4096
+	sourcePipe     = "the named pipe"
+)
+
+// pipeRequest is one command read from the pipe.
+type pipeRequest struct {
+	Command  string `json:"command"`            // "start", "stop", "status" or "extend"
+	Mode     string `json:"mode,omitempty"`     // start: a mode name
+	Duration string `json:"duration,omitempty"` // start: overrides the mode's; extend: time to add
+	Keep     string `json:"keep,omitempty"`     // start: "system", "display" or both
+	OnEnd    string `json:"on_end,omitempty"`   // start: "lock" or "sleep"
+	Note     string `json:"note,omitempty"`     // start
+}
+
+type pipeReply struct {
+	Error  string     `json:"error,omitempty"`
+	Status *apiStatus `json:"status,omitempty"`
+}
+
+// pipeCall is a command handed to the main loop.
+type pipeCall struct {
+	req   pipeRequest
+	reply chan pipeReply
+}
+
+// startPipe serves the named pipe and returns the channel its commands are
+// delivered on. Every call must be answered.
+func startPipe() <-chan pipeCall {
+	ch := make(chan pipeCall)
+	sa, err := pipeSecurity()
+	if err != nil {
+		logWarnf("named pipe disabled: %v", err)
+		return ch
+	}
+	name, _ := windows.UTF16PtrFromString(pipeName)
+
+	go func() {
+		// The first instance must be ours, or another process is already
+		// listening under the name.
+		flags := uint32(windows.PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+		for {
+			h, err := windows.CreateNamedPipe(name, flags,
+				windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+				windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, sa)
+			if err != nil {
+				logWarnf("named pipe disabled: %v", err)
+				return
+			}
+			flags = windows.PIPE_ACCESS_DUPLEX
+
+			if err := windows.ConnectNamedPipe(h, nil); err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+				windows.CloseHandle(h)
+				continue
+			}
+			go servePipeClient(os.NewFile(uintptr(h), pipeName), ch)
+		}
+	}()
+	return ch
+}
+
+// pipeSecurity allows the current user and SYSTEM to connect.
+func pipeSecurity() (*windows.SecurityAttributes, error) {
+	u, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	sd, err := windows.SecurityDescriptorFromString(fmt.Sprintf("D:P(A;;GA;;;%s)(A;;GA;;;SY)", u.User.Sid))
+	if err != nil {
+		return nil, err
+	}
+	sa := &windows.SecurityAttributes{SecurityDescriptor: sd}
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+	return sa, nil
+}
+
+// servePipeClient answers the commands of one connection until the client
+// closes it.
+func servePipeClient(f *os.File, ch chan<- pipeCall) {
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, pipeBufferSize), pipeBufferSize)
+	enc := json.NewEncoder(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var rep pipeReply
+		c := pipeCall{reply: make(chan pipeReply, 1)}
+		if err := json.Unmarshal([]byte(line), &c.req); err != nil {
+			rep.Error = "invalid JSON command"
+		} else {
+			select {
+			case ch <- c:
+				rep = <-c.reply
+			case <-time.After(ipcReplyTimeout):
+				rep.Error = "Espresso is busy"
+			}
+		}
+		if err := enc.Encode(rep); err != nil {
+			return
+		}
+	}
+}
+
+// handlePipeCall runs on the main loop.
+func (a *app) handlePipeCall(c pipeCall) {
+	if err := a.runPipeCommand(c.req); err != nil {
+		c.reply <- pipeReply{Error: err.Error()}
+		return
+	}
+	s := a.apiStatus()
+	c.reply <- pipeReply{Status: &s}
+}
+
+func (a *app) runPipeCommand(req pipeRequest) error {
+	switch strings.ToLower(req.Command) {
+	case "status":
+	case "start":
+		m, err := req.mode()
+		if err != nil {
+			return err
+		}
+		a.startSession(m, sourcePipe)
+		go showToast(tagSession, tr("toast.started", a.modeName()), a.startedMessage(), iconPath())
+	case "stop":
+		if a.active() {
+			a.resetState("stopped")
+			go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+		}
+	case "extend":
+		d, err := parseSessionDuration(req.Duration)
+		if err != nil {
+			return err
+		}
+		if !a.timed() {
+			return errors.New("no timed session is running")
+		}
+		a.extend(d)
+	default:
+		return fmt.Errorf("unknown command %q", req.Command)
+	}
+	return nil
+}
+
+// mode resolves a start command. Without a mode or duration it starts an
+// infinite session, as the control API does.
+func (req pipeRequest) mode() (EspressoMode, error) {
+	m := EspressoMode{Duration: -1}
+	var err error
+	if req.Mode != "" {
+		found := false
+		for _, p := range modes {
+			if strings.EqualFold(p.Name, req.Mode) {
+				m, found = p, true
+				break
+			}
+		}
+		if !found {
+			return m, fmt.Errorf("unknown mode %q", req.Mode)
+		}
+	}
+	if req.Duration != "" {
+		m.Duration = -1
+		if !strings.EqualFold(req.Duration, "infinite") {
+			if m.Duration, err = parseSessionDuration(req.Duration); err != nil {
+				return m, err
+			}
+		}
+	}
+	if req.Keep != "" {
+		if m.Flags, err = parseKeep(strings.Split(req.Keep, ",")); err != nil {
+			return m, err
+		}
+	}
+	if req.OnEnd != "" {
+		if m.OnEnd, err = parseEndAction(req.OnEnd); err != nil {
+			return m, err
+		}
+	}
+	if req.Note != "" {
+		m.Note = req.Note
+	}
+	return m, nil
+}
@@ -0,0 +1,42 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package espresso keeps a computer awake. It is the core of the Espresso
+// tray app, for Go programs that want the same behavior without the tray.
+//
+// An Inhibitor asks the operating system to keep the system and/or the
+// display awake: with SetThreadExecutionState on Windows, D-Bus inhibitors
+// on Linux and IOKit power assertions on macOS.
+//
+// A Session is a keep-awake period started from a Mode: how long it lasts,
+// when it ends and how much is left, with pause, resume and extend.
+//
+// An Engine ties the two together: it runs one session at a time, holds
+// the inhibitor while the session runs, and releases it when the session
+// is stopped, paused or runs out. Programs that keep awake for more than
+// one reason at once, like the tray app, use a Session for each timed
+// reason and combine the flags for the inhibitor themselves, calling it
+// on a Thread.
+//
+//	e := espresso.NewEngine(espresso.NewInhibitor())
+//	defer e.Close()
+//	if err := e.Start(espresso.Mode{Name: "Render", Duration: 2 * time.Hour, Flags: espresso.System}); err != nil {
+//		return err
+//	}
+//	<-e.Expired()
+package espresso
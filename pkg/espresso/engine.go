@@ -0,0 +1,193 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// --- Engine ---
+
+// ErrNoSession is returned for changes to the session when none runs.
+var ErrNoSession = errors.New("espresso: no session is running")
+
+// Engine runs one session at a time and holds an Inhibitor while it runs.
+// Its methods may be called from any goroutine: calls into the inhibitor
+// are made on a Thread of its own, as Windows requires.
+type Engine struct {
+	inhibitor Inhibitor
+	thread    *Thread
+	expired   chan Session
+
+	mu      sync.Mutex
+	session *Session
+	timer   *time.Timer
+	closed  bool
+}
+
+// NewEngine returns an Engine that keeps awake with inhibitor. Close it
+// to release the inhibitor and stop its goroutine.
+func NewEngine(inhibitor Inhibitor) *Engine {
+	return &Engine{
+		inhibitor: inhibitor,
+		thread:    NewThread(),
+		expired:   make(chan Session, 1),
+	}
+}
+
+// Start replaces the running session, if any, with a session of m.
+func (e *Engine) Start(m Mode) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errors.New("espresso: engine closed")
+	}
+	s := NewSession(m, time.Now())
+	var err error
+	e.thread.Do(func() { err = e.inhibitor.Prevent(s.Mode.Flags) })
+	if err != nil {
+		return err
+	}
+	e.session = s
+	e.schedule()
+	return nil
+}
+
+// Stop ends the running session, if any, and allows sleep.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.end()
+}
+
+// Pause allows sleep until Resume, keeping the time that was left.
+func (e *Engine) Pause() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return ErrNoSession
+	}
+	e.session.Pause(time.Now())
+	e.schedule()
+	e.thread.Do(e.inhibitor.Allow)
+	return nil
+}
+
+// Resume continues a paused session.
+func (e *Engine) Resume() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return ErrNoSession
+	}
+	var err error
+	e.thread.Do(func() { err = e.inhibitor.Prevent(e.session.Mode.Flags) })
+	if err != nil {
+		return err
+	}
+	e.session.Resume(time.Now())
+	e.schedule()
+	return nil
+}
+
+// Extend adds d to the running session, if it has a time limit.
+func (e *Engine) Extend(d time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return ErrNoSession
+	}
+	e.session.Extend(d)
+	e.schedule()
+	return nil
+}
+
+// Current returns a copy of the running session, and false if there is
+// none.
+func (e *Engine) Current() (Session, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session == nil {
+		return Session{}, false
+	}
+	return *e.session, true
+}
+
+// Expired receives each session that runs out, once sleep is allowed
+// again. Only the latest is kept if nobody receives it.
+func (e *Engine) Expired() <-chan Session {
+	return e.expired
+}
+
+// Close ends the running session, allows sleep and stops the engine.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.end()
+	e.closed = true
+	e.thread.Close()
+}
+
+// schedule sets the timer for the end of the running session.
+func (e *Engine) schedule() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	end, ok := e.session.End()
+	if !ok {
+		return
+	}
+	s := e.session
+	e.timer = time.AfterFunc(time.Until(end), func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.session != s {
+			return // stopped or replaced meanwhile
+		}
+		if !s.Expired(time.Now()) {
+			e.schedule() // the clock was changed
+			return
+		}
+		e.end()
+		select {
+		case <-e.expired:
+		default:
+		}
+		e.expired <- *s
+	})
+}
+
+// end clears the running session and allows sleep.
+func (e *Engine) end() {
+	if e.session == nil {
+		return
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	e.session = nil
+	e.thread.Do(e.inhibitor.Allow)
+}
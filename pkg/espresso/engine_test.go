@@ -0,0 +1,133 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInhibitor records the calls an Engine makes.
+type fakeInhibitor struct {
+	mu    sync.Mutex
+	calls []string
+	err   error // returned by Prevent
+	held  Flags
+}
+
+func (f *fakeInhibitor) Prevent(flags Flags) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "prevent")
+	if f.err != nil {
+		return f.err
+	}
+	f.held = flags
+	return nil
+}
+
+func (f *fakeInhibitor) Allow() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, "allow")
+	f.held = 0
+}
+
+func (f *fakeInhibitor) Held() (Flags, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.held, true
+}
+
+func (f *fakeInhibitor) check(t *testing.T, want ...string) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !slices.Equal(f.calls, want) {
+		t.Errorf("inhibitor calls = %v, want %v", f.calls, want)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	f := &fakeInhibitor{}
+	e := NewEngine(f)
+	defer e.Close()
+
+	if err := e.Pause(); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Pause without a session = %v, want ErrNoSession", err)
+	}
+	if err := e.Start(Mode{Name: "Build", Duration: time.Hour, Flags: System}); err != nil {
+		t.Fatal(err)
+	}
+	if held, _ := f.Held(); held != System {
+		t.Errorf("held %v, want System", held)
+	}
+	if err := e.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := e.Current(); !ok || s.Mode.Name != "Build" || s.Paused() {
+		t.Errorf("Current = %+v, %v", s, ok)
+	}
+	e.Stop()
+	if _, ok := e.Current(); ok {
+		t.Error("a session is running after Stop")
+	}
+	f.check(t, "prevent", "allow", "prevent", "allow")
+}
+
+func TestEngineExpired(t *testing.T) {
+	f := &fakeInhibitor{}
+	e := NewEngine(f)
+	defer e.Close()
+
+	if err := e.Start(Mode{Name: "Short", Duration: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-e.Expired():
+		if s.Mode.Name != "Short" {
+			t.Errorf("expired %q, want Short", s.Mode.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the session did not expire")
+	}
+	if _, ok := e.Current(); ok {
+		t.Error("a session is running after it expired")
+	}
+	f.check(t, "prevent", "allow")
+}
+
+func TestEngineStartFails(t *testing.T) {
+	f := &fakeInhibitor{err: errors.New("refused")}
+	e := NewEngine(f)
+	defer e.Close()
+
+	if err := e.Start(Mode{Name: "Build"}); err == nil {
+		t.Error("Start succeeded although the inhibitor refused")
+	}
+	if _, ok := e.Current(); ok {
+		t.Error("a session is running after Start failed")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+// --- Inhibitor ---
+
+// Flags say what to keep awake. They have the values of the Windows
+// execution state flags on every platform.
+type Flags uint32
+
+const (
+	System  Flags = 0x00000001 // ES_SYSTEM_REQUIRED
+	Display Flags = 0x00000002 // ES_DISPLAY_REQUIRED
+)
+
+// Inhibitor is the platform's way of keeping the system and the display
+// awake. NewInhibitor returns the one for the current OS.
+//
+// On Windows the request belongs to the calling thread, so Prevent and
+// Allow must be called from one goroutine locked to its OS thread; an
+// Engine does this for you.
+type Inhibitor interface {
+	// Prevent replaces the current request with flags.
+	Prevent(flags Flags) error
+	// Allow releases the current request.
+	Allow()
+	// Held returns what the OS currently keeps awake, for any process,
+	// and false if the platform cannot tell.
+	Held() (Flags, bool)
+}
+
+// Logf receives the package's diagnostic messages. It discards them
+// unless set, and must be set before the package is used.
+var Logf = func(format string, args ...any) {}
@@ -0,0 +1,106 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+#include <stdlib.h>
+#include <IOKit/pwr_mgt/IOPMLib.h>
+
+static IOReturn espressoAssert(const char *type, const char *reason, IOPMAssertionID *id) {
+	CFStringRef t = CFStringCreateWithCString(kCFAllocatorDefault, type, kCFStringEncodingUTF8);
+	CFStringRef r = CFStringCreateWithCString(kCFAllocatorDefault, reason, kCFStringEncodingUTF8);
+	IOReturn ret = IOPMAssertionCreateWithName(t, kIOPMAssertionLevelOn, r, id);
+	CFRelease(t);
+	CFRelease(r);
+	return ret;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Assertion types; the kIOPMAssertionType constants are CFSTR macros,
+// which cgo cannot use.
+const (
+	assertNoDisplaySleep = "NoDisplaySleepAssertion"
+	assertNoIdleSleep    = "NoIdleSleepAssertion"
+)
+
+// powerAssertions keeps a Mac awake with IOKit power assertions, one per
+// requested flag. They are released when the process exits.
+type powerAssertions struct {
+	ids   []C.IOPMAssertionID
+	flags Flags
+}
+
+// NewInhibitor returns the Inhibitor for macOS.
+func NewInhibitor() Inhibitor {
+	return &powerAssertions{}
+}
+
+func (p *powerAssertions) Prevent(flags Flags) error {
+	if flags == p.flags {
+		return nil
+	}
+	p.Allow()
+
+	if flags&Display != 0 {
+		if err := p.assert(assertNoDisplaySleep, "Keeping the display awake"); err != nil {
+			return err
+		}
+	}
+	if flags&System != 0 {
+		if err := p.assert(assertNoIdleSleep, "Keeping the system awake"); err != nil {
+			p.Allow()
+			return err
+		}
+	}
+	p.flags = flags
+	return nil
+}
+
+func (p *powerAssertions) Allow() {
+	for _, id := range p.ids {
+		C.IOPMAssertionRelease(id)
+	}
+	p.ids = nil
+	p.flags = 0
+}
+
+func (p *powerAssertions) Held() (Flags, bool) {
+	return 0, false
+}
+
+func (p *powerAssertions) assert(kind, reason string) error {
+	ck := C.CString(kind)
+	defer C.free(unsafe.Pointer(ck))
+	cr := C.CString("Espresso: " + reason)
+	defer C.free(unsafe.Pointer(cr))
+
+	var id C.IOPMAssertionID
+	if ret := C.espressoAssert(ck, cr, &id); ret != 0 {
+		return fmt.Errorf("IOPMAssertionCreateWithName(%s) failed: 0x%x", kind, uint32(ret))
+	}
+	p.ids = append(p.ids, id)
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// freedesktopInhibitor keeps a Linux desktop awake through D-Bus: the
+// display with org.freedesktop.ScreenSaver on the session bus, and the
+// system with a systemd-logind "sleep:idle" inhibitor lock on the system
+// bus. logind holds the lock until its file descriptor is closed.
+type freedesktopInhibitor struct {
+	cookie    uint32   // ScreenSaver inhibit cookie, 0 if none
+	lock      *os.File // logind inhibitor, nil if none
+	flags     Flags
+	session   *dbus.Conn
+	systemBus *dbus.Conn
+}
+
+// NewInhibitor returns the Inhibitor for Linux desktops.
+func NewInhibitor() Inhibitor {
+	return &freedesktopInhibitor{}
+}
+
+func (f *freedesktopInhibitor) Prevent(flags Flags) error {
+	if flags == f.flags {
+		return nil
+	}
+	f.Allow()
+
+	if flags&Display != 0 {
+		if err := f.inhibitScreenSaver(); err != nil {
+			return err
+		}
+	}
+	if flags&System != 0 {
+		if err := f.inhibitSleep(); err != nil {
+			f.Allow()
+			return err
+		}
+	}
+	f.flags = flags
+	return nil
+}
+
+func (f *freedesktopInhibitor) Allow() {
+	if f.cookie != 0 {
+		obj := f.session.Object("org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver")
+		if call := obj.Call("org.freedesktop.ScreenSaver.UnInhibit", 0, f.cookie); call.Err != nil {
+			Logf("ScreenSaver.UnInhibit failed: %v", call.Err)
+		}
+		f.cookie = 0
+	}
+	if f.lock != nil {
+		f.lock.Close()
+		f.lock = nil
+	}
+	f.flags = 0
+}
+
+// Held cannot tell: neither D-Bus interface reports the combined state.
+func (f *freedesktopInhibitor) Held() (Flags, bool) {
+	return 0, false
+}
+
+func (f *freedesktopInhibitor) inhibitScreenSaver() error {
+	if f.session == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the session bus: %w", err)
+		}
+		f.session = conn
+	}
+	obj := f.session.Object("org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver")
+	if err := obj.Call("org.freedesktop.ScreenSaver.Inhibit", 0, "Espresso", "Keeping the display awake").Store(&f.cookie); err != nil {
+		return fmt.Errorf("ScreenSaver.Inhibit failed: %w", err)
+	}
+	return nil
+}
+
+func (f *freedesktopInhibitor) inhibitSleep() error {
+	if f.systemBus == nil {
+		conn, err := dbus.ConnectSystemBus()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the system bus: %w", err)
+		}
+		f.systemBus = conn
+	}
+	var fd dbus.UnixFD
+	obj := f.systemBus.Object("org.freedesktop.login1", "/org/freedesktop/login1")
+	if err := obj.Call("org.freedesktop.login1.Manager.Inhibit", 0,
+		"sleep:idle", "Espresso", "Keeping the system awake", "block").Store(&fd); err != nil {
+		return fmt.Errorf("logind Inhibit failed: %w", err)
+	}
+	f.lock = os.NewFile(uintptr(fd), "logind-inhibitor")
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	modpowrprof                 = windows.NewLazySystemDLL("powrprof.dll")
+	procSetThreadExecutionState = modkernel32.NewProc("SetThreadExecutionState")
+	procCallNtPowerInformation  = modpowrprof.NewProc("CallNtPowerInformation")
+)
+
+const (
+	ES_CONTINUOUS        = 0x80000000
+	SystemExecutionState = 16 // POWER_INFORMATION_LEVEL
+)
+
+// executionState keeps the system awake with SetThreadExecutionState. The
+// state belongs to the calling thread, so it must always be called from the
+// same locked OS thread.
+type executionState struct{}
+
+// NewInhibitor returns the Inhibitor for Windows.
+func NewInhibitor() Inhibitor {
+	return executionState{}
+}
+
+func (executionState) Allow() {
+	prev, _, _ := procSetThreadExecutionState.Call(uintptr(ES_CONTINUOUS))
+	Logf("SetThreadExecutionState(ES_CONTINUOUS): previous state 0x%08X", prev)
+}
+
+// Held reads the combined execution state of every thread on the system.
+func (executionState) Held() (Flags, bool) {
+	if procCallNtPowerInformation.Find() != nil {
+		return 0, false
+	}
+	var state uint32
+	status, _, _ := procCallNtPowerInformation.Call(SystemExecutionState, 0, 0, uintptr(unsafe.Pointer(&state)), unsafe.Sizeof(state))
+	if status != 0 {
+		Logf("CallNtPowerInformation(SystemExecutionState) failed: NTSTATUS 0x%08X", status)
+		return 0, false
+	}
+	return Flags(state), true
+}
+
+func (executionState) Prevent(flags Flags) error {
+	ret, _, err := procSetThreadExecutionState.Call(uintptr(ES_CONTINUOUS | uint32(flags)))
+	if ret == 0 {
+		Logf("SetThreadExecutionState(0x%08X) failed: %v", ES_CONTINUOUS|uint32(flags), err)
+		return fmt.Errorf("SetThreadExecutionState failed: %w", err)
+	}
+	Logf("SetThreadExecutionState(0x%08X): previous state 0x%08X", ES_CONTINUOUS|uint32(flags), ret)
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import "time"
+
+// --- Sessions ---
+
+// Mode describes a session to start.
+type Mode struct {
+	Name     string
+	Duration time.Duration // 0 or less for no time limit
+	Flags    Flags         // 0 keeps both the system and the display awake
+}
+
+// Session is a keep-awake period started from a Mode. Its end is a wall
+// clock time, so that the time the computer spends asleep counts towards
+// it, as it does for an end time the user picked.
+type Session struct {
+	Mode    Mode
+	Started time.Time
+
+	limited bool
+	end     time.Time // zero without a time limit
+	paused  bool
+	left    time.Duration // time left while paused
+}
+
+// NewSession starts a session of m at now.
+func NewSession(m Mode, now time.Time) *Session {
+	if m.Flags == 0 {
+		m.Flags = System | Display
+	}
+	s := &Session{Mode: m, Started: now, limited: m.Duration > 0}
+	if s.limited {
+		s.end = now.Round(0).Add(m.Duration)
+	}
+	return s
+}
+
+// Limited reports whether the session has a time limit.
+func (s *Session) Limited() bool {
+	return s.limited
+}
+
+// Paused reports whether the session is paused.
+func (s *Session) Paused() bool {
+	return s.paused
+}
+
+// End returns when the session runs out, and false without a time limit
+// or while paused.
+func (s *Session) End() (time.Time, bool) {
+	return s.end, s.Limited() && !s.paused
+}
+
+// Remaining returns the time left at now, and false without a time
+// limit. It is never negative.
+func (s *Session) Remaining(now time.Time) (time.Duration, bool) {
+	switch {
+	case !s.Limited():
+		return 0, false
+	case s.paused:
+		return s.left, true
+	}
+	return max(s.end.Sub(now.Round(0)), 0), true
+}
+
+// Expired reports whether a running session with a time limit has run
+// out at now.
+func (s *Session) Expired(now time.Time) bool {
+	left, ok := s.Remaining(now)
+	return ok && !s.paused && left == 0
+}
+
+// Pause stops the countdown at now.
+func (s *Session) Pause(now time.Time) {
+	if s.paused {
+		return
+	}
+	s.left, _ = s.Remaining(now)
+	s.paused = true
+}
+
+// Resume restarts the countdown at now with the time that was left.
+func (s *Session) Resume(now time.Time) {
+	if !s.paused {
+		return
+	}
+	s.paused = false
+	if s.Limited() {
+		s.end = now.Round(0).Add(s.left)
+	}
+}
+
+// EndAt makes the session run out at t, giving it a time limit if it had
+// none; its Mode.Duration becomes the time from now to t. A paused session
+// keeps that time for when it resumes.
+func (s *Session) EndAt(t, now time.Time) {
+	s.limited = true
+	s.Mode.Duration = t.Round(0).Sub(now.Round(0))
+	if s.paused {
+		s.left = max(s.Mode.Duration, 0)
+	} else {
+		s.end = t.Round(0)
+	}
+}
+
+// Extend adds d to a session with a time limit.
+func (s *Session) Extend(d time.Duration) {
+	if !s.Limited() {
+		return
+	}
+	s.Mode.Duration += d
+	if s.paused {
+		s.left += d
+	} else {
+		s.end = s.end.Add(d)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// at returns t0 plus the given number of minutes.
+func at(minutes int) time.Time {
+	return t0.Add(time.Duration(minutes) * time.Minute)
+}
+
+func TestSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    Mode
+		steps   func(s *Session)
+		now     time.Time
+		left    time.Duration // -1 without a time limit
+		expired bool
+	}{
+		{"running", Mode{Duration: time.Hour}, func(s *Session) {}, at(20), 40 * time.Minute, false},
+		{"run out", Mode{Duration: time.Hour}, func(s *Session) {}, at(60), 0, true},
+		{"past the end", Mode{Duration: time.Hour}, func(s *Session) {}, at(90), 0, true},
+		{"no limit", Mode{}, func(s *Session) {}, at(600), -1, false},
+		{"paused", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Pause(at(10))
+		}, at(120), 50 * time.Minute, false},
+		{"resumed", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Pause(at(10))
+			s.Resume(at(30))
+		}, at(40), 40 * time.Minute, false},
+		{"paused twice", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Pause(at(10))
+			s.Pause(at(20))
+			s.Resume(at(30))
+		}, at(30), 50 * time.Minute, false},
+		{"extended", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Extend(30 * time.Minute)
+		}, at(60), 30 * time.Minute, false},
+		{"extended while paused", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Pause(at(10))
+			s.Extend(30 * time.Minute)
+			s.Resume(at(20))
+		}, at(20), 80 * time.Minute, false},
+		{"extend without a limit", Mode{}, func(s *Session) {
+			s.Extend(time.Hour)
+		}, at(0), -1, false},
+		{"end at", Mode{}, func(s *Session) {
+			s.EndAt(at(90), at(30))
+		}, at(60), 30 * time.Minute, false},
+		{"end at while paused", Mode{Duration: time.Hour}, func(s *Session) {
+			s.Pause(at(10))
+			s.EndAt(at(40), at(20))
+			s.Resume(at(30))
+		}, at(30), 20 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(tt.mode, t0)
+			tt.steps(s)
+			left, ok := s.Remaining(tt.now)
+			if !ok {
+				left = -1
+			}
+			if left != tt.left {
+				t.Errorf("Remaining = %v, want %v", left, tt.left)
+			}
+			if got := s.Expired(tt.now); got != tt.expired {
+				t.Errorf("Expired = %v, want %v", got, tt.expired)
+			}
+		})
+	}
+}
+
+func TestSessionEnd(t *testing.T) {
+	s := NewSession(Mode{Duration: time.Hour}, t0)
+	if end, ok := s.End(); !ok || !end.Equal(at(60)) {
+		t.Errorf("End = %v, %v, want %v", end, ok, at(60))
+	}
+	s.Pause(at(10))
+	if _, ok := s.End(); ok {
+		t.Error("a paused session has an end")
+	}
+	if s.Mode.Flags != System|Display {
+		t.Errorf("Flags = %v, want System|Display", s.Mode.Flags)
+	}
+}
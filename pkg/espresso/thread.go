@@ -0,0 +1,59 @@
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package espresso
+
+import "runtime"
+
+// --- Locked Thread ---
+
+// Thread runs functions on one goroutine locked to its OS thread, where
+// Windows keeps an Inhibitor's request. An Engine has its own; programs
+// that call an Inhibitor themselves, like the tray app, use one for every
+// Prevent and Allow.
+type Thread struct {
+	calls chan func()
+}
+
+// NewThread starts a Thread. Close it to stop its goroutine.
+func NewThread() *Thread {
+	t := &Thread{calls: make(chan func())}
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		for fn := range t.calls {
+			fn()
+		}
+	}()
+	return t
+}
+
+// Do runs fn on the thread and waits for it.
+func (t *Thread) Do(fn func()) {
+	done := make(chan struct{})
+	t.calls <- func() {
+		fn()
+		close(done)
+	}
+	<-done
+}
+
+// Close stops the thread. It must not be used afterwards.
+func (t *Thread) Close() {
+	close(t.calls)
+}
@@ -0,0 +1,118 @@
+//go:build linux || darwin
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/getlantern/systray"
+
+	"espresso/pkg/espresso"
+)
+
+// --- Portable Tray ---
+
+// On Linux and macOS, Espresso is a minimal tray around pkg/espresso: the
+// coffee presets, Stop and Quit, with a notification when a session runs
+// out. Everything else is Windows-only.
+
+//go:embed assets/icon.png
+var iconData []byte
+
+//go:embed assets/icoff.png
+var icoffData []byte
+
+func main() {
+	espresso.Logf = log.Printf
+	systray.Run(onReady, nil)
+}
+
+func onReady() {
+	engine := espresso.NewEngine(espresso.NewInhibitor())
+
+	systray.SetIcon(icoffData)
+	systray.SetTooltip("Espresso")
+	status := systray.AddMenuItem("Decaf", "")
+	status.Disable()
+	systray.AddSeparator()
+
+	// Clicks come to this goroutine, so that the status always shows the
+	// last thing that happened.
+	picked := make(chan EspressoMode)
+	for _, m := range builtinModes {
+		item := systray.AddMenuItem(fmt.Sprintf("%s (%s)", m.Name, formatFriendlyDuration(m.Duration)), m.Desc)
+		go func() {
+			for range item.ClickedCh {
+				picked <- m
+			}
+		}()
+	}
+
+	systray.AddSeparator()
+	stop := systray.AddMenuItem("Stop", "Allow sleep again")
+	quit := systray.AddMenuItem("Quit", "Quit Espresso")
+
+	for {
+		select {
+		case m := <-picked:
+			err := engine.Start(espresso.Mode{Name: m.Name, Duration: m.Duration, Flags: espresso.System | espresso.Display})
+			if err != nil {
+				log.Printf("Failed to start %s: %v", m.Name, err)
+				showNotification("Espresso", fmt.Sprintf("Could not start %s: %v", m.Name, err))
+				continue
+			}
+			setPortableStatus(status, m.Name, m.Duration)
+		case <-stop.ClickedCh:
+			engine.Stop()
+			setPortableStatus(status, "", 0)
+		case s := <-engine.Expired():
+			if _, running := engine.Current(); running {
+				continue // another preset was picked as it ran out
+			}
+			setPortableStatus(status, "", 0)
+			showNotification("Espresso", fmt.Sprintf("%s has run out. Your computer can sleep again.", s.Mode.Name))
+		case <-quit.ClickedCh:
+			engine.Close()
+			systray.Quit()
+			return
+		}
+	}
+}
+
+// setPortableStatus shows the running mode, or Decaf if name is empty.
+func setPortableStatus(status *systray.MenuItem, name string, d time.Duration) {
+	if name == "" {
+		status.SetTitle("Decaf")
+		systray.SetIcon(icoffData)
+		systray.SetTooltip("Espresso")
+		return
+	}
+	text := fmt.Sprintf("%s until %s", name, time.Now().Add(d).Format("15:04"))
+	if d < 0 {
+		text = name + ", until stopped"
+	}
+	status.SetTitle(text)
+	systray.SetIcon(iconData)
+	systray.SetTooltip("Espresso: " + text)
+}
@@ -0,0 +1,191 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Session Preview ---
+
+// A dry run describes what a session would do, from what it keeps awake
+// to what happens when it expires, without starting it, so an overnight
+// run with an end action can be checked first: "espresso --start 8h
+// --on-end hibernate --dry-run" prints it, and Advanced > Preview Session
+// End shows it for the running session or the hotkey's mode.
+
+// sessionPlan is a session as startSession would set it up.
+type sessionPlan struct {
+	name       string
+	flags      uint32
+	simulate   string
+	onEnd      string
+	length     time.Duration // negative for no limit
+	end        time.Time
+	milestones []time.Duration // time left at each, next first
+}
+
+// planSession returns the plan of a session of mode m started at now.
+func (a *app) planSession(m EspressoMode, now time.Time) sessionPlan {
+	p := sessionPlan{
+		name:   sessionName(m),
+		flags:  m.Flags,
+		onEnd:  a.cfg.sessionOnEnd(m),
+		length: m.Duration,
+	}
+	if p.flags == 0 {
+		p.flags = a.defaultSessionFlags()
+	}
+	p.simulate, _ = parseSimulate(a.cfg.sessionSimulate(m))
+	if p.length >= 0 {
+		p.end = now.Add(p.length)
+		if !m.Until.IsZero() {
+			p.end = m.Until
+		}
+		specs := a.cfg.Milestones
+		if m.Milestones != nil {
+			specs = m.Milestones
+		}
+		p.milestones, _ = parseMilestones(specs, p.length)
+	}
+	return p
+}
+
+// currentPlan returns the plan of the running session from here on.
+func (a *app) currentPlan() sessionPlan {
+	p := sessionPlan{
+		name:       a.modeName(),
+		flags:      a.sessionFlags(),
+		simulate:   a.sessionSimulate,
+		onEnd:      a.sessionOnEnd,
+		length:     -1,
+		milestones: a.milestones,
+	}
+	if a.timed() {
+		p.end = a.sessionEnd()
+		p.length = time.Until(p.end)
+	}
+	return p
+}
+
+// previewSession describes the running session, or a session of the
+// hotkey's mode if none is running.
+func (a *app) previewSession() string {
+	if a.active() && !a.paused() {
+		return a.describePlan(a.currentPlan())
+	}
+	m, ok := a.hotkeyMode()
+	if !ok {
+		return "There is no session to preview."
+	}
+	return "No session is running. If " + m.Name + " were started now:\n\n" + a.describePlan(a.planSession(m, time.Now()))
+}
+
+// describePlan lists what p does, in the style of statusReport.
+func (a *app) describePlan(p sessionPlan) string {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, "    "+format+"\n", args...)
+	}
+
+	if p.length < 0 {
+		fmt.Fprintf(&b, "%s: no time limit\n", p.name)
+	} else {
+		fmt.Fprintf(&b, "%s: %s, until %s\n", p.name, formatFriendlyDuration(p.length), p.end.Format("Mon 15:04"))
+	}
+	line("Keeps %s awake", flagsText(p.flags))
+	if p.simulate != "" {
+		line("Simulates %s activity every %s", p.simulate, simulateInterval)
+	}
+	if a.cfg.PreventLock {
+		line("Prevents the idle lock with a display power request")
+	}
+	if a.cfg.BlockRestarts {
+		line("Asks Windows not to restart for updates")
+	}
+	if a.cfg.IgnoreLid {
+		line("Closing the lid does nothing")
+	}
+
+	if p.length < 0 {
+		if hours := a.cfg.InfiniteReminderHours; hours > 0 {
+			line("Reminds you that it is still running every %s", formatFriendlyDuration(time.Duration(hours*float64(time.Hour))))
+		}
+		if hours := a.cfg.InfiniteCapHours; hours > 0 {
+			line("After %s, ends within the grace period unless you start a new mode", formatFriendlyDuration(time.Duration(hours*float64(time.Hour))))
+		}
+		b.WriteString("\nIt runs until you stop it; nothing happens at an expiry.")
+		return b.String()
+	}
+
+	b.WriteString("\nBefore it ends:\n")
+	before := b.Len()
+	if len(p.milestones) > 0 {
+		left := make([]string, len(p.milestones))
+		for i, d := range p.milestones {
+			left[i] = formatFriendlyDuration(d)
+		}
+		line("Notifications with %s left", strings.Join(left, ", "))
+	}
+	if warn := a.cfg.expiryWarning(); warn > 0 && p.length > warn {
+		line("A notification %s before the end offers to extend it", formatFriendlyDuration(warn))
+	}
+	if a.cfg.SoftLanding {
+		line("The displays dim during the last minute")
+	}
+	if b.Len() == before {
+		line("Nothing")
+	}
+
+	fmt.Fprintf(&b, "\nWhen it ends at %s:\n", p.end.Format("15:04"))
+	if a.cfg.Overlay.FlashOnExpiry {
+		line("The overlay flashes %q", tr("overlay.finished", p.name))
+	}
+	line("A %q notification", tr("toast.finished"))
+	if p.onEnd != "" {
+		delay := a.cfg.endActionDelay()
+		if delay == 0 {
+			line("Will %s at once", p.onEnd)
+		} else {
+			line("Will %s at %s, unless cancelled from the notification or the tray menu", p.onEnd, p.end.Add(delay).Format("15:04:05"))
+		}
+		if p.onEnd == "sleep" || p.onEnd == "hibernate" {
+			line("If Espresso is not running by then, a background copy will %s at %s", p.onEnd, p.end.Add(delay+endGuardGrace).Format("15:04:05"))
+		}
+	}
+	state := indicatorIdle
+	if holders := a.triggerHolders(); len(holders) > 0 || a.quickFlags != 0 {
+		state = indicatorTriggered
+		if a.quickFlags != 0 {
+			holders = append(holders, a.quickToggleNames())
+		}
+		line("Sleep stays blocked while %s hold, as they do now", strings.Join(holders, ", "))
+	} else if p.onEnd == "" {
+		line("The PC may then sleep as usual")
+	}
+	if a.cfg.Indicator.Command != "" {
+		line("Runs %s %s", a.cfg.Indicator.Command, strings.Join(append(append([]string(nil), a.cfg.Indicator.Args...), state), " "))
+	}
+	line("Records session_end with reason \"expired\" in events.jsonl")
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,228 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Process Inspection ---
+
+var procGetProcessIoCounters = modkernel32.NewProc("GetProcessIoCounters")
+
+type processInfo struct {
+	pid  uint32
+	name string
+}
+
+// processSample is a snapshot of the cumulative resource usage of a process.
+type processSample struct {
+	cpu time.Duration // kernel + user time
+	io  uint64        // bytes read, written and transferred by other I/O
+}
+
+// ioCounters mirrors IO_COUNTERS.
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+func listProcesses() ([]processInfo, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snap)
+
+	var procs []processInfo
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		procs = append(procs, processInfo{
+			pid:  entry.ProcessID,
+			name: windows.UTF16ToString(entry.ExeFile[:]),
+		})
+	}
+	return procs, nil
+}
+
+// findProcesses returns the running processes whose executable name matches
+// one of names, ignoring case.
+func findProcesses(procs []processInfo, names ...string) []processInfo {
+	var found []processInfo
+	for _, p := range procs {
+		for _, n := range names {
+			if strings.EqualFold(p.name, n) {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func sampleProcess(pid uint32) (processSample, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return processSample{}, err
+	}
+	defer windows.CloseHandle(h)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return processSample{}, err
+	}
+
+	var counters ioCounters
+	if r, _, err := procGetProcessIoCounters.Call(uintptr(h), uintptr(unsafe.Pointer(&counters))); r == 0 {
+		return processSample{}, err
+	}
+
+	return processSample{
+		cpu: filetimeDuration(kernel) + filetimeDuration(user),
+		io:  counters.ReadTransferCount + counters.WriteTransferCount + counters.OtherTransferCount,
+	}, nil
+}
+
+// filetimeDuration converts a FILETIME holding an interval (in 100ns units).
+func filetimeDuration(ft windows.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
+
+// --- Process Activity ---
+
+// processGroup is a named set of executables whose activity is tracked
+// together, such as all the processes of one sync client.
+type processGroup struct {
+	key       string // identifier accepted in settings.json
+	name      string // display name
+	processes []string
+}
+
+// selectGroups returns the groups named by keys, or all of them if keys is
+// empty.
+func selectGroups(all []processGroup, keys []string) ([]processGroup, error) {
+	if len(keys) == 0 {
+		return all, nil
+	}
+	var selected []processGroup
+	for _, key := range keys {
+		found := false
+		for _, g := range all {
+			if strings.EqualFold(g.key, key) {
+				selected = append(selected, g)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown client %q", key)
+		}
+	}
+	return selected, nil
+}
+
+// activityMonitor infers whether groups of processes are busy from their
+// disk/network I/O and CPU use between polls. A group stays busy until it
+// has been quiet for the cooldown, so short pauses do not count as done.
+type activityMonitor struct {
+	ioThreshold  float64 // bytes per second, 0 to ignore I/O
+	cpuThreshold float64 // percent of total CPU, 0 to ignore CPU
+	cooldown     time.Duration
+
+	samples    map[uint32]processSample
+	sampledAt  time.Time
+	lastActive map[string]time.Time
+}
+
+// newActivityMonitor uses the thresholds and cooldown from tc, falling back
+// to the given defaults for the ones left unset.
+func newActivityMonitor(tc TriggerConfig, ioThresholdKB int, cpuThreshold float64, cooldown time.Duration) *activityMonitor {
+	if tc.IOThresholdKB > 0 {
+		ioThresholdKB = tc.IOThresholdKB
+	}
+	if tc.CPUThreshold > 0 {
+		cpuThreshold = tc.CPUThreshold
+	}
+	return &activityMonitor{
+		ioThreshold:  float64(ioThresholdKB) * 1024,
+		cpuThreshold: cpuThreshold,
+		cooldown:     tc.cooldown(cooldown),
+		samples:      make(map[uint32]processSample),
+		lastActive:   make(map[string]time.Time),
+	}
+}
+
+// poll samples the processes of every group and returns the display names
+// of the groups that are busy.
+func (m *activityMonitor) poll(groups []processGroup) ([]string, error) {
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(m.sampledAt).Seconds()
+	samples := make(map[uint32]processSample)
+
+	var busy []string
+	for _, g := range groups {
+		var ioBytes uint64
+		var cpu time.Duration
+		for _, p := range findProcesses(procs, g.processes...) {
+			s, err := sampleProcess(p.pid)
+			if err != nil {
+				continue
+			}
+			samples[p.pid] = s
+			// Processes seen for the first time have no baseline yet.
+			if prev, ok := m.samples[p.pid]; ok && s.io >= prev.io && s.cpu >= prev.cpu {
+				ioBytes += s.io - prev.io
+				cpu += s.cpu - prev.cpu
+			}
+		}
+
+		if !m.sampledAt.IsZero() && elapsed > 0 {
+			ioRate := float64(ioBytes) / elapsed
+			cpuPercent := cpu.Seconds() / elapsed / float64(runtime.NumCPU()) * 100
+			if (m.ioThreshold > 0 && ioRate >= m.ioThreshold) || (m.cpuThreshold > 0 && cpuPercent >= m.cpuThreshold) {
+				m.lastActive[g.key] = now
+			}
+		}
+
+		if last, ok := m.lastActive[g.key]; ok && now.Sub(last) < m.cooldown {
+			busy = append(busy, g.name)
+		}
+	}
+
+	m.samples = samples
+	m.sampledAt = now
+	return busy, nil
+}
@@ -0,0 +1,391 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"slices"
+	"time"
+	"unsafe"
+
+	"github.com/getlantern/systray"
+	"golang.org/x/sys/windows"
+)
+
+// --- Profiles ---
+
+// Profiles are named sets of settings for different ways of using the PC,
+// such as "Work" at the docking station and "Couch" on battery. Each has
+// its own hotkey mode and adds its own schedules and triggers to those at
+// the top level of settings.json. The Profile menu picks one, or
+// Automatic, which picks the first profile whose Wi-Fi networks and
+// domain condition match the network the PC is on.
+
+const (
+	profileAuto          = "auto"
+	profileCheckInterval = 30 * time.Second
+)
+
+// ProfileConfig is one entry of "profiles" in settings.json.
+type ProfileConfig struct {
+	Name string `json:"name"`
+
+	// Mode is the preset the hotkey starts while the profile is active,
+	// instead of hotkey.mode.
+	Mode string `json:"mode,omitempty"`
+
+	// Schedules and Triggers are added to the top-level ones.
+	Schedules []ScheduleConfig `json:"schedules,omitempty"`
+	Triggers  []TriggerConfig  `json:"triggers,omitempty"`
+
+	// SSIDs and Domain are what Automatic matches: the Wi-Fi networks the
+	// profile is for, and "on" or "off" for whether a domain controller
+	// must be reachable, as for triggers. A profile with neither is never
+	// picked automatically.
+	SSIDs  []string `json:"ssids,omitempty"`
+	Domain string   `json:"domain,omitempty"`
+}
+
+// networkState is what Automatic looks at.
+type networkState struct {
+	ssid     string // "" when not connected to Wi-Fi
+	onDomain bool
+}
+
+// matches reports whether the profile is meant for network n.
+func (p ProfileConfig) matches(n networkState) bool {
+	if len(p.SSIDs) == 0 && p.Domain == "" {
+		return false
+	}
+	if len(p.SSIDs) > 0 && !slices.Contains(p.SSIDs, n.ssid) {
+		return false
+	}
+	switch p.Domain {
+	case "on":
+		return n.onDomain
+	case "off":
+		return !n.onDomain
+	default:
+		return true
+	}
+}
+
+// findProfile returns the profile named name, or nil.
+func (cfg Config) findProfile(name string) *ProfileConfig {
+	for i := range cfg.Profiles {
+		if cfg.Profiles[i].Name == name {
+			return &cfg.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// triggerSetup returns what startTriggers needs while profile p, which
+// may be nil, is active.
+func (cfg Config) triggerSetup(p *ProfileConfig) triggerSetup {
+	s := triggerSetup{
+		triggers:       cfg.Triggers,
+		places:         cfg.Places,
+		locationAccess: granted(cfg.LocationAccess),
+		calendarAccess: granted(cfg.CalendarAccess),
+	}
+	if p != nil {
+		s.triggers = append(slices.Clip(s.triggers), p.Triggers...)
+	}
+	return s
+}
+
+// allTriggers returns the top-level triggers and those of every profile.
+func (cfg Config) allTriggers() []TriggerConfig {
+	all := slices.Clip(cfg.Triggers)
+	for _, p := range cfg.Profiles {
+		all = append(all, p.Triggers...)
+	}
+	return all
+}
+
+// activeProfile returns the profile in effect, or nil.
+func (a *app) activeProfile() *ProfileConfig {
+	return a.cfg.findProfile(a.profile)
+}
+
+// selectProfile works out which profile is in effect and starts its
+// schedules and triggers. notify announces a change, for Automatic
+// switching on its own.
+func (a *app) selectProfile(notify bool) {
+	name := a.cfg.Profile
+	switch {
+	case name == profileAuto:
+		a.watchNetwork()
+		name = a.cfg.autoProfile(a.network)
+	case name != "" && a.cfg.findProfile(name) == nil:
+		logWarnf("unknown profile %q", name)
+		name = ""
+	}
+
+	if name != a.profile {
+		a.profile = name
+		logInfof("Profile: %s", a.profileName())
+		if notify {
+			go showToast(tagSession, tr("profile.switched", a.profileName()), tr("profile.switched_auto"), iconPath())
+		}
+	}
+	p := a.activeProfile()
+	a.schedules = a.cfg.schedules(p)
+	if a.cfg.feature(featureTriggers) {
+		a.restartTriggers(a.cfg.triggerSetup(p))
+	}
+	a.profileMenu.update(a.cfg, a.profile)
+}
+
+// autoProfile returns the name of the profile Automatic picks on network
+// n, or "" if none matches.
+func (cfg Config) autoProfile(n networkState) string {
+	for _, p := range cfg.Profiles {
+		if p.matches(n) {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// schedules returns the schedules in effect while profile p, which may be
+// nil, is active: none if the triggers feature is off.
+func (cfg Config) schedules(p *ProfileConfig) []schedule {
+	if !cfg.feature(featureTriggers) {
+		return nil
+	}
+	configs := slices.Clip(cfg.Schedules)
+	if p != nil {
+		configs = append(configs, p.Schedules...)
+	}
+	return startSchedules(configs)
+}
+
+// profileName is the name of the profile in effect for display.
+func (a *app) profileName() string {
+	if a.profile == "" {
+		return tr("menu.profile.none")
+	}
+	return a.profile
+}
+
+// setProfile picks a profile from the menu: a name, profileAuto or "".
+func (a *app) setProfile(name string) {
+	a.cfg.Profile = name
+	a.saveConfig()
+	a.selectProfile(false)
+}
+
+// watchNetwork starts reporting the network state to the main loop, once.
+func (a *app) watchNetwork() {
+	if a.networkCh != nil {
+		return
+	}
+	ch := make(chan networkState)
+	a.networkCh = ch
+	go func() {
+		var last networkState
+		first := true
+		for {
+			n := networkState{ssid: currentSSID(), onDomain: onDomainNetwork()}
+			if first || n != last {
+				ch <- n
+				last, first = n, false
+			}
+			time.Sleep(profileCheckInterval)
+		}
+	}()
+}
+
+// handleNetwork records a new network state and switches profile if
+// Automatic says so.
+func (a *app) handleNetwork(n networkState) {
+	known := a.networkKnown
+	a.network, a.networkKnown = n, true
+	if a.cfg.Profile == profileAuto {
+		a.selectProfile(known)
+	}
+}
+
+// --- Profile Menu ---
+
+// profileMenu is the Profile submenu: None, Automatic and one item per
+// profile. Items for profiles added by a reload are appended to the
+// submenu; items of removed ones are hidden.
+type profileMenu struct {
+	root    *systray.MenuItem
+	none    *systray.MenuItem
+	auto    *systray.MenuItem
+	slots   []*systray.MenuItem
+	clickCh chan int // index into Config.Profiles, or profileNone or profileAutomatic
+}
+
+const (
+	profileNone      = -1
+	profileAutomatic = -2
+)
+
+func newProfileMenu() *profileMenu {
+	m := &profileMenu{clickCh: make(chan int)}
+	m.root = addMenuItem(nil, "menu.profile")
+	m.none = m.addItem(profileNone)
+	m.auto = m.addItem(profileAutomatic)
+	return m
+}
+
+func (m *profileMenu) addItem(i int) *systray.MenuItem {
+	item := m.root.AddSubMenuItemCheckbox("", "", false)
+	go func() {
+		for range item.ClickedCh {
+			m.clickCh <- i
+		}
+	}()
+	return item
+}
+
+// update shows the profiles of cfg and checks the one picked in it;
+// active is the profile in effect.
+func (m *profileMenu) update(cfg Config, active string) {
+	if len(cfg.Profiles) == 0 && cfg.Profile == "" {
+		m.root.Hide()
+	} else {
+		m.root.Show()
+	}
+	for len(m.slots) < len(cfg.Profiles) {
+		m.slots = append(m.slots, m.addItem(len(m.slots)))
+	}
+	for i, slot := range m.slots {
+		if i >= len(cfg.Profiles) {
+			slot.Hide()
+			continue
+		}
+		slot.SetTitle(cfg.Profiles[i].Name)
+		setChecked(slot, cfg.Profile == cfg.Profiles[i].Name)
+		slot.Show()
+	}
+	m.none.SetTitle(tr("menu.profile.none"))
+	m.none.SetTooltip(tr("menu.profile.none.tip"))
+	setChecked(m.none, cfg.Profile == "")
+	if cfg.Profile == profileAuto && active != "" {
+		m.auto.SetTitle(tr("menu.profile.auto_active", active))
+	} else {
+		m.auto.SetTitle(tr("menu.profile.auto"))
+	}
+	m.auto.SetTooltip(tr("menu.profile.auto.tip"))
+	setChecked(m.auto, cfg.Profile == profileAuto)
+}
+
+// profileClicked maps a click on the Profile menu to the name setProfile
+// takes.
+func (a *app) profileClicked(i int) (string, bool) {
+	switch {
+	case i == profileNone:
+		return "", true
+	case i == profileAutomatic:
+		return profileAuto, true
+	case i < len(a.cfg.Profiles):
+		return a.cfg.Profiles[i].Name, true
+	default:
+		return "", false
+	}
+}
+
+// --- Wi-Fi Network ---
+
+var (
+	modwlanapi             = windows.NewLazySystemDLL("wlanapi.dll")
+	procWlanOpenHandle     = modwlanapi.NewProc("WlanOpenHandle")
+	procWlanCloseHandle    = modwlanapi.NewProc("WlanCloseHandle")
+	procWlanEnumInterfaces = modwlanapi.NewProc("WlanEnumInterfaces")
+	procWlanQueryInterface = modwlanapi.NewProc("WlanQueryInterface")
+	procWlanFreeMemory     = modwlanapi.NewProc("WlanFreeMemory")
+)
+
+const (
+	wlan_interface_state_connected      = 1
+	wlan_intf_opcode_current_connection = 7
+)
+
+// WLAN_INTERFACE_INFO
+type wlanInterfaceInfo struct {
+	InterfaceGuid windows.GUID
+	Description   [256]uint16
+	State         uint32
+}
+
+// WLAN_INTERFACE_INFO_LIST, with room for the first interface
+type wlanInterfaceInfoList struct {
+	NumberOfItems uint32
+	Index         uint32
+	InterfaceInfo [1]wlanInterfaceInfo
+}
+
+// The start of WLAN_CONNECTION_ATTRIBUTES, up to the DOT11_SSID of its
+// association attributes.
+type wlanConnectionAttributes struct {
+	State          uint32
+	ConnectionMode uint32
+	ProfileName    [256]uint16
+	SSIDLength     uint32
+	SSID           [32]byte
+}
+
+// currentSSID returns the name of the Wi-Fi network the PC is connected
+// to, or "" if none. Windows 11 only reveals it to apps allowed to use the
+// location; otherwise it is "" too.
+func currentSSID() string {
+	if !procAvailable(procWlanOpenHandle) {
+		return ""
+	}
+	var version uint32
+	var h windows.Handle
+	if r, _, _ := procWlanOpenHandle.Call(2, 0, uintptr(unsafe.Pointer(&version)), uintptr(unsafe.Pointer(&h))); r != 0 {
+		return "" // no WLAN service, as on desktops without Wi-Fi
+	}
+	defer procWlanCloseHandle.Call(uintptr(h), 0)
+
+	var list *wlanInterfaceInfoList
+	if r, _, _ := procWlanEnumInterfaces.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&list))); r != 0 {
+		return ""
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(list)))
+
+	infos := unsafe.Slice(&list.InterfaceInfo[0], list.NumberOfItems)
+	for i := range infos {
+		if infos[i].State != wlan_interface_state_connected {
+			continue
+		}
+		var size uint32
+		var attrs *wlanConnectionAttributes
+		r, _, _ := procWlanQueryInterface.Call(uintptr(h), uintptr(unsafe.Pointer(&infos[i].InterfaceGuid)),
+			wlan_intf_opcode_current_connection, 0, uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&attrs)), 0)
+		if r != 0 {
+			continue
+		}
+		n := min(attrs.SSIDLength, uint32(len(attrs.SSID)))
+		ssid := string(attrs.SSID[:n])
+		procWlanFreeMemory.Call(uintptr(unsafe.Pointer(attrs)))
+		if ssid != "" {
+			return ssid
+		}
+	}
+	return ""
+}
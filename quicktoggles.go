@@ -0,0 +1,111 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/getlantern/systray"
+)
+
+// --- Quick Toggles ---
+
+// Keep Display On and Keep System Awake are toggles, independent of modes
+// and sessions, that hold a single execution state flag. Keep Display On
+// (ES_DISPLAY_REQUIRED) keeps only the screen on, e.g. while reading a long
+// document on battery; closing the lid or the battery policy can still put
+// the PC to sleep. Keep System Awake (ES_SYSTEM_REQUIRED) lets the screen
+// turn off during downloads or server work, and shows a slate cup so that
+// the dark screen is not mistaken for sleep. They combine with sessions
+// and triggers and last until turned off or Espresso exits.
+
+// quickToggles are the toggles in menu order.
+var quickToggles = []struct {
+	key  string
+	flag uint32
+}{
+	{"display_only", ES_DISPLAY_REQUIRED},
+	{"system_only", ES_SYSTEM_REQUIRED},
+}
+
+var systemOnlyTint = [3]byte{0x5A, 0x6E, 0x8C} // RGB, slate
+
+// systemOnlyIcon is the cup tinted for Keep System Awake.
+var systemOnlyIcon = sync.OnceValue(func() []byte {
+	ico, err := tintIcon(iconData, systemOnlyTint)
+	if err != nil {
+		logWarnf("could not draw the Keep System Awake icon: %v", err)
+		return iconData
+	}
+	return ico
+})
+
+// setQuickToggle turns the toggle holding flag on or off.
+func (a *app) setQuickToggle(flag uint32, on bool) {
+	if on == (a.quickFlags&flag != 0) {
+		return
+	}
+	if on {
+		a.quickFlags |= flag
+	} else {
+		a.quickFlags &^= flag
+	}
+	logInfof("quick toggles now hold 0x%08X", a.quickFlags)
+	a.applyState()
+	a.updateStatus()
+}
+
+// quickToggleNames lists the toggles that are on, by their menu names.
+func (a *app) quickToggleNames() string {
+	var names []string
+	for _, t := range quickToggles {
+		if a.quickFlags&t.flag != 0 {
+			names = append(names, tr("menu."+t.key))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// cupIconFor returns the active icon when nothing but the toggles holds:
+// the slate cup for Keep System Awake alone, otherwise the usual cup.
+func (a *app) cupIconFor() []byte {
+	session := a.active() && !a.paused()
+	if !session && a.triggerFlags() == 0 && a.quickFlags == ES_SYSTEM_REQUIRED {
+		return systemOnlyIcon()
+	}
+	return a.cupIcon
+}
+
+// addQuickToggles adds the toggles to the menu; clicks are sent on ch.
+func addQuickToggles(ch chan<- uint32) map[uint32]*systray.MenuItem {
+	items := make(map[uint32]*systray.MenuItem)
+	for _, t := range quickToggles {
+		item := addMenuCheckbox(nil, "menu."+t.key, false)
+		items[t.flag] = item
+		go func() {
+			for range item.ClickedCh {
+				ch <- t.flag
+			}
+		}()
+	}
+	return items
+}
@@ -0,0 +1,201 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+// --- Settings Reload ---
+
+// settings.json is read again when it changes on disk, or from Advanced >
+// Reload Settings, so that edits take effect without a restart. Espresso's
+// own saves are recognized by their content and ignored, and a file that
+// does not parse leaves the current settings in effect. Settings that are
+// only read at startup are listed in the notification as needing a
+// restart.
+
+const (
+	settingsPollInterval = 2 * time.Second
+
+	// spareModeSlots are hidden mode items for modes added by a reload.
+	spareModeSlots = 8
+)
+
+// settingsChangedCh tells the main loop that settings.json was modified.
+var settingsChangedCh = make(chan struct{}, 1)
+
+// watchSettings polls the modification time of settings.json.
+func watchSettings() {
+	p := settingsPath()
+	var last time.Time
+	if fi, err := os.Stat(p); err == nil {
+		last = fi.ModTime()
+	}
+	for range time.Tick(settingsPollInterval) {
+		fi, err := os.Stat(p)
+		if err != nil || fi.ModTime().Equal(last) {
+			continue
+		}
+		last = fi.ModTime()
+		select {
+		case settingsChangedCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// modeMenu is the list of modes in the tray menu. systray cannot insert
+// items, so there are a few spare slots, hidden while unused, for modes
+// added by a reload.
+type modeMenu struct {
+	slots   []*systray.MenuItem
+	clickCh chan int // index into modes
+}
+
+func newModeMenu(list []EspressoMode) *modeMenu {
+	m := &modeMenu{clickCh: make(chan int)}
+	for i := range len(list) + spareModeSlots {
+		item := systray.AddMenuItem("", "")
+		go func() {
+			for range item.ClickedCh {
+				m.clickCh <- i
+			}
+		}()
+		m.slots = append(m.slots, item)
+	}
+	m.update(list)
+	return m
+}
+
+// update shows list and reports whether there was a slot for every mode.
+func (m *modeMenu) update(list []EspressoMode) bool {
+	for i, slot := range m.slots {
+		if i < len(list) {
+			mode := list[i]
+			slot.SetTitle(fmt.Sprintf("%s (%s)", mode.Name, formatFriendlyDuration(mode.Duration)))
+			slot.SetTooltip(mode.Desc)
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+	return len(list) <= len(m.slots)
+}
+
+// reloadConfig reads settings.json again and applies it with applyConfig.
+// The caller updates the check marks of the menu when it returns true.
+// Unless manual is set, nothing happens if the file holds what Espresso
+// saved last.
+func (a *app) reloadConfig(manual bool) bool {
+	p := settingsPath()
+	data, err := os.ReadFile(p)
+	if err == nil {
+		err = checkConfigWriters(p)
+	}
+	if err != nil {
+		a.reloadFailed(err)
+		return false
+	}
+	if own, err := configData(a.cfg); !manual && err == nil && bytes.Equal(own, data) {
+		return false
+	}
+	cfg, _, err := parseConfig(data)
+	if err != nil {
+		a.reloadFailed(err)
+		return false
+	}
+
+	restart := a.applyConfig(cfg)
+	logInfof("settings reloaded from %s", p)
+	msg := tr("reload.done")
+	if len(restart) > 0 {
+		msg = tr("reload.restart", strings.Join(restart, ", "))
+	}
+	go showToast(tagSession, tr("reload.title"), msg, iconPath())
+	return true
+}
+
+// applyConfig replaces the settings with cfg and applies them, except for
+// the check marks of the menu. It returns the settings.json keys that
+// changed but need a restart.
+func (a *app) applyConfig(cfg Config) []string {
+	old := a.cfg
+	a.cfg = cfg
+	setLogLevel(cfg.LogLevel)
+	setNotificationStyle(cfg.Notifications)
+	setLanguage(cfg.Language)
+	relabelMenu()
+	cfg.warnDisabledFeatures()
+
+	restart := restartSettings(old, cfg)
+	modes = mergeModes(cfg.Modes, cfg.ReplaceBuiltinModes)
+	if !a.modeMenu.update(modes) {
+		restart = append(restart, "modes")
+	}
+	a.tokenMenu.update(cfg.APITokens)
+	updateSettingsTokens(cfg.APITokens)
+	a.askAccess()
+	a.selectProfile(false)
+	if cfg.StartWithWindows != old.StartWithWindows {
+		go syncAutostart(cfg.StartWithWindows)
+	}
+	a.loadCupIcon()
+	a.applyState()
+
+	if len(restart) > 0 {
+		logInfof("restart to apply changes to: %s", strings.Join(restart, ", "))
+	}
+	return restart
+}
+
+func (a *app) reloadFailed(err error) {
+	logWarnf("settings not reloaded: %v", err)
+	go showToast(tagSession, tr("reload.failed_title"), tr("reload.failed", err), icoffPath())
+}
+
+// restartSettings lists the settings.json keys that changed between old
+// and cfg but are only read at startup.
+func restartSettings(old, cfg Config) []string {
+	var keys []string
+	for _, s := range []struct {
+		key      string
+		old, new any
+	}{
+		{"features", old.Features, cfg.Features},
+		{"api", old.API, cfg.API},
+		{"hotkey", [2]any{old.Hotkey.Keys, old.Hotkey.Disabled}, [2]any{cfg.Hotkey.Keys, cfg.Hotkey.Disabled}},
+		{"indicator", old.Indicator, cfg.Indicator},
+		{"discord", old.Discord, cfg.Discord},
+	} {
+		if !reflect.DeepEqual(s.old, s.new) {
+			keys = append(keys, s.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,186 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Scheduled Windows ---
+
+// ScheduleConfig is a recurring keep-awake window in settings.json, e.g.
+// Mon–Fri 09:00–17:30. A window whose end is before its start runs past
+// midnight.
+type ScheduleConfig struct {
+	Name     string   `json:"name,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+	Days     []string `json:"days"`  // "mon".."sun", ranges like "mon-fri", "weekdays", "weekends" or "daily"
+	Start    string   `json:"start"` // "09:00"
+	End      string   `json:"end"`   // "17:30"
+	Keep     []string `json:"keep,omitempty"`
+}
+
+type schedule struct {
+	name       string
+	days       [7]bool // indexed by time.Weekday
+	start, end time.Duration
+	flags      uint32
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func parseSchedule(sc ScheduleConfig) (schedule, error) {
+	s := schedule{name: sc.Name}
+	if s.name == "" {
+		s.name = fmt.Sprintf("%s–%s", sc.Start, sc.End)
+	}
+
+	var err error
+	if s.start, err = parseClock(sc.Start); err != nil {
+		return s, err
+	}
+	if s.end, err = parseClock(sc.End); err != nil {
+		return s, err
+	}
+	if s.start == s.end {
+		return s, fmt.Errorf("start and end are both %s", sc.Start)
+	}
+	if s.flags, err = parseKeep(sc.Keep); err != nil {
+		return s, err
+	}
+
+	if len(sc.Days) == 0 {
+		return s, fmt.Errorf("no days given")
+	}
+	for _, d := range sc.Days {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch d {
+		case "daily":
+			d = "sun-sat"
+		case "weekdays":
+			d = "mon-fri"
+		case "weekends":
+			d = "sat-sun"
+		}
+		from, to, isRange := strings.Cut(d, "-")
+		first, ok1 := weekdayIndex(from)
+		last, ok2 := first, true
+		if isRange {
+			last, ok2 = weekdayIndex(to)
+		}
+		if !ok1 || !ok2 {
+			return s, fmt.Errorf("unknown day %q", d)
+		}
+		for i := first; ; i = (i + 1) % 7 {
+			s.days[i] = true
+			if i == last {
+				break
+			}
+		}
+	}
+	return s, nil
+}
+
+func weekdayIndex(name string) (int, bool) {
+	for i, n := range weekdayNames {
+		if strings.HasPrefix(name, n) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// parseClock parses "hh:mm" into the time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use hh:mm", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// window returns the occurrence of s that contains now, if any. A window
+// belongs to the day it starts on.
+func (s schedule) window(now time.Time) (start, end time.Time, ok bool) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		if !s.days[day.Weekday()] {
+			continue
+		}
+		start = atClock(day, s.start)
+		if s.end < s.start {
+			end = atClock(day.AddDate(0, 0, 1), s.end)
+		} else {
+			end = atClock(day, s.end)
+		}
+		if !now.Before(start) && now.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// atClock returns the time of day clock on the day of day. Adding clock to
+// midnight would be an hour off on the days daylight saving time changes.
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+func startSchedules(configs []ScheduleConfig) []schedule {
+	var schedules []schedule
+	for _, sc := range configs {
+		if sc.Disabled {
+			continue
+		}
+		s, err := parseSchedule(sc)
+		if err != nil {
+			logWarnf("skipping schedule %s: %v", sc.Name, err)
+			continue
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules
+}
+
+// checkSchedules starts a "Scheduled" session when a window opens. Each
+// window starts at most one session, so stopping it early sticks until the
+// next window, and a manual session that is already running is left alone.
+func (a *app) checkSchedules() {
+	if len(a.scheduleStarted) != len(a.schedules) {
+		a.scheduleStarted = make([]time.Time, len(a.schedules))
+	}
+	now := time.Now()
+	for i, s := range a.schedules {
+		start, end, ok := s.window(now)
+		if !ok || a.scheduleStarted[i].Equal(start) {
+			continue
+		}
+		a.scheduleStarted[i] = start
+		if a.active() {
+			continue
+		}
+
+		a.startSession(EspressoMode{Name: "Scheduled", Duration: end.Sub(now).Round(time.Second), Flags: s.flags}, "schedule "+s.name)
+		go showToast(tagSession, tr("schedule.started_title"), tr("schedule.started", s.name, end.Format("15:04")), iconPath())
+	}
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleWindowDST(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip(err)
+	}
+	s, err := parseSchedule(ScheduleConfig{Days: []string{"daily"}, Start: "09:00", End: "01:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Clocks go forward at 02:00 on 30 March 2025 and back at 03:00 on
+	// 26 October 2025.
+	for _, now := range []time.Time{
+		time.Date(2025, time.March, 30, 12, 0, 0, 0, madrid),
+		time.Date(2025, time.October, 26, 12, 0, 0, 0, madrid),
+	} {
+		start, end, ok := s.window(now)
+		if !ok {
+			t.Fatalf("%s: not in the window", now)
+		}
+		if want := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, madrid); !start.Equal(want) {
+			t.Errorf("%s: window starts at %s, want %s", now, start, want)
+		}
+		if want := time.Date(now.Year(), now.Month(), now.Day()+1, 1, 30, 0, 0, madrid); !end.Equal(want) {
+			t.Errorf("%s: window ends at %s, want %s", now, end, want)
+		}
+	}
+}
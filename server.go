@@ -0,0 +1,88 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Windows Server ---
+
+// On Windows Server, toast notifications usually go nowhere (Server Core
+// has no notification center, and the desktop experience hides them by
+// default), and a lab server has no use for keeping its display on. There
+// Espresso notifies with balloon tips and entries in the Application event
+// log instead, and manual sessions keep only the system awake unless the
+// Keep Awake menu says otherwise.
+
+const VER_NT_WORKSTATION = 1
+
+const eventSourceName = "Espresso"
+
+// isServer reports whether this is a Server SKU, including domain
+// controllers.
+var isServer = sync.OnceValue(func() bool {
+	return windows.RtlGetVersion().ProductType != VER_NT_WORKSTATION
+})
+
+// useToasts is false when notifications go to balloons and the event log.
+var useToasts atomic.Bool
+
+// setNotificationStyle applies the notifications setting: "toast",
+// "balloon", or empty for balloons on Windows Server and toasts elsewhere.
+func setNotificationStyle(style string) {
+	switch strings.ToLower(style) {
+	case "toast":
+		useToasts.Store(true)
+	case "balloon":
+		useToasts.Store(false)
+	default:
+		if style != "" {
+			logWarnf("unknown notifications setting %q (use toast or balloon)", style)
+		}
+		useToasts.Store(!isServer())
+	}
+	if isServer() {
+		logInfof("Windows Server detected; notifications use %s", map[bool]string{true: "toasts", false: "balloons and the event log"}[useToasts.Load()])
+	}
+}
+
+// reportEvent writes a notification to the Application event log, where
+// server administrators and monitoring tools look.
+func reportEvent(title, message string) {
+	source, _ := windows.UTF16PtrFromString(eventSourceName)
+	h, err := windows.RegisterEventSource(nil, source)
+	if err != nil {
+		logWarnf("could not open the event log: %v", err)
+		return
+	}
+	defer windows.DeregisterEventSource(h)
+
+	text, _ := windows.UTF16PtrFromString(title + ": " + message)
+	strs := []*uint16{text}
+	if err := windows.ReportEvent(h, windows.EVENTLOG_INFORMATION_TYPE, 0, 1, 0, 1, 0, &strs[0], nil); err != nil {
+		logWarnf("could not write to the event log: %v", err)
+	}
+}
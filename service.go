@@ -0,0 +1,425 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"espresso/pkg/espresso"
+)
+
+// --- Service Mode ---
+
+// "espresso --service" runs as a Windows service that keeps the system
+// (not the displays) awake, with no tray and no one logged in, for kiosk
+// and lab machines. It reads the settings.json of the user who installed
+// it every servicePoll: around the clock by default, or only during the
+// schedules with "service": {"schedules": true}. "espresso --service
+// install|uninstall|start|stop" manage it from an elevated prompt.
+
+const (
+	serviceName        = "Espresso"
+	serviceDisplayName = "Espresso"
+	serviceDescription = "Keeps this computer awake for unattended use, as configured in Espresso's settings.json."
+
+	serviceKey = `SYSTEM\CurrentControlSet\Services\` + serviceName
+
+	servicePoll        = 30 * time.Second
+	serviceStopTimeout = 20 * time.Second
+)
+
+// ServiceConfig is the "service" section of settings.json.
+type ServiceConfig struct {
+	// Schedules keeps the system awake only during the schedules instead
+	// of around the clock.
+	Schedules bool `json:"schedules,omitempty"`
+}
+
+// runServiceCommand runs "espresso --service" with the arguments after
+// "--service" and returns the process exit code.
+func runServiceCommand(args []string) int {
+	if len(args) == 0 {
+		return runService()
+	}
+	attachConsole()
+	commands := map[string]func() error{
+		"install":   installService,
+		"uninstall": uninstallService,
+		"start":     startService,
+		"stop":      stopService,
+	}
+	run, ok := commands[args[0]]
+	if !ok || len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: espresso --service [install|uninstall|start|stop]")
+		return 2
+	}
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "espresso --service %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// runService is what the service control manager starts.
+func runService() int {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		attachConsole()
+		fmt.Fprintln(os.Stderr, "espresso --service: not started by the service control manager; use \"espresso --service install\" and \"espresso --service start\"")
+		return 2
+	}
+	// The service's settings live in a folder the installing user
+	// controls, so SYSTEM must not write there; it logs to the event log.
+	source, _ := windows.UTF16PtrFromString(eventSourceName)
+	if logEvents, err = windows.RegisterEventSource(nil, source); err != nil {
+		return 1
+	}
+	defer windows.DeregisterEventSource(logEvents)
+	if err := svc.Run(serviceName, espressoService{}); err != nil {
+		logErrorf("service: %v", err)
+		return 1
+	}
+	return 0
+}
+
+type espressoService struct{}
+
+func (espressoService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	// Managed mode checks settings.json against its owner, not SYSTEM.
+	owner, err := serviceConfigOwner()
+	if err != nil && managedMode() {
+		logErrorf("service: %v; reinstall it with \"espresso --service install\"", err)
+		return true, 1
+	}
+	configOwner = owner
+	engine := espresso.NewEngine(sleeper)
+	defer engine.Close()
+	w := serviceWaker{engine: engine}
+	w.update(time.Now())
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	logInfof("service: started")
+
+	ticker := time.NewTicker(servicePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.update(now)
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				engine.Stop()
+				logInfof("service: stopped")
+				return false, 0
+			}
+		}
+	}
+}
+
+// serviceWaker starts and stops the service's session as settings.json
+// and the clock say.
+type serviceWaker struct {
+	engine  *espresso.Engine
+	reason  string // why the system is kept awake, "" if it is not
+	lastErr string
+
+	// data and profile are what schedules were built from, so that a
+	// broken schedule is reported once rather than every servicePoll.
+	data      []byte
+	profile   string
+	schedules []schedule
+}
+
+func (w *serviceWaker) update(now time.Time) {
+	data, err := readServiceConfig()
+	var cfg Config
+	if err == nil {
+		cfg, _, err = parseConfig(data)
+	}
+	if err != nil {
+		// Keep doing what we did; an unattended machine should not fall
+		// asleep over a half-saved file.
+		if err.Error() != w.lastErr {
+			logWarnf("service: %v", err)
+			w.lastErr = err.Error()
+		}
+		return
+	}
+	w.lastErr = ""
+	setLogLevel(cfg.LogLevel)
+
+	reason := "always on"
+	if cfg.Service.Schedules {
+		profile := cfg.Profile
+		if profile == profileAuto {
+			profile = cfg.autoProfile(networkState{ssid: currentSSID(), onDomain: onDomainNetwork()})
+		}
+		if !bytes.Equal(data, w.data) || profile != w.profile {
+			w.data, w.profile = data, profile
+			w.schedules = cfg.schedules(cfg.findProfile(profile))
+		}
+		reason = ""
+		for _, s := range w.schedules {
+			if _, _, ok := s.window(now); ok {
+				reason = "schedule " + s.name
+				break
+			}
+		}
+	}
+	if reason == w.reason {
+		return
+	}
+
+	w.engine.Stop()
+	w.reason = ""
+	if reason == "" {
+		logInfof("service: allowing sleep outside the schedules")
+		return
+	}
+	if err := w.engine.Start(espresso.Mode{Name: reason, Flags: espresso.System}); err != nil {
+		logErrorf("service: keeping the system awake: %v", err)
+		return
+	}
+	w.reason = reason
+	logInfof("service: keeping the system awake (%s)", reason)
+}
+
+// readServiceConfig reads the installing user's settings.json. That user
+// controls the folder, so it only reads, without settingsPath (which
+// creates the folder) or loadConfig (which saves defaults and sets
+// permissions), and refuses a path that goes through a junction or link.
+// A missing file means the defaults.
+func readServiceConfig() ([]byte, error) {
+	p := filepath.Join(os.Getenv("APPDATA"), "Espresso", "settings.json")
+	f, err := os.OpenFile(p, os.O_RDONLY|windows.O_FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return []byte("{}"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := windows.Handle(f.Fd())
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		return nil, err
+	}
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), 0)
+	if err != nil {
+		return nil, err
+	}
+	final := strings.TrimPrefix(windows.UTF16ToString(buf[:n]), `\\?\`)
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 || !strings.EqualFold(final, p) {
+		return nil, fmt.Errorf("%s is a link or in a linked folder", p)
+	}
+
+	if managedMode() {
+		sd, err := windows.GetSecurityInfo(h, windows.SE_FILE_OBJECT,
+			windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+		if err != nil {
+			return nil, err
+		}
+		if who, err := untrustedWriterOf(sd); err != nil || who != "" {
+			return nil, fmt.Errorf("%s can be modified by %s", p, cmp.Or(who, fmt.Sprintf("unknown (%v)", err)))
+		}
+	}
+	return io.ReadAll(f)
+}
+
+// serviceConfigOwner returns the user who installed the service, whose
+// settings.json it reads.
+func serviceConfigOwner() (*windows.SID, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, serviceKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	v, _, err := k.GetStringValue("ConfigOwner")
+	if err != nil {
+		return nil, fmt.Errorf("the installing user is not recorded: %w", err)
+	}
+	return windows.StringToSid(v)
+}
+
+func installService() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := connectServiceManager()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return errors.New("the service is already installed")
+	}
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: serviceDisplayName,
+		Description: serviceDescription,
+		StartType:   mgr.StartAutomatic,
+	}, "--service")
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Nobody is around to notice a crash on an unattended machine.
+	restart := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: time.Minute}}
+	if err := s.SetRecoveryActions(restart, uint32((24 * time.Hour).Seconds())); err != nil {
+		fmt.Fprintln(os.Stderr, "espresso --service install: could not set restart on failure:", err)
+	}
+
+	// The service runs as LocalSystem, whose APPDATA is not the user's;
+	// point it at the settings.json of whoever installs it, and record who
+	// that is for managed mode's permission check.
+	settings := settingsPath()
+	appdata := filepath.Dir(filepath.Dir(settings))
+	u, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err == nil {
+		var k registry.Key
+		if k, err = registry.OpenKey(registry.LOCAL_MACHINE, serviceKey, registry.SET_VALUE); err == nil {
+			err = k.SetStringsValue("Environment", []string{"APPDATA=" + appdata})
+			if err == nil {
+				err = k.SetStringValue("ConfigOwner", u.User.Sid.String())
+			}
+			k.Close()
+		}
+	}
+	if err != nil {
+		s.Delete()
+		return fmt.Errorf("setting the service environment: %w", err)
+	}
+	fmt.Printf("Installed the %s service, which reads %s.\nStart it with \"espresso --service start\"; it also starts with Windows.\n", serviceName, settings)
+	return nil
+}
+
+func uninstallService() error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	if err := stopAndWait(s); err != nil {
+		return err
+	}
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	fmt.Printf("Uninstalled the %s service.\n", serviceName)
+	return nil
+}
+
+func startService() error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	if err := s.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("Started the %s service.\n", serviceName)
+	return nil
+}
+
+func stopService() error {
+	m, s, err := openService()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+	if err := stopAndWait(s); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped the %s service.\n", serviceName)
+	return nil
+}
+
+func connectServiceManager() (*mgr.Mgr, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("%w; run it from an elevated prompt", err)
+	}
+	return m, nil
+}
+
+func openService() (*mgr.Mgr, *mgr.Service, error) {
+	m, err := connectServiceManager()
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		m.Disconnect()
+		return nil, nil, fmt.Errorf("the service is not installed (%w)", err)
+	}
+	return m, s, nil
+}
+
+// stopAndWait stops s unless it is already stopped, and waits up to
+// serviceStopTimeout for it to finish.
+func stopAndWait(s *mgr.Service) error {
+	st, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if st.State == svc.Stopped {
+		return nil
+	}
+	if st, err = s.Control(svc.Stop); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(serviceStopTimeout)
+	for st.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return errors.New("the service did not stop in time")
+		}
+		time.Sleep(300 * time.Millisecond)
+		if st, err = s.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- Session Persistence ---
+
+// session.json, next to settings.json, records the manual session so that
+// if Espresso or Windows restarts mid-session the user is offered the rest
+// of it. The file is removed when no session is active.
+
+// savedSession is the manual session as written to session.json. A timed
+// session stores when it ends, so the countdown keeps running while
+// Espresso is not; a paused one stores the time it had left instead. OnEnd
+// is "none" for a session without an end action, so that the end_action
+// setting does not give it one on resume.
+type savedSession struct {
+	Mode     string    `json:"mode"`
+	Source   string    `json:"source"`
+	Note     string    `json:"note,omitempty"`
+	OnEnd    string    `json:"on_end,omitempty"`
+	Simulate string    `json:"simulate,omitempty"`
+	Keep     []string  `json:"keep"`
+	Infinite bool      `json:"infinite,omitempty"`
+	EndTime  time.Time `json:"end_time,omitempty"`
+	Paused   bool      `json:"paused,omitempty"`
+	LeftS    int64     `json:"left_s,omitempty"` // paused timed sessions only
+	Until    time.Time `json:"until,omitempty"`  // the wall-clock end of an Until session
+	Watch    []string  `json:"watch,omitempty"`
+	Back     bool      `json:"back,omitempty"`
+}
+
+func sessionStatePath() string {
+	return filepath.Join(filepath.Dir(settingsPath()), "session.json")
+}
+
+// saveSession writes the manual session to session.json, or removes the
+// file if there is none.
+func (a *app) saveSession() {
+	if !a.active() {
+		if err := os.Remove(sessionStatePath()); err != nil && !os.IsNotExist(err) {
+			logWarnf("could not remove saved session: %v", err)
+		}
+		return
+	}
+
+	s := savedSession{
+		Mode:     a.modeName(),
+		Source:   a.sessionSource,
+		Note:     a.sessionNote,
+		OnEnd:    cmp.Or(a.sessionOnEnd, "none"),
+		Simulate: a.sessionSimulate,
+		Keep:     keepList(a.sessionFlags()),
+		Infinite: a.infinite(),
+		Paused:   a.paused(),
+		Watch:    a.sessionWatch,
+		Back:     a.sessionBack,
+	}
+	switch {
+	case a.infinite():
+	case a.paused():
+		s.LeftS = int64(a.timeLeft() / time.Second)
+	default:
+		s.EndTime = a.sessionEnd()
+		if a.sessionUntil {
+			s.Until = a.sessionEnd()
+		}
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(sessionStatePath(), data, 0600)
+	}
+	if err != nil {
+		logWarnf("could not save session: %v", err)
+	}
+}
+
+// loadSavedSession returns the session left in session.json, or nil if
+// there is none or it has run out in the meantime.
+func loadSavedSession() *savedSession {
+	data, err := os.ReadFile(sessionStatePath())
+	if err != nil {
+		return nil
+	}
+	var s savedSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		logWarnf("ignoring saved session: %v", err)
+		return nil
+	}
+	if s.remaining() <= 0 && !s.Infinite {
+		return nil
+	}
+	return &s
+}
+
+// remaining is how long the saved timed session has left.
+func (s savedSession) remaining() time.Duration {
+	if s.Paused {
+		return time.Duration(s.LeftS) * time.Second
+	}
+	return time.Until(s.EndTime).Truncate(time.Second)
+}
+
+// offerResume asks whether to continue the saved session and reports the
+// answer on ch.
+func offerResume(s savedSession, ch chan<- bool) {
+	var msg string
+	switch {
+	case s.Infinite:
+		msg = tr("resume.infinite", s.Mode)
+	case s.Paused:
+		msg = tr("resume.paused", s.Mode, formatFriendlyDuration(s.remaining()))
+	default:
+		msg = tr("resume.timed", s.Mode, formatFriendlyDuration(s.remaining()))
+	}
+	ch <- confirm(tr("resume.title"), msg)
+}
+
+// resumeSaved restarts the saved session with the time it has left, paused
+// if it was.
+func (a *app) resumeSaved(s savedSession) {
+	d := time.Duration(-1)
+	if !s.Infinite {
+		if d = s.remaining(); d <= 0 {
+			go showToast(tagSession, "Espresso", tr("toast.ran_out", s.Mode), icoffPath())
+			return
+		}
+	}
+	flags, err := parseKeep(s.Keep)
+	if err != nil {
+		flags = 0
+	}
+	simulate := s.Simulate
+	if simulate == "" {
+		simulate = simulateNone
+	}
+	// Files from before on_end was always written left it out for none.
+	onEnd := cmp.Or(s.OnEnd, "none")
+	a.startSession(EspressoMode{Name: s.Mode, Duration: d, Flags: flags, Note: s.Note, OnEnd: onEnd, Simulate: simulate, Watch: s.Watch, Back: s.Back, Until: s.Until}, s.Source)
+	if s.Paused {
+		a.pause() // its notification says the session is paused
+		return
+	}
+	go showToast(tagSession, tr("toast.session_resumed", s.Mode), a.startedMessage(), iconPath())
+}
@@ -0,0 +1,103 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// --- Notification Buttons ---
+
+// Buttons on session notifications open espresso://extend and
+// espresso://stop links, which Windows hands to a new Espresso process
+// that forwards them to this one. Any web page could open such a link, so
+// each carries a nonce that is only valid for the current session.
+
+var notificationExtensions = []time.Duration{30 * time.Minute, time.Hour}
+
+// sessionLink returns an espresso:// link acting on the current session.
+func (a *app) sessionLink(action string, q url.Values) string {
+	if a.actionNonce == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			logWarnf("could not create action nonce: %v", err)
+			return ""
+		}
+		a.actionNonce = hex.EncodeToString(b)
+	}
+	q.Set("nonce", a.actionNonce)
+	return fmt.Sprintf("%s://%s?%s", urlScheme, action, q.Encode())
+}
+
+// extendActions are buttons that extend a timed session.
+func (a *app) extendActions() []toastAction {
+	var actions []toastAction
+	for _, d := range notificationExtensions {
+		actions = append(actions, toastAction{
+			Label:     tr("menu.extend.add", formatFriendlyDuration(d)),
+			Arguments: a.sessionLink("extend", url.Values{"by": {d.String()}}),
+		})
+	}
+	return actions
+}
+
+// stopAction is a button that ends the session.
+func (a *app) stopAction() toastAction {
+	return toastAction{Label: tr("action.stop"), Arguments: a.sessionLink("stop", url.Values{})}
+}
+
+func (a *app) checkActionNonce(nonce string) error {
+	if !a.active() || a.actionNonce == "" || nonce != a.actionNonce {
+		return errors.New("this notification no longer applies to the current session")
+	}
+	return nil
+}
+
+// extendFromLink extends the session from a notification button.
+func (a *app) extendFromLink(by, nonce string) error {
+	if err := a.checkActionNonce(nonce); err != nil {
+		return err
+	}
+	if a.infinite() {
+		return errors.New("the session has no time limit")
+	}
+	d, err := parseSessionDuration(by)
+	if err != nil {
+		return err
+	}
+	a.extend(d)
+	return nil
+}
+
+// stopFromLink ends the session from a notification button.
+func (a *app) stopFromLink(nonce string) error {
+	if err := a.checkActionNonce(nonce); err != nil {
+		return err
+	}
+	a.resetState("stopped")
+	go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+	return nil
+}
@@ -0,0 +1,413 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Settings Window ---
+
+// The settings window edits the common settings without touching
+// settings.json by hand. Custom modes are edited as text, one per line:
+// "name, duration, description". Their other fields, such as keep or
+// on_end, are kept for modes whose name is unchanged. API tokens are
+// managed from a list; those changes go to the main loop at once, like
+// the tray's API Tokens submenu, rather than waiting for OK.
+
+const settingsClassName = "EspressoSettings"
+
+// settingsEdit is what the window edits, sent to the main loop on OK.
+type settingsEdit struct {
+	Language         string
+	HotkeyMode       string // "" for the last mode picked
+	Notifications    string // "", "toast" or "balloon"
+	StartWithWindows bool
+	RestoreLastMode  bool
+	Modes            []ModeConfig
+	Tokens           []APIToken // listed only; changed through tokenAction
+}
+
+// settingsEdit returns the current values for the settings window.
+func (a *app) settingsEdit() settingsEdit {
+	return settingsEdit{
+		Language:         a.cfg.Language,
+		HotkeyMode:       a.cfg.Hotkey.Mode,
+		Notifications:    a.cfg.Notifications,
+		StartWithWindows: a.cfg.StartWithWindows,
+		RestoreLastMode:  a.cfg.RestoreLastMode,
+		Modes:            a.cfg.Modes,
+		Tokens:           a.cfg.APITokens,
+	}
+}
+
+// applySettingsEdit saves and applies the settings from the window.
+func (a *app) applySettingsEdit(e settingsEdit) []string {
+	cfg := a.cfg
+	cfg.Language = e.Language
+	cfg.Hotkey.Mode = e.HotkeyMode
+	cfg.Notifications = e.Notifications
+	cfg.StartWithWindows = e.StartWithWindows
+	cfg.RestoreLastMode = e.RestoreLastMode
+	cfg.Modes = e.Modes
+	restart := a.applyConfig(cfg)
+	a.saveConfig()
+	return restart
+}
+
+var notificationStyles = []string{"", "toast", "balloon"}
+
+// Control IDs.
+const (
+	settingsLanguage = 100 + iota
+	settingsMode
+	settingsNotifications
+	settingsAutostart
+	settingsRestore
+	settingsModes
+	settingsTokens
+	settingsTokenGenerate
+	settingsTokenRotate
+	settingsTokenRevoke
+)
+
+const wmSettingsTokens = WM_USER + 1
+
+// settingsWindow is the open settings window. Only one can be open at a
+// time, since the window class shares a single window procedure.
+type settingsWindow struct {
+	edit      settingsEdit
+	modeNames []string
+	ch        chan<- settingsEdit
+	tokenCh   chan<- tokenAction
+
+	hwnd                          windows.HWND
+	language, mode, notifications windows.HWND
+	autostart, restore, modes     windows.HWND
+	tokens, generate              windows.HWND
+}
+
+var (
+	settingsMu     sync.Mutex
+	activeSettings *settingsWindow
+)
+
+// showSettings opens the settings window with the values in edit and
+// sends the edited ones on ch when the user clicks OK. modeNames lists the
+// modes for the default mode. Token buttons send on tokenCh straight away.
+// It blocks until the window is closed, so call it from a goroutine; if
+// the window is already open it returns at once.
+func showSettings(edit settingsEdit, modeNames []string, ch chan<- settingsEdit, tokenCh chan<- tokenAction) {
+	settingsMu.Lock()
+	if activeSettings != nil {
+		settingsMu.Unlock()
+		return
+	}
+	s := &settingsWindow{edit: edit, modeNames: modeNames, ch: ch, tokenCh: tokenCh}
+	activeSettings = s
+	settingsMu.Unlock()
+
+	defer func() {
+		settingsMu.Lock()
+		activeSettings = nil
+		settingsMu.Unlock()
+	}()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Client area layout in 96-DPI units.
+	const width, height = 440, 498
+	frame := windows.Rect{Right: dpiScale(width), Bottom: dpiScale(height)}
+	procAdjustWindowRectEx.Call(uintptr(unsafe.Pointer(&frame)), WS_CAPTION|WS_SYSMENU, 0, 0)
+	w, h := frame.Right-frame.Left, frame.Bottom-frame.Top
+	wa := workArea()
+
+	hwnd, err := createWindow(settingsClassName, 0, WS_CAPTION|WS_SYSMENU,
+		wa.Left+(wa.Right-wa.Left-w)/2, wa.Top+(wa.Bottom-wa.Top-h)/2, w, h, settingsWndProc)
+	if err != nil {
+		logWarnf("could not open settings: %v", err)
+		return
+	}
+	settingsMu.Lock()
+	s.hwnd = hwnd
+	settingsMu.Unlock()
+	t, _ := windows.UTF16PtrFromString(tr("settings.title"))
+	procSetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(t)))
+
+	sc := dpiScale
+	label := func(text string, y int32) {
+		createControl(hwnd, "STATIC", text, 0, 0, sc(12), sc(y+4), sc(160), sc(20), 0)
+	}
+	combo := func(y int32, id uintptr, items []string, selected int) windows.HWND {
+		c := createControl(hwnd, "COMBOBOX", "", 0, WS_TABSTOP|WS_VSCROLL|CBS_DROPDOWNLIST, sc(176), sc(y), sc(252), sc(200), id)
+		for _, item := range items {
+			text, _ := windows.UTF16PtrFromString(item)
+			procSendMessageW.Call(uintptr(c), CB_ADDSTRING, 0, uintptr(unsafe.Pointer(text)))
+		}
+		procSendMessageW.Call(uintptr(c), CB_SETCURSEL, uintptr(max(selected, 0)), 0)
+		return c
+	}
+	check := func(text string, y int32, id uintptr, checked bool) windows.HWND {
+		c := createControl(hwnd, "BUTTON", text, 0, WS_TABSTOP|BS_AUTOCHECKBOX, sc(12), sc(y), sc(416), sc(22), id)
+		if checked {
+			procSendMessageW.Call(uintptr(c), BM_SETCHECK, BST_CHECKED, 0)
+		}
+		return c
+	}
+
+	var languageNames []string
+	selected := 0
+	for i, tag := range languages {
+		c, _ := loadCatalog(tag)
+		languageNames = append(languageNames, c["language.name"])
+		if tag == edit.Language {
+			selected = i
+		}
+	}
+	label(tr("settings.language"), 12)
+	s.language = combo(12, settingsLanguage, languageNames, selected)
+
+	label(tr("settings.default_mode"), 44)
+	selected = 0
+	for i, name := range modeNames {
+		if name == edit.HotkeyMode {
+			selected = i + 1
+		}
+	}
+	s.mode = combo(44, settingsMode, append([]string{tr("settings.default_mode.last")}, modeNames...), selected)
+
+	label(tr("settings.notifications"), 76)
+	selected = 0
+	for i, style := range notificationStyles {
+		if strings.EqualFold(style, edit.Notifications) {
+			selected = i
+		}
+	}
+	s.notifications = combo(76, settingsNotifications, []string{
+		tr("settings.notifications.auto"), tr("settings.notifications.toast"), tr("settings.notifications.balloon"),
+	}, selected)
+
+	s.autostart = check(tr("settings.autostart"), 112, settingsAutostart, edit.StartWithWindows)
+	s.restore = check(tr("settings.restore_last_mode"), 138, settingsRestore, edit.RestoreLastMode)
+
+	createControl(hwnd, "STATIC", tr("settings.modes"), 0, 0, sc(12), sc(172), sc(416), sc(20), 0)
+	s.modes = createControl(hwnd, "EDIT", formatModeLines(edit.Modes), WS_EX_CLIENTEDGE,
+		WS_TABSTOP|WS_VSCROLL|ES_MULTILINE|ES_AUTOVSCROLL|ES_WANTRETURN, sc(12), sc(194), sc(416), sc(128), settingsModes)
+
+	createControl(hwnd, "STATIC", tr("settings.tokens"), 0, 0, sc(12), sc(334), sc(416), sc(20), 0)
+	s.tokens = createControl(hwnd, "LISTBOX", "", WS_EX_CLIENTEDGE,
+		WS_TABSTOP|WS_VSCROLL|LBS_NOTIFY, sc(12), sc(356), sc(320), sc(84), settingsTokens)
+	s.generate = createControl(hwnd, "BUTTON", tr("menu.tokens.generate"), 0, WS_TABSTOP|BS_PUSHBUTTON, sc(340), sc(356), sc(88), sc(24), settingsTokenGenerate)
+	createControl(hwnd, "BUTTON", tr("menu.tokens.rotate"), 0, WS_TABSTOP|BS_PUSHBUTTON, sc(340), sc(386), sc(88), sc(24), settingsTokenRotate)
+	createControl(hwnd, "BUTTON", tr("menu.tokens.revoke"), 0, WS_TABSTOP|BS_PUSHBUTTON, sc(340), sc(416), sc(88), sc(24), settingsTokenRevoke)
+	s.listTokens(edit.Tokens)
+
+	createControl(hwnd, "BUTTON", "OK", 0, WS_TABSTOP|BS_DEFPUSHBUTTON, sc(260), sc(460), sc(80), sc(26), IDOK)
+	createControl(hwnd, "BUTTON", tr("action.cancel"), 0, WS_TABSTOP|BS_PUSHBUTTON, sc(348), sc(460), sc(80), sc(26), IDCANCEL)
+
+	procShowWindow.Call(uintptr(hwnd), SW_SHOWNORMAL)
+	procSetForegroundWindow.Call(uintptr(hwnd))
+	procSetFocus.Call(uintptr(s.language))
+	runMessageLoop(hwnd)
+}
+
+func settingsWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	settingsMu.Lock()
+	s := activeSettings
+	settingsMu.Unlock()
+
+	switch msg {
+	case WM_COMMAND:
+		switch wParam & 0xFFFF {
+		case IDOK:
+			if s != nil && !s.submit(hwnd) {
+				return 0
+			}
+			procDestroyWindow.Call(uintptr(hwnd))
+			return 0
+		case IDCANCEL:
+			procDestroyWindow.Call(uintptr(hwnd))
+			return 0
+		case settingsTokenGenerate, settingsTokenRotate, settingsTokenRevoke:
+			if s != nil {
+				s.tokenButton(int(wParam & 0xFFFF))
+			}
+			return 0
+		}
+	case wmSettingsTokens:
+		if s != nil {
+			settingsMu.Lock()
+			tokens := s.edit.Tokens
+			settingsMu.Unlock()
+			s.listTokens(tokens)
+		}
+		return 0
+	case WM_CLOSE:
+		procDestroyWindow.Call(uintptr(hwnd))
+		return 0
+	case WM_DESTROY:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// submit reads the controls and sends the result, or explains what is
+// wrong and returns false to keep the window open.
+func (s *settingsWindow) submit(hwnd windows.HWND) bool {
+	modes, err := parseModeLines(windowText(s.modes), s.edit.Modes)
+	if err != nil {
+		t, _ := windows.UTF16PtrFromString(tr("settings.title"))
+		m, _ := windows.UTF16PtrFromString(err.Error())
+		procMessageBoxW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(m)), uintptr(unsafe.Pointer(t)), MB_ICONWARNING)
+		procSetFocus.Call(uintptr(s.modes))
+		return false
+	}
+
+	settingsMu.Lock()
+	e := s.edit
+	settingsMu.Unlock()
+	e.Modes = modes
+	if i := comboSelection(s.language); i >= 0 && i < len(languages) {
+		e.Language = languages[i]
+	}
+	if i := comboSelection(s.mode); i == 0 {
+		e.HotkeyMode = ""
+	} else if i > 0 && i <= len(s.modeNames) {
+		e.HotkeyMode = s.modeNames[i-1]
+	}
+	if i := comboSelection(s.notifications); i >= 0 && i < len(notificationStyles) {
+		e.Notifications = notificationStyles[i]
+	}
+	e.StartWithWindows = isChecked(s.autostart)
+	e.RestoreLastMode = isChecked(s.restore)
+	go func() { s.ch <- e }()
+	return true
+}
+
+// updateSettingsTokens shows tokens in the settings window, if it is open.
+func updateSettingsTokens(tokens []APIToken) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if s := activeSettings; s != nil && s.hwnd != 0 {
+		s.edit.Tokens = tokens
+		procPostMessageW.Call(uintptr(s.hwnd), wmSettingsTokens, 0, 0)
+	}
+}
+
+// listTokens fills the token list. Call it on the window's thread.
+func (s *settingsWindow) listTokens(tokens []APIToken) {
+	procSendMessageW.Call(uintptr(s.tokens), LB_RESETCONTENT, 0, 0)
+	for _, t := range tokens {
+		text, _ := windows.UTF16PtrFromString(tr("menu.tokens.slot", t.ID, t.Created.Format("2006-01-02")))
+		procSendMessageW.Call(uintptr(s.tokens), LB_ADDSTRING, 0, uintptr(unsafe.Pointer(text)))
+	}
+	enable := uintptr(0)
+	if len(tokens) < maxAPITokens {
+		enable = 1
+	}
+	procEnableWindow.Call(uintptr(s.generate), enable)
+}
+
+// tokenButton sends the action for a token button to the main loop. Rotate
+// and revoke name the selected token, so a list that changed in the
+// meantime is not acted on.
+func (s *settingsWindow) tokenButton(id int) {
+	act := tokenAction{generate: id == settingsTokenGenerate}
+	if !act.generate {
+		i, _, _ := procSendMessageW.Call(uintptr(s.tokens), LB_GETCURSEL, 0, 0)
+		settingsMu.Lock()
+		tokens := s.edit.Tokens
+		settingsMu.Unlock()
+		slot := int(int32(i))
+		if slot < 0 || slot >= len(tokens) {
+			return
+		}
+		act.slot, act.id, act.revoke = slot, tokens[slot].ID, id == settingsTokenRevoke
+	}
+	go func() { s.tokenCh <- act }()
+}
+
+func comboSelection(c windows.HWND) int {
+	i, _, _ := procSendMessageW.Call(uintptr(c), CB_GETCURSEL, 0, 0)
+	return int(int32(i))
+}
+
+func isChecked(c windows.HWND) bool {
+	r, _, _ := procSendMessageW.Call(uintptr(c), BM_GETCHECK, 0, 0)
+	return r == BST_CHECKED
+}
+
+// formatModeLines writes modes one per line for the window.
+func formatModeLines(modes []ModeConfig) string {
+	var b strings.Builder
+	for _, m := range modes {
+		line := m.Name + ", " + m.Duration
+		if m.Description != "" {
+			line += ", " + m.Description
+		}
+		b.WriteString(line + "\r\n")
+	}
+	return b.String()
+}
+
+// parseModeLines reads the lines written by formatModeLines, keeping the
+// other fields of the modes in previous with the same name.
+func parseModeLines(text string, previous []ModeConfig) ([]ModeConfig, error) {
+	var modes []ModeConfig
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, ",", 3)
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, errors.New(tr("settings.invalid_mode", i+1, tr("settings.mode_format")))
+		}
+		m := ModeConfig{Name: fields[0], Duration: fields[1]}
+		for _, p := range previous {
+			if strings.EqualFold(p.Name, m.Name) {
+				m = p
+				m.Name, m.Duration, m.Description = fields[0], fields[1], ""
+			}
+		}
+		if len(fields) == 3 {
+			m.Description = fields[2]
+		}
+		if !strings.EqualFold(m.Duration, "infinite") {
+			if _, err := parseSessionDuration(m.Duration); err != nil {
+				return nil, errors.New(tr("settings.invalid_mode", i+1, err))
+			}
+		}
+		modes = append(modes, m)
+	}
+	return modes, nil
+}
@@ -0,0 +1,102 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Shared Machine Etiquette ---
+
+// SharedMachineConfig is etiquette for PCs used by several people.
+type SharedMachineConfig struct {
+	// NotifyRemote shows the console user a notification whenever a
+	// remote client, such as the control API, keeps the machine awake.
+	NotifyRemote bool `json:"notify_remote,omitempty"`
+	// AllowRelease adds a "Release Now" button to that notification. The
+	// AllowRelease policy value, if set, takes precedence.
+	AllowRelease bool `json:"allow_release,omitempty"`
+}
+
+// Administrators can decide whether the console user may release remote
+// sessions with the DWORD AllowRelease under this key.
+const policyKey = `SOFTWARE\Policies\Espresso`
+
+// releaseAllowed reports whether the console user may end remote sessions.
+func (cfg SharedMachineConfig) releaseAllowed() bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, policyKey, registry.QUERY_VALUE)
+	if err == nil {
+		defer k.Close()
+		if v, _, err := k.GetIntegerValue("AllowRelease"); err == nil {
+			return v != 0
+		}
+	}
+	return cfg.AllowRelease
+}
+
+// announceRemote tells the console user that a remote client keeps the
+// machine awake, offering to release it if policy allows.
+func (a *app) announceRemote() {
+	if !a.cfg.SharedMachine.NotifyRemote {
+		return
+	}
+	title := tr("remote.title")
+	msg := tr("remote.started", a.modeName(), a.sessionSource, a.startedMessage())
+
+	if !a.cfg.SharedMachine.releaseAllowed() {
+		a.releaseNonce = ""
+		go showToast(tagSession, title, msg, iconPath())
+		return
+	}
+
+	// The nonce ties the button to this notification, so that no other
+	// link can end the session.
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logWarnf("%v", err)
+		return
+	}
+	a.releaseNonce = hex.EncodeToString(b)
+	release := fmt.Sprintf("%s://release?nonce=%s", urlScheme, a.releaseNonce)
+	go showToastActions(tagSession, title, msg, iconPath(), []toastAction{
+		{Label: tr("remote.release"), Arguments: release},
+		{Label: tr("action.dismiss"), Arguments: ""},
+	})
+}
+
+// releaseRemote ends a remote session from the "Release Now" button.
+func (a *app) releaseRemote(nonce string) error {
+	if !a.active() || a.releaseNonce == "" || nonce != a.releaseNonce {
+		return errors.New("this notification no longer applies to the current session")
+	}
+	if !a.cfg.SharedMachine.releaseAllowed() {
+		return errors.New("releasing remote sessions is not allowed by policy")
+	}
+	source := a.sessionSource
+	a.resetState("released")
+	go showToast(tagSession, tr("remote.released_title"), tr("remote.released", source, a.releasedMessage()), icoffPath())
+	return nil
+}
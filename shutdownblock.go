@@ -0,0 +1,144 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Restart Blocking ---
+
+// With block_restarts on, Espresso asks Windows not to shut down or
+// restart while a session runs, so that an automatic restart for updates
+// does not kill an overnight job. Windows lists Espresso with the mode
+// name as the reason on the "apps are preventing restart" screen, where
+// the user can still restart anyway.
+
+var (
+	procShutdownBlockReasonCreate  = user32.NewProc("ShutdownBlockReasonCreate")
+	procShutdownBlockReasonDestroy = user32.NewProc("ShutdownBlockReasonDestroy")
+)
+
+const (
+	WM_QUERYENDSESSION = 0x0011
+
+	shutdownBlockerClassName = "EspressoShutdownBlocker"
+	wmShutdownBlockUpdate    = WM_USER + 1
+)
+
+// shutdownBlocker is a hidden window that holds the block reason, which
+// Windows ties to a window and only accepts from the thread that created
+// it. Other goroutines call set.
+type shutdownBlocker struct {
+	hwnd windows.HWND
+
+	mu     sync.Mutex
+	reason string // "" to allow shutdown
+
+	blocked string // only touched on the window thread
+}
+
+func startShutdownBlocker() *shutdownBlocker {
+	b := &shutdownBlocker{}
+	ready := make(chan struct{})
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hwnd, err := createWindow(shutdownBlockerClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0, b.wndProc)
+		if err != nil {
+			logWarnf("could not create the restart blocking window: %v", err)
+			close(ready)
+			return
+		}
+		b.hwnd = hwnd
+		close(ready)
+		runMessageLoop(0)
+	}()
+
+	<-ready
+	return b
+}
+
+// set blocks shutdown with reason, or allows it if reason is "".
+func (b *shutdownBlocker) set(reason string) {
+	if b.hwnd == 0 {
+		return
+	}
+	b.mu.Lock()
+	changed := b.reason != reason
+	b.reason = reason
+	b.mu.Unlock()
+	if changed {
+		procPostMessageW.Call(uintptr(b.hwnd), wmShutdownBlockUpdate, 0, 0)
+	}
+}
+
+func (b *shutdownBlocker) wndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case wmShutdownBlockUpdate:
+		b.mu.Lock()
+		reason := b.reason
+		b.mu.Unlock()
+		b.apply(hwnd, reason)
+		return 0
+	case WM_QUERYENDSESSION:
+		if b.blocked != "" {
+			logInfof("Blocked a shutdown or restart: %s", b.blocked)
+			return 0
+		}
+		return 1
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+func (b *shutdownBlocker) apply(hwnd windows.HWND, reason string) {
+	if reason == b.blocked || !procAvailable(procShutdownBlockReasonCreate) {
+		return
+	}
+	if reason == "" {
+		procShutdownBlockReasonDestroy.Call(uintptr(hwnd))
+		logInfof("Restarts allowed again")
+	} else {
+		p, _ := windows.UTF16PtrFromString(reason)
+		if r, _, err := procShutdownBlockReasonCreate.Call(uintptr(hwnd), uintptr(unsafe.Pointer(p))); r == 0 {
+			logWarnf("could not block restarts: %v", err)
+			return
+		}
+		logInfof("Blocking restarts: %s", reason)
+	}
+	b.blocked = reason
+}
+
+// syncShutdownBlock blocks restarts while a session runs, if block_restarts
+// is on.
+func (a *app) syncShutdownBlock() {
+	reason := ""
+	if a.cfg.BlockRestarts && a.active() && !a.paused() {
+		reason = tr("shutdown.reason", a.modeName())
+	}
+	a.shutdownBlock.set(reason)
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import "espresso/pkg/espresso"
+
+// --- Sleep Control ---
+
+// Keep-awake requests are expressed with the flags of Windows'
+// SetThreadExecutionState on every platform, as espresso.System and
+// espresso.Display.
+const (
+	ES_SYSTEM_REQUIRED  = 0x00000001
+	ES_DISPLAY_REQUIRED = 0x00000002
+)
+
+// sleeper is the platform's espresso.Inhibitor. The tray changes it only
+// on sleepThread, which main starts; the service and "espresso ci" go
+// through an espresso.Engine instead.
+var (
+	sleeper     = espresso.NewInhibitor()
+	sleepThread *espresso.Thread
+)
+
+func allowSleep() {
+	sleeper.Allow()
+}
+
+// systemHeld returns what the OS currently keeps awake; see
+// espresso.Inhibitor.
+func systemHeld() (uint32, bool) {
+	flags, ok := sleeper.Held()
+	return uint32(flags), ok
+}
+
+func preventSleep(flags uint32) error {
+	// flags selects system sleep and/or display sleep prevention
+	return sleeper.Prevent(espresso.Flags(flags))
+}
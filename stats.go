@@ -0,0 +1,405 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Statistics ---
+
+const (
+	statsDays      = 30
+	statsClassName = "EspressoStats"
+)
+
+// awakeSpan is a stretch of time during which Espresso kept the system
+// awake.
+type awakeSpan struct {
+	start, end time.Time
+}
+
+// awakeSpans rebuilds from the event log (including its rotation) when a
+// manual session or a trigger kept the system awake, merged so that no
+// time is counted twice. Paused time is left out. A session without an end
+// event, e.g. after a crash, ends at its planned length or at the next
+// session start.
+func awakeSpans() []awakeSpan {
+	var spans []awakeSpan
+	var session *awakeSpan
+	var sessionLimit time.Time // zero for no limit
+	var pausedLeft time.Duration
+	triggers := make(map[string]time.Time)
+
+	closeSession := func(at time.Time) {
+		if session == nil {
+			return
+		}
+		if !sessionLimit.IsZero() && at.After(sessionLimit) {
+			at = sessionLimit
+		}
+		if at.After(session.start) {
+			session.end = at
+			spans = append(spans, *session)
+		}
+		session = nil
+	}
+
+	dir := filepath.Dir(eventLogPath())
+	for _, name := range []string{"events.1.jsonl", "events.jsonl"} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var e event
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				continue
+			}
+			ts, err := time.Parse(time.RFC3339, e.Time)
+			if err != nil {
+				continue
+			}
+
+			switch e.Event {
+			case eventSessionStart:
+				closeSession(ts)
+				session = &awakeSpan{start: ts}
+				sessionLimit = time.Time{}
+				if e.DurationS >= 0 {
+					sessionLimit = ts.Add(time.Duration(e.DurationS) * time.Second)
+				}
+			case eventSessionExtended:
+				if !sessionLimit.IsZero() {
+					sessionLimit = sessionLimit.Add(time.Duration(e.AddedS) * time.Second)
+				}
+			case eventSessionPaused:
+				// Resuming starts a new span with the time that was left.
+				limit := sessionLimit
+				closeSession(ts)
+				pausedLeft = -1
+				if !limit.IsZero() {
+					pausedLeft = limit.Sub(ts)
+				}
+			case eventSessionResumed:
+				if session == nil {
+					session = &awakeSpan{start: ts}
+					sessionLimit = time.Time{}
+					if pausedLeft >= 0 {
+						sessionLimit = ts.Add(pausedLeft)
+					}
+				}
+			case eventSessionEnd:
+				closeSession(ts)
+				sessionLimit = time.Time{}
+			case eventTriggerFired:
+				if e.Active == nil {
+					continue
+				}
+				if *e.Active {
+					if _, ok := triggers[e.Trigger]; !ok {
+						triggers[e.Trigger] = ts
+					}
+				} else if start, ok := triggers[e.Trigger]; ok {
+					spans = append(spans, awakeSpan{start: start, end: ts})
+					delete(triggers, e.Trigger)
+				}
+			}
+		}
+		f.Close()
+	}
+
+	now := time.Now()
+	closeSession(now)
+	for _, start := range triggers {
+		spans = append(spans, awakeSpan{start: start, end: now})
+	}
+	return mergeSpans(spans)
+}
+
+func mergeSpans(spans []awakeSpan) []awakeSpan {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+	var merged []awakeSpan
+	for _, s := range spans {
+		if n := len(merged); n > 0 && !s.start.After(merged[n-1].end) {
+			if s.end.After(merged[n-1].end) {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// awakeHeatmap is the awake time in each hour of the last statsDays days,
+// oldest day first, starting at midnight of first.
+type awakeHeatmap struct {
+	first time.Time
+	hours [statsDays][24]time.Duration
+	total time.Duration
+	night time.Duration // between midnight and 6 am
+	today time.Duration
+	week  time.Duration // since Monday
+}
+
+func newAwakeHeatmap(spans []awakeSpan, now time.Time) *awakeHeatmap {
+	y, m, d := now.Date()
+	h := &awakeHeatmap{first: time.Date(y, m, d-statsDays+1, 0, 0, 0, 0, now.Location())}
+	weekday := (int(now.Weekday()) + 6) % 7 // days since Monday
+
+	for _, s := range spans {
+		start, end := s.start.In(now.Location()), s.end.In(now.Location())
+		if start.Before(h.first) {
+			start = h.first
+		}
+		for start.Before(end) {
+			hourEnd := start.Truncate(time.Hour).Add(time.Hour)
+			if hourEnd.After(end) {
+				hourEnd = end
+			}
+			day := int(time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()).Sub(h.first).Hours()/24 + 0.5)
+			if day >= 0 && day < statsDays {
+				d := hourEnd.Sub(start)
+				h.hours[day][start.Hour()] += d
+				h.total += d
+				if start.Hour() < 6 {
+					h.night += d
+				}
+				if day == statsDays-1 {
+					h.today += d
+				}
+				if day >= statsDays-1-weekday {
+					h.week += d
+				}
+			}
+			start = hourEnd
+		}
+	}
+	return h
+}
+
+// --- Statistics Window ---
+
+// statsWindow is the open statistics window. Only one can be open at a
+// time, since the window class shares a single window procedure.
+type statsWindow struct {
+	heatmap *awakeHeatmap
+	summary string
+
+	energy   EnergyConfig
+	energyCh chan<- EnergyConfig // edited energy settings, for the main loop
+}
+
+var (
+	statsMu     sync.Mutex
+	activeStats *statsWindow
+)
+
+// Heatmap layout in 96-DPI units.
+const (
+	statsMargin      = 12
+	statsLabelWidth  = 64
+	statsHeaderH     = 20
+	statsCellW       = 18
+	statsCellH       = 13
+	statsSummaryH    = 84
+	statsButtonW     = 120
+	statsButtonH     = 26
+	statsClientWidth = statsMargin*2 + statsLabelWidth + 24*statsCellW
+
+	statsEnergyButton = 100
+)
+
+// showStats opens the statistics window with an hour-by-day heatmap of the
+// time kept awake over the last month, and its energy cost. Energy
+// settings edited from the window are sent on energyCh. It blocks until
+// the window is closed, so call it from a goroutine; if the window is
+// already open it returns at once.
+func showStats(energy EnergyConfig, energyCh chan<- EnergyConfig) {
+	statsMu.Lock()
+	if activeStats != nil {
+		statsMu.Unlock()
+		return
+	}
+	s := &statsWindow{energy: energy, energyCh: energyCh}
+	activeStats = s
+	statsMu.Unlock()
+
+	defer func() {
+		statsMu.Lock()
+		activeStats = nil
+		statsMu.Unlock()
+	}()
+
+	s.heatmap = newAwakeHeatmap(awakeSpans(), time.Now())
+	s.summary = tr("stats.recent", formatFriendlyDuration(s.heatmap.today.Round(time.Minute)),
+		formatFriendlyDuration(s.heatmap.week.Round(time.Minute))) + "\n" + tr("stats.summary", statsDays, formatFriendlyDuration(s.heatmap.total.Round(time.Minute)),
+		formatFriendlyDuration(s.heatmap.night.Round(time.Minute)))
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	const width = statsClientWidth
+	const height = statsMargin*3 + statsHeaderH + statsDays*statsCellH + statsSummaryH + statsButtonH
+	frame := windows.Rect{Right: dpiScale(width), Bottom: dpiScale(height)}
+	procAdjustWindowRectEx.Call(uintptr(unsafe.Pointer(&frame)), WS_CAPTION|WS_SYSMENU, 0, 0)
+	w, h := frame.Right-frame.Left, frame.Bottom-frame.Top
+	wa := workArea()
+
+	hwnd, err := createWindow(statsClassName, 0, WS_CAPTION|WS_SYSMENU,
+		wa.Left+(wa.Right-wa.Left-w)/2, wa.Top+(wa.Bottom-wa.Top-h)/2, w, h, statsWndProc)
+	if err != nil {
+		logWarnf("could not open statistics: %v", err)
+		return
+	}
+	t, _ := windows.UTF16PtrFromString(tr("stats.title"))
+	procSetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(t)))
+
+	sc := dpiScale
+	createControl(hwnd, "BUTTON", tr("stats.energy_button"), 0, WS_TABSTOP|BS_PUSHBUTTON,
+		sc(width-statsMargin-statsButtonW), sc(height-statsMargin-statsButtonH), sc(statsButtonW), sc(statsButtonH), statsEnergyButton)
+
+	procShowWindow.Call(uintptr(hwnd), SW_SHOWNORMAL)
+	procSetForegroundWindow.Call(uintptr(hwnd))
+	runMessageLoop(hwnd)
+}
+
+func statsWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	statsMu.Lock()
+	s := activeStats
+	statsMu.Unlock()
+
+	switch msg {
+	case WM_PAINT:
+		if s != nil {
+			s.paint(hwnd)
+			return 0
+		}
+	case WM_COMMAND:
+		switch wParam & 0xFFFF {
+		case IDCANCEL:
+			procDestroyWindow.Call(uintptr(hwnd))
+			return 0
+		case statsEnergyButton:
+			if s != nil {
+				go s.editEnergy(hwnd)
+			}
+			return 0
+		}
+	case WM_CLOSE:
+		procDestroyWindow.Call(uintptr(hwnd))
+		return 0
+	case WM_DESTROY:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// heatColor shades a cell from cream (no awake time) to dark roast (the
+// whole hour).
+func heatColor(d time.Duration) uint32 {
+	if d <= 0 {
+		return rgb(244, 240, 234)
+	}
+	f := float64(d) / float64(time.Hour)
+	if f > 1 {
+		f = 1
+	}
+	mix := func(a, b byte) byte { return byte(float64(a) + (float64(b)-float64(a))*f) }
+	return rgb(mix(222, 92), mix(196, 58), mix(166, 32))
+}
+
+func (s *statsWindow) paint(hwnd windows.HWND) {
+	var ps paintStruct
+	hdc, _, _ := procBeginPaint.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&ps)))
+	defer procEndPaint.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&ps)))
+
+	old, _, _ := procSelectObject.Call(hdc, dialogFont())
+	defer procSelectObject.Call(hdc, old)
+	procSetBkMode.Call(hdc, TRANSPARENT)
+
+	sc := dpiScale
+	text := func(str string, rc windows.Rect, format uintptr) {
+		t, _ := windows.UTF16FromString(str)
+		procDrawTextW.Call(hdc, uintptr(unsafe.Pointer(&t[0])), ^uintptr(0), uintptr(unsafe.Pointer(&rc)), format|DT_NOPREFIX)
+	}
+	left := sc(statsMargin + statsLabelWidth)
+	top := sc(statsMargin + statsHeaderH)
+
+	for hour := 0; hour < 24; hour += 3 {
+		x := left + sc(int32(hour)*statsCellW)
+		text(fmt.Sprintf("%02d", hour), windows.Rect{Left: x, Top: sc(statsMargin), Right: x + sc(3*statsCellW), Bottom: top}, DT_SINGLELINE)
+	}
+
+	for day := 0; day < statsDays; day++ {
+		y := top + sc(int32(day)*statsCellH)
+		date := s.heatmap.first.AddDate(0, 0, day)
+		text(date.Format("Mon 02 Jan"), windows.Rect{Left: sc(statsMargin), Top: y, Right: left - sc(6), Bottom: y + sc(statsCellH)}, DT_SINGLELINE|DT_VCENTER)
+
+		for hour := 0; hour < 24; hour++ {
+			x := left + sc(int32(hour)*statsCellW)
+			// Leave a one-pixel gap between cells.
+			cell := windows.Rect{Left: x, Top: y, Right: x + sc(statsCellW) - 1, Bottom: y + sc(statsCellH) - 1}
+			brush, _, _ := procCreateSolidBrush.Call(uintptr(heatColor(s.heatmap.hours[day][hour])))
+			procFillRect.Call(hdc, uintptr(unsafe.Pointer(&cell)), brush)
+			procDeleteObject.Call(brush)
+		}
+	}
+
+	statsMu.Lock()
+	energy := s.energy
+	statsMu.Unlock()
+	summary := s.summary + "\n" + energy.describe(s.heatmap.total)
+
+	y := top + sc(statsDays*statsCellH+8)
+	text(summary, windows.Rect{Left: sc(statsMargin), Top: y, Right: sc(statsClientWidth - statsMargin), Bottom: y + sc(statsSummaryH)}, DT_WORDBREAK)
+}
+
+// editEnergy asks for new energy settings and redraws the estimate.
+func (s *statsWindow) editEnergy(hwnd windows.HWND) {
+	statsMu.Lock()
+	energy := s.energy
+	statsMu.Unlock()
+
+	energy, ok := askEnergy(energy)
+	if !ok {
+		return
+	}
+	statsMu.Lock()
+	s.energy = energy
+	statsMu.Unlock()
+	s.energyCh <- energy
+	procInvalidateRect.Call(uintptr(hwnd), 0, 1)
+}
@@ -0,0 +1,113 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Status Report ---
+
+// statusReport lists the manual session and every satisfied trigger with
+// the flags it requests and how long it has held, so that overlapping rules
+// can be debugged.
+func (a *app) statusReport() string {
+	var b strings.Builder
+	now := time.Now()
+
+	if a.active() {
+		fmt.Fprintf(&b, "Manual session: %s, started from %s at %s\n", a.modeName(), a.sessionSource, a.session.Started.Format("15:04"))
+		if a.sessionNote != "" {
+			b.WriteString("    " + a.sessionNote + "\n")
+		}
+		if a.sessionOnEnd != "" {
+			b.WriteString("    Will " + a.sessionOnEnd + " when it ends\n")
+		}
+		if a.cfg.PreventLock && !a.paused() {
+			b.WriteString("    Preventing the idle lock with a display power request\n")
+		}
+		if a.sessionSimulate != "" && !a.paused() {
+			fmt.Fprintf(&b, "    Simulating %s activity every %s\n", a.sessionSimulate, simulateInterval)
+		}
+		held := now.Sub(a.session.Started)
+		switch {
+		case a.paused() && a.infinite():
+			b.WriteString("    Paused · sleep is allowed until it is resumed\n\n")
+		case a.paused():
+			fmt.Fprintf(&b, "    Paused with %s left · sleep is allowed until it is resumed\n\n", formatDuration(a.timeLeft()))
+		case a.infinite():
+			fmt.Fprintf(&b, "    Keeps %s awake · held %s · no time limit\n\n",
+				flagsText(a.sessionFlags()), formatDuration(held))
+		default:
+			fmt.Fprintf(&b, "    Keeps %s awake · held %s · %s left\n\n",
+				flagsText(a.sessionFlags()), formatDuration(held), formatDuration(a.timeLeft()))
+		}
+	}
+
+	names := make([]string, 0, len(a.activeTriggers))
+	for name := range a.activeTriggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := a.activeTriggers[name]
+		b.WriteString("Trigger: " + name)
+		if t.detail != "" {
+			b.WriteString(" (" + t.detail + ")")
+		}
+		b.WriteString("\n")
+
+		held := now.Sub(t.since)
+		switch {
+		case t.capped:
+			fmt.Fprintf(&b, "    Condition met for %s, but released after its %s maximum\n\n",
+				formatDuration(held), formatFriendlyDuration(t.rule.maxDuration))
+		case t.rule.maxDuration > 0:
+			fmt.Fprintf(&b, "    Keeps %s awake · held %s · released after %s\n\n",
+				flagsText(t.rule.flags), formatDuration(held), formatFriendlyDuration(t.rule.maxDuration))
+		default:
+			fmt.Fprintf(&b, "    Keeps %s awake · held %s\n\n", flagsText(t.rule.flags), formatDuration(held))
+		}
+	}
+
+	if b.Len() == 0 {
+		return "Nothing is keeping the system awake. Sleep is allowed."
+	}
+	return "Now keeping awake because of:\n\n" + strings.TrimSuffix(b.String(), "\n")
+}
+
+// flagsText names the execution state flags, e.g. "system and display".
+func flagsText(flags uint32) string {
+	switch {
+	case flags&ES_SYSTEM_REQUIRED != 0 && flags&ES_DISPLAY_REQUIRED != 0:
+		return "system and display"
+	case flags&ES_DISPLAY_REQUIRED != 0:
+		return "display"
+	case flags&ES_SYSTEM_REQUIRED != 0:
+		return "system"
+	default:
+		return "nothing"
+	}
+}
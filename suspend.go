@@ -0,0 +1,140 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Suspend and Resume ---
+
+// The PC can sleep despite a session: closing the lid, pressing the power
+// button or a critical battery all override keep-awake requests. The
+// countdown stands still while the PC is asleep: after a suspend, the next
+// tick compares the wall clock with the unbiased interrupt time, which
+// stops during sleep, and extends the session by the difference, then
+// makes the keep-awake request again. Until sessions keep their end time,
+// and a clock that jumps without a suspend (a manual change or a time
+// sync) extends nothing. A hidden window hears the power broadcasts so
+// that this happens as soon as the PC resumes.
+
+var procQueryUnbiasedInterruptTime = modkernel32.NewProc("QueryUnbiasedInterruptTime")
+
+const (
+	WM_POWERBROADCAST      = 0x0218
+	PBT_APMSUSPEND         = 0x0004
+	PBT_APMRESUMESUSPEND   = 0x0007
+	PBT_APMRESUMEAUTOMATIC = 0x0012
+
+	powerWatcherClassName = "EspressoPowerWatcher"
+
+	// minSuspend is the shortest sleep worth reconciling; shorter
+	// differences are clock noise.
+	minSuspend = 5 * time.Second
+)
+
+// resumedCh tells the main loop that the system resumed.
+var resumedCh = make(chan struct{}, 1)
+
+// suspended is set when the system suspends, until checkSuspend accounts
+// for the sleep.
+var suspended atomic.Bool
+
+// startPowerWatcher creates the hidden window that hears about suspend
+// and resume.
+func startPowerWatcher() {
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		if _, err := createWindow(powerWatcherClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0, powerWndProc); err != nil {
+			logWarnf("could not watch for suspend and resume: %v", err)
+			return
+		}
+		runMessageLoop(0)
+	}()
+}
+
+func powerWndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	if msg != WM_POWERBROADCAST {
+		return defWindowProc(hwnd, msg, wParam, lParam)
+	}
+	switch wParam {
+	case PBT_APMSUSPEND:
+		logInfof("System suspending")
+		suspended.Store(true)
+	case PBT_APMRESUMEAUTOMATIC, PBT_APMRESUMESUSPEND:
+		select {
+		case resumedCh <- struct{}{}:
+		default:
+		}
+	}
+	return 1
+}
+
+// awakeTime returns the time the system has been running, without the
+// time spent asleep.
+func awakeTime() time.Duration {
+	var t uint64 // 100 ns units
+	procQueryUnbiasedInterruptTime.Call(uintptr(unsafe.Pointer(&t)))
+	return time.Duration(t * 100)
+}
+
+// checkSuspend reconciles the session if the PC slept since it last ran.
+// It runs on every tick and on resume.
+func (a *app) checkSuspend() {
+	wall, awake := time.Now().Round(0), awakeTime() // the wall clock runs during sleep
+	if !a.lastTick.IsZero() {
+		if slept := wall.Sub(a.lastTick) - (awake - a.lastAwake); slept >= minSuspend {
+			if suspended.Swap(false) {
+				a.resumed(slept.Round(time.Second))
+			} else {
+				logInfof("The clock jumped by %s without a suspend; the session is left as it is", formatFriendlyDuration(slept))
+			}
+		}
+	}
+	a.lastTick, a.lastAwake = wall, awake
+}
+
+// resumed extends a running timed session, other than an Until session,
+// by the time the PC slept and asks to keep awake again.
+func (a *app) resumed(slept time.Duration) {
+	logInfof("System resumed after %s asleep", formatFriendlyDuration(slept))
+	if !a.active() || a.paused() {
+		a.applyState()
+		return
+	}
+	if a.infinite() || a.sessionUntil {
+		logWarnf("The PC slept for %s during %s", formatFriendlyDuration(slept), a.modeName())
+		a.applyState()
+		return
+	}
+	a.session.Extend(slept)
+	logWarnf("The PC slept for %s during %s; it now ends at %s", formatFriendlyDuration(slept), a.modeName(), a.sessionEnd().Format("15:04"))
+	a.applyState()
+	go showToast(tagSession, tr("suspend.title"),
+		tr("suspend.extended", formatFriendlyDuration(slept), a.modeName(), a.sessionEnd().Format("15:04")), iconPath())
+}
@@ -0,0 +1,212 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+// --- API Tokens ---
+
+const (
+	apiTokenPrefix = "esp_"
+	maxAPITokens   = 5
+)
+
+// APIToken is a credential for the control API. Only the SHA-256 of the
+// token is stored; the token itself is shown once, when it is generated.
+type APIToken struct {
+	ID      string    `json:"id"` // short public identifier shown in the menu
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// newAPIToken returns a fresh random token and its stored form. If id is
+// empty a new one is generated; rotation keeps the old id.
+func newAPIToken(id string) (string, APIToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", APIToken{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if id == "" {
+		b := make([]byte, 3)
+		if _, err := rand.Read(b); err != nil {
+			return "", APIToken{}, fmt.Errorf("failed to generate token id: %w", err)
+		}
+		id = hex.EncodeToString(b)
+	}
+
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, APIToken{ID: id, Hash: hashAPIToken(token), Created: time.Now()}, nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyAPIToken returns the stored token matching token, if any.
+func verifyAPIToken(tokens []APIToken, token string) (APIToken, bool) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return APIToken{}, false
+	}
+	h := []byte(hashAPIToken(token))
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare(h, []byte(t.Hash)) == 1 {
+			return t, true
+		}
+	}
+	return APIToken{}, false
+}
+
+// --- API Token Menu ---
+
+type tokenAction struct {
+	slot     int
+	revoke   bool   // rotate otherwise
+	generate bool   // add a new token; slot is unused
+	id       string // from the settings window: the token listed in slot
+}
+
+// tokenMenu is the "API Tokens" submenu. systray cannot remove items, so
+// every possible token has a fixed slot that is hidden while unused.
+type tokenMenu struct {
+	root     *systray.MenuItem
+	generate *systray.MenuItem
+	guest    *systray.MenuItem
+	slots    [maxAPITokens]*systray.MenuItem
+	actionCh chan tokenAction
+}
+
+func newTokenMenu() *tokenMenu {
+	m := &tokenMenu{actionCh: make(chan tokenAction)}
+	m.root = addMenuItem(nil, "menu.tokens")
+	m.generate = addMenuItem(m.root, "menu.tokens.generate")
+	m.guest = addMenuItem(m.root, "menu.tokens.guest")
+
+	for i := range m.slots {
+		slot := m.root.AddSubMenuItem("", "")
+		rotate := addMenuItem(slot, "menu.tokens.rotate")
+		revoke := addMenuItem(slot, "menu.tokens.revoke")
+		go func() {
+			for {
+				select {
+				case <-rotate.ClickedCh:
+					m.actionCh <- tokenAction{slot: i}
+				case <-revoke.ClickedCh:
+					m.actionCh <- tokenAction{slot: i, revoke: true}
+				}
+			}
+		}()
+		slot.Hide()
+		m.slots[i] = slot
+	}
+	return m
+}
+
+func (m *tokenMenu) update(tokens []APIToken) {
+	for i, slot := range m.slots {
+		if i < len(tokens) {
+			t := tokens[i]
+			slot.SetTitle(tr("menu.tokens.slot", t.ID, t.Created.Format("2006-01-02")))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+	if len(tokens) >= maxAPITokens {
+		m.generate.Disable()
+	} else {
+		m.generate.Enable()
+	}
+}
+
+// generateAPIToken adds a new token to the config and shows it once.
+func (a *app) generateAPIToken() {
+	if len(a.cfg.APITokens) >= maxAPITokens {
+		return
+	}
+	token, entry, err := newAPIToken("")
+	if err != nil {
+		go showMessage(tr("menu.tokens"), err.Error())
+		return
+	}
+	a.cfg.APITokens = append(a.cfg.APITokens, entry)
+	a.tokensChanged()
+	go revealAPIToken(entry.ID, token)
+}
+
+// tokensChanged saves the tokens and shows them in the menu and the
+// settings window.
+func (a *app) tokensChanged() {
+	a.saveConfig()
+	a.tokenMenu.update(a.cfg.APITokens)
+	updateSettingsTokens(a.cfg.APITokens)
+}
+
+func (a *app) handleTokenAction(act tokenAction) {
+	if act.generate {
+		a.generateAPIToken()
+		return
+	}
+	if act.slot < 0 || act.slot >= len(a.cfg.APITokens) {
+		return
+	}
+	id := a.cfg.APITokens[act.slot].ID
+	if act.id != "" && act.id != id {
+		return // the list changed while the window showed it
+	}
+
+	if act.revoke {
+		a.cfg.APITokens = append(a.cfg.APITokens[:act.slot:act.slot], a.cfg.APITokens[act.slot+1:]...)
+		a.tokensChanged()
+		go showMessage(tr("menu.tokens"), tr("tokens.revoked", id))
+		return
+	}
+
+	token, entry, err := newAPIToken(id)
+	if err != nil {
+		go showMessage(tr("menu.tokens"), err.Error())
+		return
+	}
+	a.cfg.APITokens[act.slot] = entry
+	a.tokensChanged()
+	go revealAPIToken(id, token)
+}
+
+// revealAPIToken copies token to the clipboard and shows it. This is the
+// only time the token is available.
+func revealAPIToken(id, token string) {
+	copied := tr("tokens.copied")
+	if err := copyToClipboard(token); err != nil {
+		copied = tr("tokens.not_copied")
+	}
+	showMessage(tr("menu.tokens"), tr("tokens.new", id, token, copied))
+}
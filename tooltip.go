@@ -0,0 +1,135 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+// --- Tray Tooltip ---
+
+// The tooltip names what keeps the PC awake. When a session and triggers,
+// or several triggers, hold at once, it lists all of them and when the
+// last of those with a time limit ends, rather than one mode's countdown.
+
+// maxTooltip is the capacity of NOTIFYICONDATA.szTip, without the NUL.
+const maxTooltip = 127
+
+// updateTooltip sets the tooltip for the current state.
+func (a *app) updateTooltip() {
+	setTooltip(a.tooltipText())
+}
+
+func (a *app) tooltipText() string {
+	session := a.active() && !a.paused()
+	triggers := a.triggerHolders()
+	n := len(triggers)
+	if session {
+		n++
+	}
+	for _, t := range quickToggles {
+		if a.quickFlags&t.flag != 0 {
+			n++
+		}
+	}
+	if n > 1 {
+		return a.holdersTooltip(session, triggers)
+	}
+	switch {
+	case session && a.infinite():
+		return tr("tooltip.infinite")
+	case session:
+		return tr("tooltip.timed", a.modeName(), formatDuration(a.timeLeft()))
+	case len(triggers) > 0:
+		return tr("tooltip.triggered", a.triggerReasons())
+	case a.quickFlags != 0:
+		return tr("tooltip.quick", a.quickToggleNames())
+	case a.paused():
+		return tr("tooltip.paused", a.modeName())
+	default:
+		return tr("tooltip.decaf")
+	}
+}
+
+// holdersTooltip summarizes several holders, e.g. "2 holders: OBS
+// recording, Latte 25m — last timer ends 18:40".
+func (a *app) holdersTooltip(session bool, triggers []string) string {
+	var labels []string
+	var last time.Time
+	if session {
+		if a.infinite() {
+			labels = append(labels, a.modeName())
+		} else {
+			labels = append(labels, fmt.Sprintf("%s %s", a.modeName(), formatFriendlyDuration(a.timeLeft().Round(time.Minute))))
+			last = a.sessionEnd()
+		}
+	}
+	for _, name := range triggers {
+		t := a.activeTriggers[name]
+		if t.detail != "" {
+			labels = append(labels, fmt.Sprintf("%s (%s)", name, t.detail))
+		} else {
+			labels = append(labels, name)
+		}
+		if t.rule.maxDuration > 0 {
+			if end := t.since.Add(t.rule.maxDuration); end.After(last) {
+				last = end
+			}
+		}
+	}
+	if names := a.quickToggleNames(); names != "" {
+		labels = append(labels, names)
+	}
+	list := strings.Join(labels, ", ")
+	if last.IsZero() {
+		return tr("tooltip.holders", len(labels), list)
+	}
+	return tr("tooltip.holders_until", len(labels), list, last.Format("15:04"))
+}
+
+// triggerHolders returns the names of the holding triggers, sorted.
+func (a *app) triggerHolders() []string {
+	names := make([]string, 0, len(a.activeTriggers))
+	for name := range a.activeTriggers {
+		if a.triggerHolds(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setTooltip sets the tray tooltip, shortened to fit. systray copies it
+// into a fixed buffer without checking its length.
+func setTooltip(text string) {
+	r := []rune(text)
+	if len(r) > maxTooltip {
+		// Counting runes is close enough: tooltip text is rarely outside
+		// the Basic Multilingual Plane.
+		text = string(r[:maxTooltip-1]) + "…"
+	}
+	systray.SetTooltip(text)
+}
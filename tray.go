@@ -0,0 +1,173 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Tray Icon Recovery ---
+
+// At login the shell may not be ready for notification icons yet, and
+// explorer.exe can restart at any time. Espresso waits for the taskbar
+// before adding its icon and checks regularly that the icon still exists,
+// asking systray to add it again if not. systray cannot retry a failed
+// start itself, so if the icon never appears Espresso restarts with
+// backoff rather than run with no UI. Whenever the icon is added again the
+// main loop reapplies the icon, tooltip and menu for the current state.
+
+const (
+	trayReadyTimeout  = 20 * time.Second
+	trayCheckInterval = 30 * time.Second
+	trayShellInterval = 5 * time.Second // how often to look for a new taskbar
+	trayTaskbarWait   = 2 * time.Minute
+	trayMaxAttempts   = 5
+	trayAttemptEnv    = "ESPRESSO_TRAY_ATTEMPT" // set on restarts
+)
+
+var (
+	procRegisterWindowMessageW = user32.NewProc("RegisterWindowMessageW")
+
+	// trayReady is closed by onReady.
+	trayReady = make(chan struct{})
+
+	// trayRestoredCh tells the main loop that the icon was added again.
+	trayRestoredCh = make(chan struct{}, 1)
+)
+
+// prepareTray runs before systray starts. It waits for the taskbar, after
+// a backoff delay if this is a restart, and watches for the icon.
+func prepareTray() {
+	attempt, _ := strconv.Atoi(os.Getenv(trayAttemptEnv))
+	os.Unsetenv(trayAttemptEnv)
+	if attempt > 0 {
+		time.Sleep(time.Duration(1<<attempt) * time.Second)
+	}
+	waitForTaskbar(trayTaskbarWait)
+	go watchTrayStartup(attempt)
+}
+
+// waitForTaskbar waits up to limit for the taskbar window to exist.
+func waitForTaskbar(limit time.Duration) {
+	deadline := time.Now().Add(limit)
+	delay := 500 * time.Millisecond
+	for time.Now().Before(deadline) {
+		if taskbarWindow() != 0 {
+			return
+		}
+		logInfof("Waiting for the taskbar...")
+		time.Sleep(delay)
+		delay = min(delay*2, 8*time.Second)
+	}
+}
+
+// watchTrayStartup restarts Espresso if onReady has not run within
+// trayReadyTimeout, which is what happens when systray fails to add the
+// icon.
+func watchTrayStartup(attempt int) {
+	select {
+	case <-trayReady:
+		return
+	case <-time.After(trayReadyTimeout):
+	}
+	if attempt+1 >= trayMaxAttempts {
+		showMessage("Espresso", tr("tray.failed"))
+		os.Exit(1)
+	}
+
+	logWarnf("tray icon not created; restarting (attempt %d of %d)", attempt+2, trayMaxAttempts)
+	if instanceMutex != 0 {
+		windows.CloseHandle(instanceMutex)
+		instanceMutex = 0
+	}
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", trayAttemptEnv, attempt+1))
+	if err := cmd.Start(); err != nil {
+		showMessage("Espresso", tr("tray.restart_failed", err))
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// taskbarWindow returns the taskbar window, or 0 while there is none.
+func taskbarWindow() uintptr {
+	cls, _ := windows.UTF16PtrFromString("Shell_TrayWnd")
+	hwnd, _, _ := procFindWindowW.Call(uintptr(unsafe.Pointer(cls)), 0)
+	return hwnd
+}
+
+// keepTrayIcon checks every trayCheckInterval that the icon exists and, if
+// it does not, sends systray the TaskbarCreated message that explorer.exe
+// broadcasts on restart, so that it adds the icon again. It catches the
+// broadcasts that arrive while the shell is not ready yet. A new taskbar
+// window means explorer.exe restarted, and systray has added the icon
+// again by itself; either way the main loop is told to refresh it.
+func keepTrayIcon() {
+	name, _ := windows.UTF16PtrFromString("TaskbarCreated")
+	wmTaskbarCreated, _, _ := procRegisterWindowMessageW.Call(uintptr(unsafe.Pointer(name)))
+	taskbar := taskbarWindow()
+	lastCheck := time.Now()
+	for range time.Tick(trayShellInterval) {
+		hwnd := trayWindow()
+		if hwnd == 0 {
+			continue
+		}
+		if tb := taskbarWindow(); tb != 0 && tb != taskbar {
+			logInfof("Taskbar restarted; restoring the tray icon")
+			taskbar = tb
+			notifyTrayRestored()
+			continue
+		}
+		if time.Since(lastCheck) < trayCheckInterval {
+			continue
+		}
+		lastCheck = time.Now()
+		nid := notifyIconData{Wnd: hwnd, ID: systrayIconID}
+		nid.Size = uint32(unsafe.Sizeof(nid))
+		if r, _, _ := procShellNotifyIconW.Call(NIM_MODIFY, uintptr(unsafe.Pointer(&nid))); r != 0 {
+			continue
+		}
+		logWarnf("tray icon missing; adding it again")
+		procPostMessageW.Call(uintptr(hwnd), wmTaskbarCreated, 0, 0)
+		notifyTrayRestored()
+	}
+}
+
+func notifyTrayRestored() {
+	select {
+	case trayRestoredCh <- struct{}{}:
+	default: // a refresh is already pending
+	}
+}
+
+// refreshTray reapplies the icon, tooltip and menu after the icon was added
+// again, so that the new icon does not show the state from before the
+// shell restarted.
+func (a *app) refreshTray() {
+	a.applyState()
+}
@@ -0,0 +1,166 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+// --- Tray Icon Countdown ---
+
+// With tray_countdown on, timed sessions replace the cup in the tray with
+// the time left, drawn into an icon generated in memory: minutes below
+// 100 minutes, then whole hours ("3h"). The icon is only redrawn when the
+// text changes.
+
+const countdownIconSize = 32
+
+// countdownGlyphs are 3x5 pixel glyphs, one string per row.
+var countdownGlyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'h': {"#..", "#..", "###", "#.#", "#.#"},
+	'!': {".#.", ".#.", ".#.", "...", ".#."},
+}
+
+var (
+	countdownBackground = [4]byte{0x37, 0x4E, 0x6F, 0xFF} // BGRA, coffee brown
+	countdownForeground = [4]byte{0xFF, 0xFF, 0xFF, 0xFF}
+	warningBackground   = [4]byte{0x1C, 0x1C, 0xD0, 0xFF} // red
+)
+
+// countdownText is what the icon shows with remaining left.
+func countdownText(remaining time.Duration) string {
+	minutes := int((remaining + time.Minute - 1) / time.Minute) // rounded up
+	if minutes < 100 {
+		return fmt.Sprint(minutes)
+	}
+	return fmt.Sprintf("%dh", minutes/60)
+}
+
+// countdownIcon renders text as a 32x32 ICO file.
+func countdownIcon(text string) []byte {
+	return badgeIcon(text, countdownBackground)
+}
+
+// badgeIcon renders text in white on background as a 32x32 ICO file.
+func badgeIcon(text string, background [4]byte) []byte {
+	const size = countdownIconSize
+	var pixels [size][size][4]byte // top-down BGRA
+	for y := range size {
+		for x := range size {
+			// Cut the corners for a rounded square.
+			if (x < 2 || x >= size-2) && (y < 2 || y >= size-2) {
+				continue
+			}
+			pixels[y][x] = background
+		}
+	}
+
+	glyphs := []rune(text)
+	scale, gap := 4, 4
+	if len(glyphs) > 2 {
+		scale, gap = 3, 2
+	}
+	width := len(glyphs)*3*scale + (len(glyphs)-1)*gap
+	left, top := (size-width)/2, (size-5*scale)/2
+	for i, r := range glyphs {
+		glyph := countdownGlyphs[r]
+		x0 := left + i*(3*scale+gap)
+		for row, line := range glyph {
+			for col, c := range line {
+				if c != '#' {
+					continue
+				}
+				for dy := range scale {
+					for dx := range scale {
+						pixels[top+row*scale+dy][x0+col*scale+dx] = countdownForeground
+					}
+				}
+			}
+		}
+	}
+
+	// One 32 bpp bitmap image. Its height counts the AND mask too, which
+	// stays empty since the alpha channel decides transparency.
+	const (
+		headerSize = 6 + 16
+		infoSize   = 40
+		xorSize    = size * size * 4
+		andSize    = size * size / 8
+	)
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	le([3]uint16{0, 1, 1})                                  // ICONDIR: reserved, type icon, one image
+	le([4]uint8{size, size, 0, 0})                          // ICONDIRENTRY: width, height, no palette
+	le([2]uint16{1, 32})                                    // planes, bits per pixel
+	le([2]uint32{infoSize + xorSize + andSize, headerSize}) // size, offset
+	le(struct {
+		Size, Width, Height    int32
+		Planes, BitCount       uint16
+		Compression, SizeImage uint32
+		XPPM, YPPM             int32
+		ClrUsed, ClrImportant  uint32
+	}{infoSize, size, 2 * size, 1, 32, 0, xorSize + andSize, 0, 0, 0, 0})
+	for y := size - 1; y >= 0; y-- { // bottom-up
+		for x := range size {
+			b.Write(pixels[y][x][:])
+		}
+	}
+	b.Write(make([]byte, andSize))
+	return b.Bytes()
+}
+
+// setActiveIcon shows the cup (see cupIconFor), or the countdown for timed sessions with
+// tray_countdown on, or a warning while Windows refuses to keep awake.
+func (a *app) setActiveIcon() {
+	if a.inhibitFailed {
+		if a.iconText != "!" {
+			a.iconText = "!"
+			systray.SetIcon(badgeIcon("!", warningBackground))
+		}
+		return
+	}
+	if !a.cfg.TrayCountdown || a.infinite() || !a.active() || a.paused() {
+		a.iconText = ""
+		systray.SetIcon(a.cupIconFor())
+		return
+	}
+	text := countdownText(a.timeLeft())
+	if text == a.iconText {
+		return
+	}
+	a.iconText = text
+	systray.SetIcon(countdownIcon(text))
+}
@@ -0,0 +1,452 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Calendar Trigger ---
+
+// calendarTrigger holds during the meetings of an iCalendar feed (a .ics
+// file or an http(s)/webcal URL, such as an Outlook or Google "secret
+// address"), from bufferBefore each meeting starts to bufferAfter it ends.
+// Meetings whose buffered windows touch or overlap are merged, so
+// back-to-back meetings keep the system awake in one continuous stretch.
+// With keywords, only the meetings whose title contains one of them, such
+// as "demo" or "presentation", count.
+type calendarTrigger struct {
+	source       string
+	bufferBefore time.Duration
+	bufferAfter  time.Duration
+	keywords     []string // lower case
+
+	client   *http.Client
+	events   []calendarEvent
+	fetched  time.Time
+	fetchErr error
+}
+
+const (
+	defaultCalendarBufferBefore = 5 * time.Minute
+	defaultCalendarBufferAfter  = 10 * time.Minute
+	calendarRefresh             = 15 * time.Minute
+	calendarMaxSize             = 16 << 20
+)
+
+type calendarEvent struct {
+	summary    string
+	start, end time.Time
+}
+
+// calendarWindow is a stretch of one or more buffered meetings.
+type calendarWindow struct {
+	start, end time.Time
+	summaries  []string
+}
+
+func newCalendarTrigger(tc TriggerConfig) (*calendarTrigger, error) {
+	if tc.Calendar == "" {
+		return nil, fmt.Errorf("calendar trigger needs a \"calendar\" file or URL")
+	}
+	t := &calendarTrigger{
+		source:       tc.Calendar,
+		bufferBefore: defaultCalendarBufferBefore,
+		bufferAfter:  defaultCalendarBufferAfter,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, k := range tc.Keywords {
+		if k = strings.TrimSpace(k); k != "" {
+			t.keywords = append(t.keywords, strings.ToLower(k))
+		}
+	}
+	for _, b := range []struct {
+		spec string
+		d    *time.Duration
+	}{{tc.BufferBefore, &t.bufferBefore}, {tc.BufferAfter, &t.bufferAfter}} {
+		if b.spec == "" {
+			continue
+		}
+		d, err := time.ParseDuration(b.spec)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid calendar buffer %q", b.spec)
+		}
+		*b.d = d
+	}
+	return t, nil
+}
+
+func (t *calendarTrigger) check() (bool, string, error) {
+	if time.Since(t.fetched) >= calendarRefresh {
+		events, err := t.fetch()
+		t.fetched, t.fetchErr = time.Now(), err
+		if err == nil {
+			t.events = t.matching(events)
+		}
+	}
+	if t.fetchErr != nil && t.events == nil {
+		return false, "", t.fetchErr
+	}
+
+	now := time.Now()
+	for _, w := range mergeCalendarWindows(t.events, t.bufferBefore, t.bufferAfter) {
+		if !now.Before(w.start) && now.Before(w.end) {
+			return true, fmt.Sprintf("%s until %s", strings.Join(w.summaries, ", "), w.end.Format("15:04")), nil
+		}
+	}
+	return false, "", nil
+}
+
+// matching returns the events whose summary contains one of the keywords,
+// or all of them if there are none.
+func (t *calendarTrigger) matching(events []calendarEvent) []calendarEvent {
+	if len(t.keywords) == 0 {
+		return events
+	}
+	matched := []calendarEvent{}
+	for _, e := range events {
+		summary := strings.ToLower(e.summary)
+		for _, k := range t.keywords {
+			if strings.Contains(summary, k) {
+				matched = append(matched, e)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// remoteCalendar reports whether a calendar source is downloaded from the
+// network rather than read from a file.
+func remoteCalendar(src string) bool {
+	for _, prefix := range []string{"http://", "https://", "webcal://"} {
+		if strings.HasPrefix(strings.ToLower(src), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// calendarHost names a remote calendar by its host, leaving out the
+// secret that calendar addresses usually carry.
+func calendarHost(src string) string {
+	if u, err := url.Parse(src); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "feed"
+}
+
+func (t *calendarTrigger) fetch() ([]calendarEvent, error) {
+	var r io.ReadCloser
+	src := t.source
+	if rest, ok := strings.CutPrefix(src, "webcal://"); ok {
+		src = "https://" + rest
+	}
+	if remoteCalendar(src) {
+		resp, err := t.client.Get(src)
+		if err != nil {
+			// A *url.Error would repeat the whole address, secret and all.
+			var ue *url.Error
+			if errors.As(err, &ue) {
+				err = ue.Err
+			}
+			return nil, fmt.Errorf("calendar %s unreachable: %w", calendarHost(src), err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("calendar returned %s", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	// Expanding recurrences a day either side of now is enough for a
+	// refresh period of minutes.
+	now := time.Now()
+	return parseICS(io.LimitReader(r, calendarMaxSize), now.AddDate(0, 0, -1), now.AddDate(0, 0, 1))
+}
+
+// mergeCalendarWindows buffers each event and merges the windows that
+// touch or overlap.
+func mergeCalendarWindows(events []calendarEvent, before, after time.Duration) []calendarWindow {
+	windows := make([]calendarWindow, 0, len(events))
+	for _, e := range events {
+		windows = append(windows, calendarWindow{start: e.start.Add(-before), end: e.end.Add(after), summaries: []string{e.summary}})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start.Before(windows[j].start) })
+
+	var merged []calendarWindow
+	for _, w := range windows {
+		if n := len(merged); n > 0 && !w.start.After(merged[n-1].end) {
+			last := &merged[n-1]
+			if w.end.After(last.end) {
+				last.end = w.end
+			}
+			last.summaries = appendUnique(last.summaries, w.summaries[0])
+			continue
+		}
+		merged = append(merged, w)
+	}
+	return merged
+}
+
+// --- iCalendar Parsing ---
+
+// icsProperty is one content line, e.g. DTSTART;TZID=Europe/Madrid:20261015T090000.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICS returns the timed events of an iCalendar stream that overlap
+// [from, to). All-day, cancelled and free ("transparent") events are
+// skipped. Daily and weekly recurrences are expanded with INTERVAL, BYDAY,
+// COUNT, UNTIL and EXDATE; other recurrences only count their first
+// occurrence.
+func parseICS(r io.Reader, from, to time.Time) ([]calendarEvent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []calendarEvent
+	var props []icsProperty
+	inEvent := false
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent, props = true, nil
+		case line == "END:VEVENT":
+			inEvent = false
+			events = append(events, expandICSEvent(props, from, to)...)
+		case inEvent:
+			if p, ok := parseICSLine(line); ok {
+				props = append(props, p)
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfoldICS joins continuation lines, which start with a space or tab.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if n := len(lines); n > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[n-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+func parseICSLine(line string) (icsProperty, bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return icsProperty{}, false
+	}
+	parts := strings.Split(head, ";")
+	p := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+// parseICSTime parses a DATE-TIME value in UTC ("Z"), in its TZID zone or
+// in local time. Zone names that are not IANA names, as Outlook writes
+// them, fall back to local time. DATE values report false.
+func parseICSTime(p icsProperty) (time.Time, bool) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len("20060102") {
+		return time.Time{}, false
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, err == nil
+	}
+	loc := time.Local
+	if tz := p.params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	return t, err == nil
+}
+
+func expandICSEvent(props []icsProperty, from, to time.Time) []calendarEvent {
+	var e calendarEvent
+	var rrule string
+	var exdates []time.Time
+	hasStart, hasEnd := false, false
+	for _, p := range props {
+		switch p.name {
+		case "SUMMARY":
+			e.summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(p.value)
+		case "DTSTART":
+			e.start, hasStart = parseICSTime(p)
+		case "DTEND":
+			e.end, hasEnd = parseICSTime(p)
+		case "DURATION":
+			if d, ok := parseICSDuration(p.value); ok && hasStart {
+				e.end, hasEnd = e.start.Add(d), true
+			}
+		case "STATUS":
+			if p.value == "CANCELLED" {
+				return nil
+			}
+		case "TRANSP":
+			if p.value == "TRANSPARENT" {
+				return nil
+			}
+		case "RRULE":
+			rrule = p.value
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				if t, ok := parseICSTime(icsProperty{params: p.params, value: v}); ok {
+					exdates = append(exdates, t)
+				}
+			}
+		}
+	}
+	if !hasStart || !hasEnd || !e.end.After(e.start) {
+		return nil
+	}
+	if e.summary == "" {
+		e.summary = "Meeting"
+	}
+
+	var events []calendarEvent
+	for _, start := range icsOccurrences(e.start, rrule, to) {
+		end := start.Add(e.end.Sub(e.start))
+		if !end.After(from) || excluded(start, exdates) {
+			continue
+		}
+		events = append(events, calendarEvent{summary: e.summary, start: start, end: end})
+	}
+	return events
+}
+
+func excluded(t time.Time, exdates []time.Time) bool {
+	for _, x := range exdates {
+		if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseICSDuration parses the time part of a DURATION such as PT1H30M.
+func parseICSDuration(s string) (time.Duration, bool) {
+	s, ok := strings.CutPrefix(s, "PT")
+	if !ok || s == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(strings.ToLower(s))
+	return d, err == nil
+}
+
+// icsOccurrences lists the starts of a possibly recurring event, up to to.
+func icsOccurrences(start time.Time, rrule string, to time.Time) []time.Time {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(rrule, ";") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			rule[k] = v
+		}
+	}
+	freq := rule["FREQ"]
+	if freq != "DAILY" && freq != "WEEKLY" {
+		if start.Before(to) {
+			return []time.Time{start}
+		}
+		return nil
+	}
+
+	interval, _ := strconv.Atoi(rule["INTERVAL"])
+	if interval < 1 {
+		interval = 1
+	}
+	count, _ := strconv.Atoi(rule["COUNT"])
+	until := to
+	if u, ok := parseICSTime(icsProperty{value: rule["UNTIL"]}); ok && u.Before(until) {
+		until = u.Add(time.Second) // UNTIL is inclusive
+	}
+	days := make(map[time.Weekday]bool)
+	for _, d := range strings.Split(rule["BYDAY"], ",") {
+		if wd, ok := icsWeekdays[strings.TrimLeft(d, "+-0123456789")]; ok {
+			days[wd] = true
+		}
+	}
+	if freq == "WEEKLY" && len(days) == 0 {
+		days[start.Weekday()] = true
+	}
+
+	// Step day by day so that the time of day stays put across DST
+	// changes, counting weeks from the week of the first occurrence.
+	var starts []time.Time
+	n := 0
+	for day := 0; ; day++ {
+		t := start.AddDate(0, 0, day)
+		if !t.Before(until) || (count > 0 && n >= count) {
+			break
+		}
+		var match bool
+		if freq == "DAILY" {
+			match = day%interval == 0
+		} else {
+			week := (day + int(start.Weekday()+6)%7) / 7
+			match = week%interval == 0 && days[t.Weekday()]
+		}
+		if match {
+			starts = append(starts, t)
+			n++
+		}
+	}
+	return starts
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
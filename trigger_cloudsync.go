@@ -0,0 +1,67 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// --- Cloud Sync Trigger ---
+
+const (
+	defaultSyncIOThresholdKB = 100
+	defaultSyncCPUThreshold  = 2
+	defaultSyncCooldown      = 60 * time.Second
+)
+
+var cloudSyncClients = []processGroup{
+	{"onedrive", "OneDrive", []string{"OneDrive.exe"}},
+	{"dropbox", "Dropbox", []string{"Dropbox.exe"}},
+	{"googledrive", "Google Drive", []string{"GoogleDriveFS.exe"}},
+}
+
+// cloudSyncTrigger holds while a sync client looks busy. None of the
+// clients expose a documented sync status API, so activity is inferred from
+// the I/O and CPU use of their processes.
+type cloudSyncTrigger struct {
+	clients []processGroup
+	monitor *activityMonitor
+}
+
+func newCloudSyncTrigger(tc TriggerConfig) (*cloudSyncTrigger, error) {
+	clients, err := selectGroups(cloudSyncClients, tc.Clients)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudSyncTrigger{
+		clients: clients,
+		monitor: newActivityMonitor(tc, defaultSyncIOThresholdKB, defaultSyncCPUThreshold, defaultSyncCooldown),
+	}, nil
+}
+
+func (t *cloudSyncTrigger) check() (bool, string, error) {
+	busy, err := t.monitor.poll(t.clients)
+	if err != nil || len(busy) == 0 {
+		return false, "", err
+	}
+	return true, strings.Join(busy, ", "), nil
+}
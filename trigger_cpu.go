@@ -0,0 +1,81 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// --- CPU Load Trigger ---
+
+const (
+	defaultCPULoadThreshold = 50 // percent
+	defaultCPULoadSustain   = 30 * time.Second
+	defaultCPULoadCooldown  = 2 * time.Minute
+)
+
+var procGetSystemTimes = modkernel32.NewProc("GetSystemTimes")
+
+// cpuTrigger holds while overall CPU use has stayed above a threshold for
+// a while, as during a long compile or render, and until it has been
+// lower for the cooldown.
+type cpuTrigger struct {
+	threshold float64 // percent
+	load      sustainedLoad
+
+	idle, total uint64 // 100 ns units at sampledAt
+	sampledAt   time.Time
+}
+
+func newCPUTrigger(tc TriggerConfig) *cpuTrigger {
+	t := &cpuTrigger{
+		threshold: defaultCPULoadThreshold,
+		load:      newSustainedLoad(tc, defaultCPULoadSustain, defaultCPULoadCooldown),
+	}
+	if tc.CPUThreshold > 0 {
+		t.threshold = tc.CPUThreshold
+	}
+	return t
+}
+
+func (t *cpuTrigger) check() (bool, string, error) {
+	var idle, kernel, user uint64 // FILETIMEs; kernel time includes idle time
+	r, _, err := procGetSystemTimes.Call(uintptr(unsafe.Pointer(&idle)), uintptr(unsafe.Pointer(&kernel)), uintptr(unsafe.Pointer(&user)))
+	if r == 0 {
+		return false, "", fmt.Errorf("GetSystemTimes: %w", err)
+	}
+
+	now := time.Now()
+	total := kernel + user
+	var usage float64
+	if !t.sampledAt.IsZero() && total > t.total {
+		usage = 100 * (1 - float64(idle-t.idle)/float64(total-t.total))
+		t.load.sample(usage >= t.threshold, t.sampledAt, now)
+	}
+	t.idle, t.total, t.sampledAt = idle, total, now
+
+	if !t.load.holds(now) {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("CPU %.0f%%", usage), nil
+}
@@ -0,0 +1,156 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Docker Trigger ---
+
+const (
+	defaultDockerHost   = "npipe:////./pipe/docker_engine"
+	composeProjectLabel = "com.docker.compose.project"
+)
+
+// dockerTrigger holds while any of the configured containers or compose
+// projects has a running container. With neither configured, any running
+// container satisfies it.
+type dockerTrigger struct {
+	containers []string
+	projects   []string
+	client     *http.Client
+	baseURL    string
+}
+
+type dockerContainer struct {
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+}
+
+func newDockerTrigger(tc TriggerConfig) *dockerTrigger {
+	host := tc.DockerHost
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	t := &dockerTrigger{
+		containers: tc.Containers,
+		projects:   tc.Projects,
+	}
+
+	transport := &http.Transport{DisableKeepAlives: true}
+	if pipe, ok := strings.CutPrefix(host, "npipe://"); ok {
+		// npipe:////./pipe/docker_engine -> \\.\pipe\docker_engine
+		pipe = strings.ReplaceAll(pipe, "/", `\`)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialPipe(pipe)
+		}
+		t.baseURL = "http://docker"
+	} else {
+		t.baseURL = "http://" + strings.TrimPrefix(host, "tcp://")
+	}
+	t.client = &http.Client{Transport: transport, Timeout: 5 * time.Second}
+	return t
+}
+
+func (t *dockerTrigger) check() (bool, string, error) {
+	resp, err := t.client.Get(t.baseURL + "/containers/json")
+	if err != nil {
+		return false, "", fmt.Errorf("docker engine unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, "", fmt.Errorf("docker engine returned %s", resp.Status)
+	}
+
+	var running []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&running); err != nil {
+		return false, "", fmt.Errorf("failed to decode container list: %w", err)
+	}
+
+	var matched []string
+	for _, c := range running {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		project := c.Labels[composeProjectLabel]
+
+		switch {
+		case len(t.containers) == 0 && len(t.projects) == 0:
+			matched = appendUnique(matched, name)
+		case slices.Contains(t.containers, name):
+			matched = appendUnique(matched, name)
+		case project != "" && slices.Contains(t.projects, project):
+			matched = appendUnique(matched, project)
+		}
+	}
+
+	if len(matched) == 0 {
+		return false, "", nil
+	}
+	return true, strings.Join(matched, ", "), nil
+}
+
+func appendUnique(s []string, v string) []string {
+	if v == "" || slices.Contains(s, v) {
+		return s
+	}
+	return append(s, v)
+}
+
+// --- Named Pipe Transport ---
+
+// pipeConn adapts a named pipe client handle to net.Conn. The handle is
+// opened for overlapped I/O so that deadlines and Close work as expected.
+type pipeConn struct {
+	*os.File
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+func (c pipeConn) LocalAddr() net.Addr  { return pipeAddr(c.Name()) }
+func (c pipeConn) RemoteAddr() net.Addr { return pipeAddr(c.Name()) }
+
+func dialPipe(path string) (net.Conn, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|windows.O_FILE_FLAG_OVERLAPPED, 0)
+	if err != nil {
+		return nil, err
+	}
+	return pipeConn{f}, nil
+}
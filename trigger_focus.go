@@ -0,0 +1,86 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Focus Assist Trigger ---
+
+var (
+	modntdll                 = windows.NewLazySystemDLL("ntdll.dll")
+	procNtQueryWnfStateData  = modntdll.NewProc("NtQueryWnfStateData")
+	wnfQuietHoursProfileName = uint64(0x0D83063EA3BF1C75) // WNF_SHEL_QUIETHOURS_ACTIVE_PROFILE_CHANGED
+)
+
+// Focus Assist profiles as published in the WNF state.
+const (
+	focusAssistOff          = 0
+	focusAssistPriorityOnly = 1
+	focusAssistAlarmsOnly   = 2
+)
+
+// focusAssistTrigger holds while Focus Assist ("Do not disturb" on
+// Windows 11) is on. The shell only publishes its state through an
+// undocumented WNF state name, which is what Windows' own components read.
+type focusAssistTrigger struct{}
+
+func (focusAssistTrigger) check() (bool, string, error) {
+	profile, err := focusAssistProfile()
+	if err != nil {
+		return false, "", err
+	}
+
+	switch profile {
+	case focusAssistOff:
+		return false, "", nil
+	case focusAssistPriorityOnly:
+		return true, "priority only", nil
+	case focusAssistAlarmsOnly:
+		return true, "alarms only", nil
+	default:
+		return true, "", nil
+	}
+}
+
+func focusAssistProfile() (uint32, error) {
+	if err := procNtQueryWnfStateData.Find(); err != nil {
+		return 0, err
+	}
+
+	var changeStamp, profile uint32
+	size := uint32(unsafe.Sizeof(profile))
+	status, _, _ := procNtQueryWnfStateData.Call(
+		uintptr(unsafe.Pointer(&wnfQuietHoursProfileName)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&changeStamp)),
+		uintptr(unsafe.Pointer(&profile)),
+		uintptr(unsafe.Pointer(&size)))
+	if status != 0 {
+		return 0, fmt.Errorf("NtQueryWnfStateData failed: NTSTATUS 0x%08X", uint32(status))
+	}
+	return profile, nil
+}
@@ -0,0 +1,133 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// --- Game Download Trigger ---
+
+const (
+	steamAppsKey = `Software\Valve\Steam\Apps`
+
+	defaultEpicIOThresholdKB = 1024
+	defaultEpicCooldown      = 2 * time.Minute
+)
+
+var gameLaunchers = []processGroup{
+	{"steam", "Steam", []string{"steam.exe"}},
+	{"epic", "Epic Games", []string{"EpicGamesLauncher.exe"}},
+}
+
+// gameDownloadTrigger holds while Steam or the Epic Games Launcher is
+// downloading or installing. Steam flags every app it is updating in the
+// registry, so it is exact; Epic has no such flag and is inferred from the
+// launcher's I/O.
+type gameDownloadTrigger struct {
+	steam   bool
+	epic    []processGroup
+	monitor *activityMonitor
+}
+
+func newGameDownloadTrigger(tc TriggerConfig) (*gameDownloadTrigger, error) {
+	launchers, err := selectGroups(gameLaunchers, tc.Clients)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &gameDownloadTrigger{
+		monitor: newActivityMonitor(tc, defaultEpicIOThresholdKB, 0, defaultEpicCooldown),
+	}
+	for _, l := range launchers {
+		if l.key == "steam" {
+			t.steam = true
+		} else {
+			t.epic = append(t.epic, l)
+		}
+	}
+	return t, nil
+}
+
+func (t *gameDownloadTrigger) check() (bool, string, error) {
+	var busy []string
+
+	if t.steam {
+		games, err := steamUpdatingApps()
+		if err != nil {
+			return false, "", err
+		}
+		busy = append(busy, games...)
+	}
+
+	if len(t.epic) > 0 {
+		launchers, err := t.monitor.poll(t.epic)
+		if err != nil {
+			return false, "", err
+		}
+		busy = append(busy, launchers...)
+	}
+
+	if len(busy) == 0 {
+		return false, "", nil
+	}
+	return true, strings.Join(busy, ", "), nil
+}
+
+// steamUpdatingApps returns the names of the Steam apps being downloaded or
+// installed.
+func steamUpdatingApps() ([]string, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, steamAppsKey, registry.ENUMERATE_SUB_KEYS)
+	if err == registry.ErrNotExist {
+		// Steam is not installed for this user.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	ids, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []string
+	for _, id := range ids {
+		app, err := registry.OpenKey(k, id, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		updating, _, err := app.GetIntegerValue("Updating")
+		if err == nil && updating != 0 {
+			name, _, err := app.GetStringValue("Name")
+			if err != nil || name == "" {
+				name = "Steam app " + id
+			}
+			apps = append(apps, name)
+		}
+		app.Close()
+	}
+	return apps, nil
+}
@@ -0,0 +1,142 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Network Activity Trigger ---
+
+const (
+	defaultNetworkThresholdKB = 500
+	defaultNetworkSustain     = 30 * time.Second
+	defaultNetworkCooldown    = 5 * time.Minute
+)
+
+// networkTrigger holds while traffic on the chosen interfaces has stayed
+// above a threshold for a while, as during a long download or backup, and
+// until it has been low for the cooldown.
+type networkTrigger struct {
+	interfaces []string // names, or empty for every interface that is up
+	threshold  float64  // bytes per second, received and sent
+	load       sustainedLoad
+
+	octets    map[uint32]uint64 // by interface index
+	sampledAt time.Time
+}
+
+func newNetworkTrigger(tc TriggerConfig) *networkTrigger {
+	t := &networkTrigger{
+		interfaces: tc.Interfaces,
+		threshold:  defaultNetworkThresholdKB * 1024,
+		load:       newSustainedLoad(tc, defaultNetworkSustain, defaultNetworkCooldown),
+	}
+	if tc.IOThresholdKB > 0 {
+		t.threshold = float64(tc.IOThresholdKB) * 1024
+	}
+	return t
+}
+
+func (t *networkTrigger) check() (bool, string, error) {
+	ifaces, err := t.selectInterfaces()
+	if err != nil {
+		return false, "", err
+	}
+
+	now := time.Now()
+	octets := make(map[uint32]uint64)
+	var transferred uint64
+	for _, iface := range ifaces {
+		row := windows.MibIfRow2{InterfaceIndex: uint32(iface.Index)}
+		if err := windows.GetIfEntry2Ex(windows.MibIfEntryNormal, &row); err != nil {
+			continue
+		}
+		n := row.InOctets + row.OutOctets
+		octets[row.InterfaceIndex] = n
+		// Counters restart when an adapter is reset.
+		if prev, ok := t.octets[row.InterfaceIndex]; ok && n >= prev {
+			transferred += n - prev
+		}
+	}
+
+	var rate float64
+	if !t.sampledAt.IsZero() {
+		if elapsed := now.Sub(t.sampledAt).Seconds(); elapsed > 0 {
+			rate = float64(transferred) / elapsed
+		}
+		t.load.sample(rate >= t.threshold, t.sampledAt, now)
+	}
+	t.octets = octets
+	t.sampledAt = now
+
+	if !t.load.holds(now) {
+		return false, "", nil
+	}
+	return true, formatRate(rate), nil
+}
+
+// selectInterfaces returns the configured interfaces, or every interface
+// that is up except loopback.
+func (t *networkTrigger) selectInterfaces() ([]net.Interface, error) {
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	if len(t.interfaces) == 0 {
+		var up []net.Interface
+		for _, iface := range all {
+			if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+				up = append(up, iface)
+			}
+		}
+		return up, nil
+	}
+
+	var selected []net.Interface
+	for _, name := range t.interfaces {
+		found := false
+		for _, iface := range all {
+			if strings.EqualFold(iface.Name, name) {
+				selected = append(selected, iface)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no network interface named %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// formatRate formats a transfer rate, e.g. "2.4 MB/s".
+func formatRate(bytesPerSecond float64) string {
+	if bytesPerSecond >= 1<<20 {
+		return fmt.Sprintf("%.1f MB/s", bytesPerSecond/(1<<20))
+	}
+	return fmt.Sprintf("%.0f KB/s", bytesPerSecond/1024)
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// --- Presentation Trigger ---
+
+// A presentation trigger holds while Windows reports that the user is
+// presenting: a full-screen app such as a PowerPoint slide show, or
+// presentation mode turned on in Windows Mobility Center. The same check
+// backs the "Keep Awake During Presentations" menu toggle, which works
+// without the triggers feature flag.
+
+var procSHQueryUserNotificationState = modshell32.NewProc("SHQueryUserNotificationState")
+
+// QUERY_USER_NOTIFICATION_STATE values.
+const (
+	QUNS_NOT_PRESENT             = 1
+	QUNS_BUSY                    = 2 // a full-screen app is running
+	QUNS_RUNNING_D3D_FULL_SCREEN = 3
+	QUNS_PRESENTATION_MODE       = 4
+	QUNS_ACCEPTS_NOTIFICATIONS   = 5
+	QUNS_QUIET_TIME              = 6
+	QUNS_APP                     = 7
+)
+
+const (
+	// presentationName is the name of the menu toggle's trigger.
+	presentationName     = "Presenting"
+	presentationInterval = 5 * time.Second
+)
+
+type presentationTrigger struct{}
+
+func (presentationTrigger) check() (bool, string, error) {
+	if !procAvailable(procSHQueryUserNotificationState) {
+		return false, "", fmt.Errorf("SHQueryUserNotificationState is not supported on this system")
+	}
+	var state uint32
+	if hr, _, _ := procSHQueryUserNotificationState.Call(uintptr(unsafe.Pointer(&state))); hr != 0 {
+		return false, "", fmt.Errorf("SHQueryUserNotificationState failed: HRESULT 0x%08X", hr)
+	}
+	switch state {
+	case QUNS_BUSY:
+		return true, "full-screen app", nil
+	case QUNS_PRESENTATION_MODE:
+		return true, "presentation mode", nil
+	default:
+		return false, "", nil
+	}
+}
+
+// startPresentationWatch polls for presentations for the menu toggle.
+func startPresentationWatch() <-chan triggerEvent {
+	ch := make(chan triggerEvent)
+	rule := triggerRule{flags: ES_SYSTEM_REQUIRED | ES_DISPLAY_REQUIRED}
+	go pollTrigger(presentationName, presentationTrigger{}, rule, presentationInterval, ch, nil)
+	return ch
+}
+
+// handlePresentationEvent records the latest presentation state and, if
+// the toggle is on, applies it like a trigger event.
+func (a *app) handlePresentationEvent(ev triggerEvent) {
+	a.presentation = ev
+	if a.cfg.PresentationAutoStart {
+		a.handleTriggerEvent(ev)
+	}
+}
+
+// setPresentationAutoStart turns the menu toggle on or off, starting or
+// ending the hold at once if a presentation is running.
+func (a *app) setPresentationAutoStart(on bool) {
+	a.cfg.PresentationAutoStart = on
+	a.saveConfig()
+	switch {
+	case on && a.presentation.active:
+		a.handleTriggerEvent(a.presentation)
+	case !on && a.triggerHolds(presentationName):
+		a.handleTriggerEvent(triggerEvent{name: presentationName})
+	}
+}
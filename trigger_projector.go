@@ -0,0 +1,119 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Projector Trigger ---
+
+var (
+	procGetDisplayConfigBufferSizes = user32.NewProc("GetDisplayConfigBufferSizes")
+	procQueryDisplayConfig          = user32.NewProc("QueryDisplayConfig")
+)
+
+const (
+	QDC_DATABASE_CURRENT = 0x00000004
+
+	DISPLAYCONFIG_TOPOLOGY_INTERNAL = 0x00000001
+	DISPLAYCONFIG_TOPOLOGY_CLONE    = 0x00000002
+	DISPLAYCONFIG_TOPOLOGY_EXTEND   = 0x00000004
+	DISPLAYCONFIG_TOPOLOGY_EXTERNAL = 0x00000008
+
+	displayConfigPathInfoSize = 72 // sizeof(DISPLAYCONFIG_PATH_INFO)
+	displayConfigModeInfoSize = 64 // sizeof(DISPLAYCONFIG_MODE_INFO)
+)
+
+// projectionModes are the Win+P choices a projector trigger can hold for.
+var projectionModes = map[string]uint32{
+	"duplicate": DISPLAYCONFIG_TOPOLOGY_CLONE,
+	"extend":    DISPLAYCONFIG_TOPOLOGY_EXTEND,
+}
+
+// projectorTrigger holds while the desktop is duplicated or extended to a
+// second screen, as chosen with Win+P when a projector is connected, and
+// releases once Windows is back to a single display.
+type projectorTrigger struct {
+	topologies uint32 // DISPLAYCONFIG_TOPOLOGY_* flags that hold
+}
+
+func newProjectorTrigger(tc TriggerConfig) (*projectorTrigger, error) {
+	if len(tc.Projection) == 0 {
+		return &projectorTrigger{topologies: DISPLAYCONFIG_TOPOLOGY_CLONE | DISPLAYCONFIG_TOPOLOGY_EXTEND}, nil
+	}
+	t := &projectorTrigger{}
+	for _, name := range tc.Projection {
+		topology, ok := projectionModes[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown projection %q (use duplicate, extend)", name)
+		}
+		t.topologies |= topology
+	}
+	return t, nil
+}
+
+func (t *projectorTrigger) check() (bool, string, error) {
+	topology, err := displayTopology()
+	if err != nil {
+		return false, "", err
+	}
+	if topology&t.topologies == 0 {
+		return false, "", nil
+	}
+	if topology == DISPLAYCONFIG_TOPOLOGY_CLONE {
+		return true, "duplicate", nil
+	}
+	return true, "extend", nil
+}
+
+// displayTopology returns the current Win+P projection as a
+// DISPLAYCONFIG_TOPOLOGY_* value.
+func displayTopology() (uint32, error) {
+	if !procAvailable(procQueryDisplayConfig) {
+		return 0, fmt.Errorf("QueryDisplayConfig is not supported on this system")
+	}
+	for {
+		var paths, modes uint32
+		if r, _, _ := procGetDisplayConfigBufferSizes.Call(QDC_DATABASE_CURRENT, uintptr(unsafe.Pointer(&paths)), uintptr(unsafe.Pointer(&modes))); r != 0 {
+			return 0, fmt.Errorf("GetDisplayConfigBufferSizes: %w", windows.Errno(r))
+		}
+		pathBuf := make([]byte, max(paths, 1)*displayConfigPathInfoSize)
+		modeBuf := make([]byte, max(modes, 1)*displayConfigModeInfoSize)
+		var topology uint32
+		r, _, _ := procQueryDisplayConfig.Call(QDC_DATABASE_CURRENT,
+			uintptr(unsafe.Pointer(&paths)), uintptr(unsafe.Pointer(&pathBuf[0])),
+			uintptr(unsafe.Pointer(&modes)), uintptr(unsafe.Pointer(&modeBuf[0])),
+			uintptr(unsafe.Pointer(&topology)))
+		switch windows.Errno(r) {
+		case 0:
+			return topology, nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			// A display was connected in between; ask for the sizes again.
+		default:
+			return 0, fmt.Errorf("QueryDisplayConfig: %w", windows.Errno(r))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Scheduled Task Trigger ---
+
+var (
+	CLSID_TaskScheduler = windows.GUID{Data1: 0x0F87369F, Data2: 0xA4E5, Data3: 0x4CFC, Data4: [8]byte{0xBD, 0x3E, 0x73, 0xE6, 0x15, 0x45, 0x72, 0xDD}}
+	IID_ITaskService    = windows.GUID{Data1: 0x2FABA4C7, Data2: 0x4DA9, Data3: 0x4013, Data4: [8]byte{0x96, 0x97, 0x20, 0xCC, 0x3F, 0xD4, 0x0F, 0x85}}
+)
+
+// Vtable indexes of the Task Scheduler 2.0 interfaces used here. All of
+// them derive from IDispatch.
+const (
+	taskServiceGetFolder = 7
+	taskServiceConnect   = 10
+	taskFolderGetTask    = 13
+	registeredTaskState  = 9
+
+	TASK_STATE_RUNNING = 4
+)
+
+// variant is an empty VARIANT. On 64-bit Windows VARIANTs are passed by
+// reference even when the signature takes them by value.
+type variant struct {
+	vt  uint16
+	_   [3]uint16
+	val [2]uintptr
+}
+
+// scheduledTaskTrigger holds while any of the named Task Scheduler tasks is
+// in the Running state. Names are task paths such as "\Backup\Nightly";
+// tasks in the root folder may omit the leading backslash.
+type scheduledTaskTrigger struct {
+	tasks []string
+}
+
+func newScheduledTaskTrigger(tc TriggerConfig) (*scheduledTaskTrigger, error) {
+	if len(tc.Tasks) == 0 {
+		return nil, fmt.Errorf("scheduled_task trigger needs at least one entry in \"tasks\"")
+	}
+	return &scheduledTaskTrigger{tasks: tc.Tasks}, nil
+}
+
+func (t *scheduledTaskTrigger) check() (bool, string, error) {
+	var running []string
+	err := withCOM(func() error {
+		service, err := createInstance(&CLSID_TaskScheduler, &IID_ITaskService)
+		if err != nil {
+			return err
+		}
+		defer service.release()
+
+		var empty variant
+		e := uintptr(unsafe.Pointer(&empty))
+		if err := service.call(taskServiceConnect, e, e, e, e); err != nil {
+			return fmt.Errorf("failed to connect to Task Scheduler: %w", err)
+		}
+
+		root := newBSTR(`\`)
+		defer root.free()
+		var folder *comObject
+		if err := service.call(taskServiceGetFolder, uintptr(root), uintptr(unsafe.Pointer(&folder))); err != nil {
+			return fmt.Errorf("failed to open task folder: %w", err)
+		}
+		defer folder.release()
+
+		for _, name := range t.tasks {
+			state, err := taskState(folder, name)
+			if err != nil {
+				return fmt.Errorf("task %s: %w", name, err)
+			}
+			if state == TASK_STATE_RUNNING {
+				running = append(running, strings.TrimPrefix(name, `\`))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(running) == 0 {
+		return false, "", nil
+	}
+	return true, strings.Join(running, ", "), nil
+}
+
+func taskState(folder *comObject, name string) (int32, error) {
+	path := newBSTR(name)
+	defer path.free()
+
+	var task *comObject
+	if err := folder.call(taskFolderGetTask, uintptr(path), uintptr(unsafe.Pointer(&task))); err != nil {
+		return 0, err
+	}
+	defer task.release()
+
+	var state int32
+	if err := task.call(registeredTaskState, uintptr(unsafe.Pointer(&state))); err != nil {
+		return 0, err
+	}
+	return state, nil
+}
@@ -0,0 +1,544 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// --- Trigger Definitions ---
+
+const defaultTriggerInterval = 15 * time.Second
+
+// triggerToastQuiet is how long a trigger stays quiet after notifying that
+// it started, or that it released, before it notifies the same again, so
+// that one that keeps starting and stopping does not flood the action
+// center. Its changes are still logged.
+const triggerToastQuiet = 5 * time.Minute
+
+// triggerToast keys app.triggerToasts.
+type triggerToast struct {
+	name    string
+	holding bool
+}
+
+// TriggerConfig describes one automatic keep-awake condition in settings.json.
+// Only the fields relevant to Type are read.
+type TriggerConfig struct {
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Interval int    `json:"interval_seconds,omitempty"` // 0 for defaultTriggerInterval
+
+	// Keep lists what the trigger keeps awake: "system", "display" or both
+	// (the default). MaxDuration, e.g. "4h", caps how long the trigger may
+	// hold in one stretch; it holds again once its condition clears.
+	Keep        []string `json:"keep,omitempty"`
+	MaxDuration string   `json:"max_duration,omitempty"`
+
+	// MinHold, e.g. "2m", keeps the trigger holding for at least that long
+	// once it starts, and Cooldown keeps it holding until its condition has
+	// been clear for that long, so that a flapping condition does not
+	// toggle keep-awake every poll. Cooldown replaces CooldownSeconds and
+	// wins if both are set.
+	MinHold  string `json:"min_hold,omitempty"`
+	Cooldown string `json:"cooldown,omitempty"`
+
+	// Domain limits the trigger to when a domain controller is reachable
+	// ("on") or not ("off").
+	Domain string `json:"domain,omitempty"`
+
+	// Location limits the trigger to when the machine is inside a place
+	// from settings.json, or outside it with a "!" prefix.
+	Location string `json:"location,omitempty"`
+
+	// docker
+	DockerHost string   `json:"docker_host,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Projects   []string `json:"projects,omitempty"`
+
+	// scheduled_task
+	Tasks []string `json:"tasks,omitempty"`
+
+	// calendar: an .ics file or URL, how long before each meeting starts
+	// and after it ends to keep awake (default "5m" and "10m"), and words
+	// that limit it to the meetings whose title contains one of them.
+	Calendar     string   `json:"calendar,omitempty"`
+	BufferBefore string   `json:"buffer_before,omitempty"`
+	BufferAfter  string   `json:"buffer_after,omitempty"`
+	Keywords     []string `json:"keywords,omitempty"`
+
+	// cloud_sync, game_downloads; network also uses IOThresholdKB (for
+	// traffic) and cpu uses CPUThreshold (for the whole system).
+	// CooldownSeconds is the old spelling of Cooldown for these four; see
+	// cooldown.
+	Clients         []string `json:"clients,omitempty"`
+	IOThresholdKB   int      `json:"io_threshold_kb,omitempty"` // KB/s
+	CPUThreshold    float64  `json:"cpu_threshold,omitempty"`   // percent
+	CooldownSeconds int      `json:"cooldown_seconds,omitempty"`
+
+	// network: interface names, all that are up if empty. network, cpu:
+	// how long the load must stay above the threshold before the trigger
+	// holds.
+	Interfaces     []string `json:"interfaces,omitempty"`
+	SustainSeconds int      `json:"sustain_seconds,omitempty"`
+
+	// projector: "duplicate" and/or "extend" (the default is both).
+	Projection []string `json:"projection,omitempty"`
+}
+
+// trigger is a condition that is polled periodically. While it holds,
+// Espresso keeps the system awake.
+type trigger interface {
+	// check reports whether the condition holds and, if so, a short
+	// description of what satisfied it.
+	check() (bool, string, error)
+}
+
+// triggerRule is how a trigger keeps the system awake while it holds.
+type triggerRule struct {
+	flags       uint32        // ES_SYSTEM_REQUIRED and/or ES_DISPLAY_REQUIRED
+	maxDuration time.Duration // 0 for no cap
+	minHold     time.Duration
+	cooldown    time.Duration
+}
+
+type triggerEvent struct {
+	name   string
+	active bool
+	detail string
+	rule   triggerRule
+}
+
+// activeTrigger is a trigger whose condition currently holds.
+type activeTrigger struct {
+	detail string
+	rule   triggerRule
+	since  time.Time
+	capped bool // held for rule.maxDuration and no longer keeps awake
+}
+
+func newTrigger(tc TriggerConfig) (trigger, error) {
+	switch tc.Type {
+	case "docker":
+		return newDockerTrigger(tc), nil
+	case "scheduled_task":
+		return newScheduledTaskTrigger(tc)
+	case "cloud_sync":
+		return newCloudSyncTrigger(tc)
+	case "game_downloads":
+		return newGameDownloadTrigger(tc)
+	case "focus_assist":
+		return focusAssistTrigger{}, nil
+	case "calendar":
+		return newCalendarTrigger(tc)
+	case "network":
+		return newNetworkTrigger(tc), nil
+	case "cpu":
+		return newCPUTrigger(tc), nil
+	case "projector":
+		return newProjectorTrigger(tc)
+	case "presentation":
+		return presentationTrigger{}, nil
+	default:
+		return nil, fmt.Errorf("unknown trigger type %q", tc.Type)
+	}
+}
+
+func (tc TriggerConfig) displayName() string {
+	if tc.Name != "" {
+		return tc.Name
+	}
+	switch tc.Type {
+	case "docker":
+		return "Docker"
+	case "scheduled_task":
+		return "Scheduled task"
+	case "cloud_sync":
+		return "Cloud sync"
+	case "game_downloads":
+		return "Game downloads"
+	case "focus_assist":
+		return "Do not disturb"
+	case "calendar":
+		return "Meeting"
+	case "network":
+		return "Network activity"
+	case "cpu":
+		return "CPU load"
+	case "projector":
+		return "Presentation"
+	case "presentation":
+		return "Full screen"
+	default:
+		return tc.Type
+	}
+}
+
+// coolsDownItself lists the trigger types that only report their
+// condition clear once it has been for the cooldown, each with its own
+// default.
+var coolsDownItself = map[string]bool{
+	"cloud_sync":     true,
+	"game_downloads": true,
+	"network":        true,
+	"cpu":            true,
+}
+
+// cooldown returns the cooldown set for tc, or def. The cooldown key wins
+// over cooldown_seconds; rule reports an invalid one.
+func (tc TriggerConfig) cooldown(def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(tc.Cooldown); err == nil && d > 0 {
+		return d
+	}
+	if tc.CooldownSeconds > 0 {
+		return time.Duration(tc.CooldownSeconds) * time.Second
+	}
+	return def
+}
+
+func (tc TriggerConfig) rule() (triggerRule, error) {
+	var r triggerRule
+	flags, err := parseKeep(tc.Keep)
+	if err != nil {
+		return r, err
+	}
+	r.flags = flags
+
+	for _, d := range []struct {
+		key   string
+		value string
+		dst   *time.Duration
+	}{
+		{"max_duration", tc.MaxDuration, &r.maxDuration},
+		{"min_hold", tc.MinHold, &r.minHold},
+		{"cooldown", tc.Cooldown, &r.cooldown},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v <= 0 {
+			return r, fmt.Errorf("invalid %s %q", d.key, d.value)
+		}
+		*d.dst = v
+	}
+	if coolsDownItself[tc.Type] {
+		r.cooldown = 0 // the trigger waits it out itself; see cooldown
+	} else if r.cooldown == 0 && tc.CooldownSeconds > 0 {
+		r.cooldown = time.Duration(tc.CooldownSeconds) * time.Second
+	}
+	return r, nil
+}
+
+// parseKeep converts a "keep" list ("system", "display") into execution
+// state flags. An empty list keeps both awake.
+func parseKeep(keep []string) (uint32, error) {
+	var flags uint32
+	for _, k := range keep {
+		switch strings.ToLower(k) {
+		case "system":
+			flags |= ES_SYSTEM_REQUIRED
+		case "display":
+			flags |= ES_DISPLAY_REQUIRED
+		default:
+			return 0, fmt.Errorf("unknown keep value %q", k)
+		}
+	}
+	if flags == 0 {
+		flags = ES_SYSTEM_REQUIRED | ES_DISPLAY_REQUIRED
+	}
+	return flags, nil
+}
+
+func (tc TriggerConfig) interval() time.Duration {
+	if tc.Interval <= 0 {
+		return defaultTriggerInterval
+	}
+	return time.Duration(tc.Interval) * time.Second
+}
+
+// --- Trigger Polling ---
+
+// triggerSetup is what startTriggers reads from settings.json.
+type triggerSetup struct {
+	triggers       []TriggerConfig
+	places         map[string]PlaceConfig
+	locationAccess bool
+	calendarAccess bool
+}
+
+// startTriggers starts a poller for every enabled trigger of s and returns
+// the channel on which state changes are reported. The pollers stop when
+// stop is closed.
+func startTriggers(s triggerSetup, stop <-chan struct{}) <-chan triggerEvent {
+	ch := make(chan triggerEvent)
+	seen := make(map[string]int)
+
+	for _, tc := range s.triggers {
+		if tc.Disabled {
+			continue
+		}
+		var t trigger
+		var err error
+		if tc.Type == "calendar" && remoteCalendar(tc.Calendar) && !s.calendarAccess {
+			err = fmt.Errorf("calendar %s requires \"calendar_access\": true", calendarHost(tc.Calendar))
+		} else {
+			t, err = newTrigger(tc)
+		}
+		if err == nil {
+			t, err = newDomainCondition(t, tc.Domain)
+		}
+		if err == nil {
+			t, err = newLocationCondition(t, tc.Location, s.places, s.locationAccess)
+		}
+		if err != nil {
+			logWarnf("skipping trigger: %v", err)
+			continue
+		}
+		rule, err := tc.rule()
+		if err != nil {
+			logWarnf("skipping trigger %s: %v", tc.displayName(), err)
+			continue
+		}
+
+		// Names key the active set, so they must be unique.
+		name := tc.displayName()
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s #%d", name, n)
+		}
+
+		go pollTrigger(name, t, rule, tc.interval(), ch, stop)
+	}
+	return ch
+}
+
+// pollTrigger checks t every interval and sends what changes on ch, until
+// stop is closed. A nil stop never is. Once the trigger holds, it goes on
+// holding for the rule's min_hold, and until its condition has been clear
+// for the rule's cooldown.
+func pollTrigger(name string, t trigger, rule triggerRule, interval time.Duration, ch chan<- triggerEvent, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last triggerEvent
+	var changed time.Time // when last.active last changed
+	var seen time.Time    // when the condition last held
+	for {
+		active, detail, err := t.check()
+		if err != nil {
+			logWarnf("trigger %s: %v", name, err)
+			active, detail = false, ""
+		}
+		if active {
+			seen = time.Now()
+		}
+		if last.active && !active {
+			switch {
+			case time.Since(changed) < rule.minHold:
+				active, detail = true, last.detail
+			case time.Since(seen) < rule.cooldown:
+				logDebugf("trigger %s: condition clear, releasing in %s", name, formatFriendlyDuration(rule.cooldown-time.Since(seen)))
+				active, detail = true, last.detail
+			}
+		}
+
+		ev := triggerEvent{name: name, active: active, detail: detail, rule: rule}
+		if ev.active != last.active {
+			changed = time.Now()
+		}
+		if ev != last {
+			select {
+			case ch <- ev:
+			case <-stop:
+				return
+			}
+			last = ev
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// restartTriggers stops the running triggers and starts those of s, unless
+// they are the same. Whatever the stopped triggers held is released.
+func (a *app) restartTriggers(s triggerSetup) {
+	if a.triggerStop != nil {
+		if reflect.DeepEqual(s, a.runningTriggers) {
+			return
+		}
+		close(a.triggerStop)
+		for name := range a.activeTriggers {
+			if name != presentationName { // not started by startTriggers
+				delete(a.activeTriggers, name)
+			}
+		}
+		a.applyState()
+	}
+	a.triggerStop = make(chan struct{})
+	a.runningTriggers = s
+	a.triggerCh = startTriggers(s, a.triggerStop)
+}
+
+// --- Trigger State ---
+
+func (a *app) handleTriggerEvent(ev triggerEvent) {
+	wasHolding := a.triggerHolds(ev.name)
+	if ev.active {
+		if t, ok := a.activeTriggers[ev.name]; ok {
+			t.detail = ev.detail
+		} else {
+			a.activeTriggers[ev.name] = &activeTrigger{detail: ev.detail, rule: ev.rule, since: time.Now()}
+		}
+	} else {
+		delete(a.activeTriggers, ev.name)
+	}
+	a.applyState()
+
+	isHolding := a.triggerHolds(ev.name)
+	if isHolding != wasHolding {
+		logTriggerFired(ev.name, isHolding, ev.detail)
+	}
+
+	// A manual session already keeps the system awake, so trigger changes
+	// are not worth a notification.
+	if a.active() || isHolding == wasHolding {
+		return
+	}
+	key := triggerToast{ev.name, isHolding}
+	if time.Since(a.triggerToasts[key]) < triggerToastQuiet {
+		logDebugf("trigger %s changed again within %s; not notifying", ev.name, triggerToastQuiet)
+		return
+	}
+	a.triggerToasts[key] = time.Now()
+	if isHolding {
+		go showToast(toastTag(tagTrigger, ev.name), tr("trigger.started_title"), tr("trigger.started", a.triggerReasons()), iconPath())
+	} else {
+		go showToast(toastTag(tagTrigger, ev.name), tr("trigger.released", ev.name), a.releasedMessage(), icoffPath())
+	}
+}
+
+// enforceTriggerCaps releases the triggers that have held for longer than
+// their max_duration.
+func (a *app) enforceTriggerCaps() {
+	changed := false
+	for name, t := range a.activeTriggers {
+		if t.capped || t.rule.maxDuration == 0 || time.Since(t.since) < t.rule.maxDuration {
+			continue
+		}
+		t.capped = true
+		changed = true
+		logTriggerFired(name, false, "max_duration reached")
+		go showToast(toastTag(tagTrigger, name), tr("trigger.released", name),
+			tr("trigger.capped", formatFriendlyDuration(t.rule.maxDuration), a.releasedMessage()), icoffPath())
+	}
+	if changed {
+		a.applyState()
+	}
+}
+
+// triggerHolds reports whether the named trigger is keeping the system awake.
+func (a *app) triggerHolds(name string) bool {
+	t, ok := a.activeTriggers[name]
+	return ok && !t.capped
+}
+
+// triggerFlags returns the execution state flags requested by the holding
+// triggers, or 0 if none holds.
+func (a *app) triggerFlags() uint32 {
+	var flags uint32
+	for _, t := range a.activeTriggers {
+		if !t.capped {
+			flags |= t.rule.flags
+		}
+	}
+	return flags
+}
+
+// triggerReasons describes the holding triggers, e.g. "Docker (web, db)".
+func (a *app) triggerReasons() string {
+	names := a.triggerHolders()
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if detail := a.activeTriggers[name].detail; detail != "" {
+			parts = append(parts, fmt.Sprintf("%s (%s)", name, detail))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// releasedMessage is the notification text shown when something stops
+// keeping the system awake.
+func (a *app) releasedMessage() string {
+	if a.triggerFlags() != 0 {
+		return tr("toast.still_awake", a.triggerReasons())
+	}
+	return tr("toast.released")
+}
+
+// --- Sustained Load ---
+
+// sustainedLoad decides when a sampled load, such as network traffic or
+// CPU use, holds a trigger: once it has stayed above its threshold for
+// sustain, and until it has been below it for cooldown.
+type sustainedLoad struct {
+	sustain    time.Duration
+	cooldown   time.Duration
+	aboveSince time.Time // zero while below the threshold
+	lastActive time.Time
+}
+
+// newSustainedLoad uses SustainSeconds and the cooldown from tc, falling
+// back to the given defaults.
+func newSustainedLoad(tc TriggerConfig, sustain, cooldown time.Duration) sustainedLoad {
+	if tc.SustainSeconds > 0 {
+		sustain = time.Duration(tc.SustainSeconds) * time.Second
+	}
+	return sustainedLoad{sustain: sustain, cooldown: tc.cooldown(cooldown)}
+}
+
+// sample records whether the load was above the threshold between from and
+// now. Once the trigger holds, every sample above the threshold extends it.
+func (l *sustainedLoad) sample(above bool, from, now time.Time) {
+	if !above {
+		l.aboveSince = time.Time{}
+		return
+	}
+	if l.aboveSince.IsZero() {
+		l.aboveSince = from
+	}
+	if l.holds(now) || now.Sub(l.aboveSince) >= l.sustain {
+		l.lastActive = now
+	}
+}
+
+func (l *sustainedLoad) holds(now time.Time) bool {
+	return !l.lastActive.IsZero() && now.Sub(l.lastActive) < l.cooldown
+}
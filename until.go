@@ -0,0 +1,95 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// --- Keep Awake Until ---
+
+// An Until session ends at a wall-clock time rather than after a fixed
+// duration. Its end time carries no monotonic clock reading, so the time
+// left is always derived from the wall clock: if the clock is changed, or
+// the machine sleeps in between, the session still ends at the chosen
+// time. Daylight saving changes need no special care, since the target is
+// an instant in time.
+
+// untilHours are the fixed clock times offered in the Until menu.
+var untilHours = []int{17, 18}
+
+// nextClockTime returns the next time after now that the clock shows
+// hour:min, today or tomorrow.
+func nextClockTime(hour, min int, now time.Time) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, now.Location())
+	if !t.After(now) {
+		t = time.Date(now.Year(), now.Month(), now.Day()+1, hour, min, 0, 0, now.Location())
+	}
+	return t
+}
+
+// parseClockTime parses a time of day such as "17:30", "5:30pm" or "5pm".
+func parseClockTime(s string) (hour, min int, err error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	for _, layout := range []string{"15:04", "15", "3:04pm", "3pm"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Hour(), t.Minute(), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid time %q", s)
+}
+
+// untilMode returns a session that ends at t.
+func untilMode(t time.Time) EspressoMode {
+	return EspressoMode{
+		Name:     tr("until.mode", t.Format("15:04")),
+		Duration: time.Until(t),
+		Until:    t,
+	}
+}
+
+// askUntilTime prompts for a clock time, starting from text, until the
+// user enters a valid one or cancels, and sends its next occurrence on ch.
+func askUntilTime(text string, ch chan<- time.Time) {
+	for {
+		var ok bool
+		text, ok = inputBox(tr("until.title"), tr("until.prompt"), text)
+		if !ok {
+			return
+		}
+		hour, min, err := parseClockTime(text)
+		if err != nil {
+			showMessage(tr("until.title"), tr("until.invalid", text))
+			continue
+		}
+		ch <- nextClockTime(hour, min, time.Now())
+		return
+	}
+}
+
+// startUntil starts a session from the Until menu that ends at t.
+func (a *app) startUntil(t time.Time) {
+	a.startSession(untilMode(t), sourceTray)
+	go showToast(tagSession, tr("toast.started", a.modeName()),
+		tr("until.started", formatFriendlyDuration(time.Until(t).Round(time.Minute))), iconPath())
+}
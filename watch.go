@@ -0,0 +1,106 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Process Watch ---
+
+// A watch session keeps the system awake for as long as any of a list of
+// processes runs, such as a HandBrake encode or a robocopy job, and ends
+// with a notification once they have all exited.
+
+const watchInterval = 5 * time.Second
+
+// parseWatchList splits a comma-separated list of process names, adding
+// ".exe" to names without an extension.
+func parseWatchList(s string) []string {
+	var names []string
+	for _, n := range strings.Split(s, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if filepath.Ext(n) == "" {
+			n += ".exe"
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+// askWatchProcesses prompts for the processes to watch, starting from
+// last, until at least one of those entered is running, and sends them on
+// ch.
+func askWatchProcesses(last []string, ch chan<- []string) {
+	text := strings.Join(last, ", ")
+	for {
+		var ok bool
+		text, ok = inputBox(tr("watch.title"), tr("watch.prompt"), text)
+		if !ok {
+			return
+		}
+		names := parseWatchList(text)
+		if len(names) == 0 {
+			continue
+		}
+		procs, err := listProcesses()
+		if err != nil {
+			showMessage(tr("watch.title"), tr("watch.list_failed", err))
+			return
+		}
+		if len(findProcesses(procs, names...)) == 0 {
+			showMessage(tr("watch.title"), tr("watch.not_running", strings.Join(names, ", ")))
+			continue
+		}
+		ch <- names
+		return
+	}
+}
+
+// watchMode is the session that watches names.
+func watchMode(names []string) EspressoMode {
+	return EspressoMode{Name: tr("watch.mode", strings.Join(names, ", ")), Duration: -1, Watch: names}
+}
+
+// checkWatch ends a watch session once none of its processes runs. It is
+// called every tick and looks every watchInterval.
+func (a *app) checkWatch() {
+	if !a.active() || len(a.sessionWatch) == 0 || time.Since(a.watchChecked) < watchInterval {
+		return
+	}
+	a.watchChecked = time.Now()
+	procs, err := listProcesses()
+	if err != nil {
+		logWarnf("could not list processes: %v", err)
+		return
+	}
+	if len(findProcesses(procs, a.sessionWatch...)) > 0 {
+		return
+	}
+	names := strings.Join(a.sessionWatch, ", ")
+	a.resetState("process exited")
+	go showToast(tagSession, tr("watch.finished", names), a.releasedMessage(), icoffPath())
+}
@@ -0,0 +1,206 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- Incoming Webhooks ---
+
+// Other systems, such as a CI server, can start and stop a named session
+// by posting to /v1/webhook/<name>:
+//
+//	{"event": "start", "timestamp": 1760000000}
+//
+// with the header X-Espresso-Signature: sha256=<hex HMAC-SHA256 of the raw
+// body under the webhook's secret>. "started" and "finished" are accepted
+// as well, as CI servers say. The timestamp, in Unix seconds, must be
+// within webhookMaxSkew of this machine's clock, so that a captured post
+// cannot be replayed later.
+
+const (
+	webhookMaxSkew         = 5 * time.Minute
+	defaultWebhookDuration = 4 * time.Hour
+	minWebhookSecret       = 16
+	sourceWebhookPrefix    = "webhook " // followed by the webhook name
+)
+
+// WebhookConfig is an incoming webhook in settings.json.
+type WebhookConfig struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+
+	// Mode names the session, by default after the webhook. Duration
+	// bounds it in case the stop event never comes (default 4h;
+	// "infinite" for no limit). Keep is as for triggers.
+	Mode     string   `json:"mode,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Keep     []string `json:"keep,omitempty"`
+}
+
+// webhookEvents maps the accepted events to "start" or "stop".
+var webhookEvents = map[string]string{
+	"start":    "start",
+	"started":  "start",
+	"stop":     "stop",
+	"finished": "stop",
+}
+
+type webhookPost struct {
+	Event     string `json:"event"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// readWebhook reads a webhook post for the main loop. It writes the error
+// response if the request is malformed.
+func readWebhook(w http.ResponseWriter, r *http.Request) (apiCall, bool) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, apiError{"use POST"})
+		return apiCall{}, false
+	}
+	_, name, _ := strings.Cut(r.URL.Path, "/webhook/")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 4096))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"body too large"})
+		return apiCall{}, false
+	}
+	return apiCall{
+		action:  "webhook",
+		webhook: webhookRequest{name: name, body: body, signature: r.Header.Get("X-Espresso-Signature")},
+	}, true
+}
+
+// webhookRequest is an unverified webhook post.
+type webhookRequest struct {
+	name      string
+	body      []byte
+	signature string
+}
+
+// verify checks the signature and timestamp of a post to hook and returns
+// its event, "start" or "stop".
+func (req webhookRequest) verify(hook WebhookConfig, now time.Time) (string, error) {
+	if len(hook.Secret) < minWebhookSecret {
+		return "", fmt.Errorf("webhook %s needs a secret of at least %d characters", hook.Name, minWebhookSecret)
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(req.signature, "sha256="))
+	if err != nil || len(got) == 0 {
+		return "", errors.New("missing or invalid signature")
+	}
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(req.body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return "", errors.New("missing or invalid signature")
+	}
+
+	var post webhookPost
+	if err := json.Unmarshal(req.body, &post); err != nil {
+		return "", errors.New("invalid JSON body")
+	}
+	if skew := now.Sub(time.Unix(post.Timestamp, 0)); skew > webhookMaxSkew || skew < -webhookMaxSkew {
+		return "", errors.New("timestamp missing or too far from the current time")
+	}
+	event, ok := webhookEvents[strings.ToLower(post.Event)]
+	if !ok {
+		return "", fmt.Errorf("unknown event %q (use start or stop)", post.Event)
+	}
+	return event, nil
+}
+
+// handleWebhook runs on the main loop. A stop only ends the session if the
+// same webhook started it, so a build finishing does not end a session
+// started by hand in the meantime.
+func (a *app) handleWebhook(c apiCall) {
+	var hook *WebhookConfig
+	for i := range a.cfg.Webhooks {
+		if a.cfg.Webhooks[i].Name == c.webhook.name {
+			hook = &a.cfg.Webhooks[i]
+			break
+		}
+	}
+	if hook == nil {
+		c.reply <- apiReply{http.StatusNotFound, apiError{"unknown webhook"}}
+		return
+	}
+	event, err := c.webhook.verify(*hook, time.Now())
+	if err != nil {
+		c.reply <- apiReply{http.StatusUnauthorized, apiError{err.Error()}}
+		return
+	}
+
+	source := sourceWebhookPrefix + hook.Name
+	switch event {
+	case "start":
+		m, err := hook.mode()
+		if err != nil {
+			c.reply <- apiReply{http.StatusInternalServerError, apiError{err.Error()}}
+			return
+		}
+		a.startSession(m, source)
+		if a.cfg.SharedMachine.NotifyRemote {
+			a.announceRemote()
+		} else {
+			go showToast(tagSession, tr("toast.started", a.modeName()), tr("webhook.started", hook.Name), iconPath())
+		}
+	case "stop":
+		if a.active() && a.sessionSource == source {
+			a.resetState("stopped")
+			go showToast(tagSession, tr("toast.stopped"), a.releasedMessage(), icoffPath())
+		}
+	}
+	c.reply <- apiReply{http.StatusOK, a.apiStatus()}
+}
+
+// mode returns the session a start event begins.
+func (hook WebhookConfig) mode() (EspressoMode, error) {
+	m := EspressoMode{Name: hook.Mode, Duration: defaultWebhookDuration}
+	if m.Name == "" {
+		m.Name = hook.Name
+	}
+	switch {
+	case strings.EqualFold(hook.Duration, "infinite"):
+		m.Duration = -1
+	case hook.Duration != "":
+		d, err := parseSessionDuration(hook.Duration)
+		if err != nil {
+			return m, fmt.Errorf("webhook %s: %w", hook.Name, err)
+		}
+		m.Duration = d
+	}
+	if len(hook.Keep) > 0 {
+		flags, err := parseKeep(hook.Keep)
+		if err != nil {
+			return m, fmt.Errorf("webhook %s: %w", hook.Name, err)
+		}
+		m.Flags = flags
+	}
+	return m, nil
+}
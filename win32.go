@@ -0,0 +1,352 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Win32 Windowing ---
+
+var (
+	procRegisterClassExW           = user32.NewProc("RegisterClassExW")
+	procCreateWindowExW            = user32.NewProc("CreateWindowExW")
+	procDefWindowProcW             = user32.NewProc("DefWindowProcW")
+	procGetMessageW                = user32.NewProc("GetMessageW")
+	procTranslateMessage           = user32.NewProc("TranslateMessage")
+	procDispatchMessageW           = user32.NewProc("DispatchMessageW")
+	procPostMessageW               = user32.NewProc("PostMessageW")
+	procShowWindow                 = user32.NewProc("ShowWindow")
+	procEnableWindow               = user32.NewProc("EnableWindow")
+	procSetWindowPos               = user32.NewProc("SetWindowPos")
+	procInvalidateRect             = user32.NewProc("InvalidateRect")
+	procGetClientRect              = user32.NewProc("GetClientRect")
+	procBeginPaint                 = user32.NewProc("BeginPaint")
+	procEndPaint                   = user32.NewProc("EndPaint")
+	procFillRect                   = user32.NewProc("FillRect")
+	procDrawTextW                  = user32.NewProc("DrawTextW")
+	procSetTimer                   = user32.NewProc("SetTimer")
+	procKillTimer                  = user32.NewProc("KillTimer")
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes")
+	procSystemParametersInfoW      = user32.NewProc("SystemParametersInfoW")
+	procGetDpiForSystem            = user32.NewProc("GetDpiForSystem")
+	procLoadCursorW                = user32.NewProc("LoadCursorW")
+	procSendMessageW               = user32.NewProc("SendMessageW")
+	procGetWindowTextW             = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW       = user32.NewProc("GetWindowTextLengthW")
+	procIsDialogMessageW           = user32.NewProc("IsDialogMessageW")
+	procDestroyWindow              = user32.NewProc("DestroyWindow")
+	procPostQuitMessage            = user32.NewProc("PostQuitMessage")
+	procSetForegroundWindow        = user32.NewProc("SetForegroundWindow")
+	procSetFocus                   = user32.NewProc("SetFocus")
+	procSetWindowTextW             = user32.NewProc("SetWindowTextW")
+	procAdjustWindowRectEx         = user32.NewProc("AdjustWindowRectEx")
+
+	modgdi32                = windows.NewLazySystemDLL("gdi32.dll")
+	procCreateSolidBrush    = modgdi32.NewProc("CreateSolidBrush")
+	procCreateFontW         = modgdi32.NewProc("CreateFontW")
+	procSelectObject        = modgdi32.NewProc("SelectObject")
+	procDeleteObject        = modgdi32.NewProc("DeleteObject")
+	procSetTextColor        = modgdi32.NewProc("SetTextColor")
+	procSetBkMode           = modgdi32.NewProc("SetBkMode")
+	procCreateFontIndirectW = modgdi32.NewProc("CreateFontIndirectW")
+	procGetStockObject      = modgdi32.NewProc("GetStockObject")
+)
+
+const (
+	WM_DESTROY = 0x0002
+	WM_CLOSE   = 0x0010
+	WM_SETFONT = 0x0030
+	WM_COMMAND = 0x0111
+	WM_PAINT   = 0x000F
+	WM_TIMER   = 0x0113
+	WM_USER    = 0x0400
+
+	WS_POPUP   = 0x80000000
+	WS_CHILD   = 0x40000000
+	WS_VISIBLE = 0x10000000
+	WS_CAPTION = 0x00C00000
+	WS_SYSMENU = 0x00080000
+	WS_BORDER  = 0x00800000
+	WS_TABSTOP = 0x00010000
+
+	WS_EX_DLGMODALFRAME = 0x00000001
+	WS_EX_CLIENTEDGE    = 0x00000200
+
+	ES_AUTOHSCROLL   = 0x0080
+	ES_MULTILINE     = 0x0004
+	ES_AUTOVSCROLL   = 0x0040
+	ES_WANTRETURN    = 0x1000
+	WS_VSCROLL       = 0x00200000
+	BS_AUTOCHECKBOX  = 0x0003
+	BM_GETCHECK      = 0x00F0
+	BM_SETCHECK      = 0x00F1
+	BST_CHECKED      = 1
+	CBS_DROPDOWNLIST = 0x0003
+	CB_ADDSTRING     = 0x0143
+	CB_GETCURSEL     = 0x0147
+	CB_SETCURSEL     = 0x014E
+	LBS_NOTIFY       = 0x0001
+	LB_ADDSTRING     = 0x0180
+	LB_RESETCONTENT  = 0x0184
+	LB_GETCURSEL     = 0x0188
+	BS_PUSHBUTTON    = 0x0000
+	BS_DEFPUSHBUTTON = 0x0001
+	IDOK             = 1
+	IDCANCEL         = 2
+	EM_SETSEL        = 0x00B1
+
+	IDC_ARROW               = 32512
+	COLOR_BTNFACE           = 15
+	DEFAULT_GUI_FONT        = 17
+	SPI_GETNONCLIENTMETRICS = 0x0029
+
+	WS_EX_TOPMOST     = 0x00000008
+	WS_EX_TRANSPARENT = 0x00000020
+	WS_EX_TOOLWINDOW  = 0x00000080
+	WS_EX_LAYERED     = 0x00080000
+	WS_EX_NOACTIVATE  = 0x08000000
+
+	SW_HIDE           = 0
+	SW_SHOWNORMAL     = 1
+	SW_SHOWNOACTIVATE = 4
+
+	SWP_NOACTIVATE = 0x0010
+	HWND_TOPMOST   = ^uintptr(0) // (HWND)-1
+
+	LWA_ALPHA         = 0x00000002
+	SPI_GETWORKAREA   = 0x0030
+	TRANSPARENT       = 1
+	FW_NORMAL         = 400
+	FW_BOLD           = 700
+	DT_CENTER         = 0x00000001
+	DT_VCENTER        = 0x00000004
+	DT_SINGLELINE     = 0x00000020
+	DT_WORDBREAK      = 0x00000010
+	DT_NOPREFIX       = 0x00000800
+	DT_CALCRECT       = 0x00000400
+	DEFAULT_CHARSET   = 1
+	CLEARTYPE_QUALITY = 5
+)
+
+// wndClassEx mirrors WNDCLASSEXW.
+type wndClassEx struct {
+	Size       uint32
+	Style      uint32
+	WndProc    uintptr
+	ClsExtra   int32
+	WndExtra   int32
+	Instance   windows.Handle
+	Icon       windows.Handle
+	Cursor     windows.Handle
+	Background windows.Handle
+	MenuName   *uint16
+	ClassName  *uint16
+	IconSm     windows.Handle
+}
+
+// winMsg mirrors MSG.
+type winMsg struct {
+	Hwnd    windows.HWND
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+}
+
+// paintStruct mirrors PAINTSTRUCT.
+type paintStruct struct {
+	Hdc         windows.Handle
+	Erase       int32
+	Paint       windows.Rect
+	Restore     int32
+	IncUpdate   int32
+	RGBReserved [32]byte
+}
+
+var (
+	classMu    sync.Mutex
+	registered = make(map[string]bool)
+)
+
+// createWindow creates a top-level window of className, registering the
+// class for wndProc on first use. Later windows of the same class share the
+// first wndProc. It must be called on the thread that will run the message
+// loop.
+func createWindow(className string, exStyle, style uint32, x, y, w, h int32,
+	wndProc func(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr) (windows.HWND, error) {
+	var instance windows.Handle
+	if err := windows.GetModuleHandleEx(0, nil, &instance); err != nil {
+		return 0, err
+	}
+
+	cls, _ := windows.UTF16PtrFromString(className)
+
+	classMu.Lock()
+	if !registered[className] {
+		cursor, _, _ := procLoadCursorW.Call(0, IDC_ARROW)
+		wc := wndClassEx{
+			WndProc:    windows.NewCallback(wndProc),
+			Instance:   instance,
+			Cursor:     windows.Handle(cursor),
+			Background: COLOR_BTNFACE + 1,
+			ClassName:  cls,
+		}
+		wc.Size = uint32(unsafe.Sizeof(wc))
+		if r, _, err := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))); r == 0 {
+			classMu.Unlock()
+			return 0, err
+		}
+		registered[className] = true
+	}
+	classMu.Unlock()
+
+	hwnd, _, err := procCreateWindowExW.Call(
+		uintptr(exStyle),
+		uintptr(unsafe.Pointer(cls)),
+		uintptr(unsafe.Pointer(cls)),
+		uintptr(style),
+		uintptr(x), uintptr(y), uintptr(w), uintptr(h),
+		0, 0, uintptr(instance), 0)
+	if hwnd == 0 {
+		return 0, err
+	}
+	return windows.HWND(hwnd), nil
+}
+
+// createControl creates a child control of a system class such as "EDIT"
+// or "BUTTON". id is reported in WM_COMMAND.
+func createControl(parent windows.HWND, class, text string, exStyle, style uint32, x, y, w, h int32, id uintptr) windows.HWND {
+	c, _ := windows.UTF16PtrFromString(class)
+	t, _ := windows.UTF16PtrFromString(text)
+	hwnd, _, _ := procCreateWindowExW.Call(
+		uintptr(exStyle),
+		uintptr(unsafe.Pointer(c)),
+		uintptr(unsafe.Pointer(t)),
+		uintptr(WS_CHILD|WS_VISIBLE|style),
+		uintptr(x), uintptr(y), uintptr(w), uintptr(h),
+		uintptr(parent), id, 0, 0)
+	procSendMessageW.Call(hwnd, WM_SETFONT, dialogFont(), 1)
+	return windows.HWND(hwnd)
+}
+
+// windowText returns the text of a window or control.
+func windowText(hwnd windows.HWND) string {
+	n, _, _ := procGetWindowTextLengthW.Call(uintptr(hwnd))
+	buf := make([]uint16, n+1)
+	procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return windows.UTF16ToString(buf)
+}
+
+var (
+	dialogFontOnce sync.Once
+	dialogFontH    uintptr
+)
+
+// dialogFont returns the font Windows uses for message boxes.
+func dialogFont() uintptr {
+	dialogFontOnce.Do(func() {
+		var ncm nonClientMetrics
+		ncm.Size = uint32(unsafe.Sizeof(ncm))
+		if r, _, _ := procSystemParametersInfoW.Call(SPI_GETNONCLIENTMETRICS, uintptr(ncm.Size), uintptr(unsafe.Pointer(&ncm)), 0); r != 0 {
+			dialogFontH, _, _ = procCreateFontIndirectW.Call(uintptr(unsafe.Pointer(&ncm.MessageFont)))
+		}
+		if dialogFontH == 0 {
+			dialogFontH, _, _ = procGetStockObject.Call(DEFAULT_GUI_FONT)
+		}
+	})
+	return dialogFontH
+}
+
+// logFont mirrors LOGFONTW.
+type logFont struct {
+	Height, Width, Escapement, Orientation, Weight int32
+	Italic, Underline, StrikeOut, CharSet          byte
+	OutPrecision, ClipPrecision, Quality, Pitch    byte
+	FaceName                                       [32]uint16
+}
+
+// nonClientMetrics mirrors NONCLIENTMETRICSW.
+type nonClientMetrics struct {
+	Size                                   uint32
+	BorderWidth, ScrollWidth, ScrollHeight int32
+	CaptionWidth, CaptionHeight            int32
+	CaptionFont                            logFont
+	SmCaptionWidth, SmCaptionHeight        int32
+	SmCaptionFont                          logFont
+	MenuWidth, MenuHeight                  int32
+	MenuFont, StatusFont, MessageFont      logFont
+	PaddedBorderWidth                      int32
+}
+
+// runMessageLoop pumps messages for the windows of the calling thread until
+// WM_QUIT is received. If dialog is not 0, its keyboard navigation (Tab,
+// Enter, Esc) is handled as in a dialog box.
+func runMessageLoop(dialog windows.HWND) {
+	var m winMsg
+	for {
+		r, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+		if int32(r) <= 0 {
+			return
+		}
+		if dialog != 0 {
+			if r, _, _ := procIsDialogMessageW.Call(uintptr(dialog), uintptr(unsafe.Pointer(&m))); r != 0 {
+				continue
+			}
+		}
+		procTranslateMessage.Call(uintptr(unsafe.Pointer(&m)))
+		procDispatchMessageW.Call(uintptr(unsafe.Pointer(&m)))
+	}
+}
+
+func defWindowProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	r, _, _ := procDefWindowProcW.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+	return r
+}
+
+// workArea returns the desktop area not covered by the taskbar on the
+// primary monitor.
+func workArea() windows.Rect {
+	var r windows.Rect
+	procSystemParametersInfoW.Call(SPI_GETWORKAREA, 0, uintptr(unsafe.Pointer(&r)), 0)
+	return r
+}
+
+// dpiScale scales a length in 96-DPI pixels to the system DPI.
+func dpiScale(v int32) int32 {
+	if !procAvailable(procGetDpiForSystem) {
+		return v
+	}
+	dpi, _, _ := procGetDpiForSystem.Call()
+	if dpi == 0 {
+		return v
+	}
+	return v * int32(dpi) / 96
+}
+
+// rgb builds a COLORREF.
+func rgb(r, g, b byte) uint32 {
+	return uint32(r) | uint32(g)<<8 | uint32(b)<<16
+}
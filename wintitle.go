@@ -0,0 +1,129 @@
+//go:build windows
+
+/*
+   Espresso - A lightweight utility to keep your screen on and your system active.
+   Copyright (C) 2025  Rodrigo Toraño Valle
+
+   This program is free software: you can redistribute it and/or modify
+   it under the terms of the GNU General Public License as published by
+   the Free Software Foundation, either version 3 of the License, or
+   (at your option) any later version.
+
+   This program is distributed in the hope that it will be useful,
+   but WITHOUT ANY WARRANTY; without even the implied warranty of
+   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+   GNU General Public License for more details.
+
+   You should have received a copy of the GNU General Public License
+   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// --- Status Window Title ---
+
+// A hidden window of class EspressoStatus carries the state in its title,
+// for remote-management and monitoring tools that can read window titles
+// but not call the API. The title is not translated and keeps one format:
+//
+//	Espresso | <state> | <mode> | <time left> | ends <HH:MM>
+//
+// where state is "active", "triggered", "paused" or "idle" as for the
+// indicator, time left is H:MM:SS or "no limit", and the last two parts
+// are "-" without a timed session.
+
+const (
+	titleWindowClassName = "EspressoStatus"
+	wmTitleUpdate        = WM_USER + 1
+)
+
+type titleWindow struct {
+	hwnd windows.HWND
+
+	mu    sync.Mutex
+	title string
+}
+
+func startTitleWindow() *titleWindow {
+	w := &titleWindow{}
+	ready := make(chan struct{})
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		hwnd, err := createWindow(titleWindowClassName, WS_EX_TOOLWINDOW, WS_POPUP, 0, 0, 0, 0, w.wndProc)
+		if err != nil {
+			logWarnf("could not create the status window: %v", err)
+			close(ready)
+			return
+		}
+		w.hwnd = hwnd
+		close(ready)
+		runMessageLoop(0)
+	}()
+
+	<-ready
+	return w
+}
+
+// set changes the title if it differs.
+func (w *titleWindow) set(title string) {
+	if w.hwnd == 0 {
+		return
+	}
+	w.mu.Lock()
+	changed := w.title != title
+	w.title = title
+	w.mu.Unlock()
+	if changed {
+		procPostMessageW.Call(uintptr(w.hwnd), wmTitleUpdate, 0, 0)
+	}
+}
+
+func (w *titleWindow) wndProc(hwnd windows.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+	if msg == wmTitleUpdate {
+		w.mu.Lock()
+		t, _ := windows.UTF16PtrFromString(w.title)
+		w.mu.Unlock()
+		procSetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(t)))
+		return 0
+	}
+	return defWindowProc(hwnd, msg, wParam, lParam)
+}
+
+// updateTitle sets the status window title for the current state.
+func (a *app) updateTitle() {
+	left, ends := "-", "-"
+	switch {
+	case !a.active():
+	case a.paused() && a.timed():
+		left = clockDuration(a.timeLeft())
+	case a.infinite():
+		left = "no limit"
+	case !a.paused():
+		left = clockDuration(a.timeLeft())
+		ends = a.sessionEnd().Format("15:04")
+	}
+	mode := "-"
+	if a.active() {
+		mode = a.modeName()
+	}
+	a.titleWindow.set(fmt.Sprintf("Espresso | %s | %s | %s | ends %s", a.indicatorState(), mode, left, ends))
+}
+
+// clockDuration formats d as H:MM:SS.
+func clockDuration(d time.Duration) string {
+	s := max(int(d.Round(time.Second).Seconds()), 0)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}